@@ -0,0 +1,185 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the check command.
+const (
+	// checkExitWhitelisted: all the subjects are whitelisted.
+	checkExitWhitelisted = 0
+	// checkExitBlocked: at least one subject is blocked.
+	checkExitBlocked = 1
+	// checkExitError: the subjects could not be checked.
+	checkExitError = 2
+)
+
+// Statuses reported by the check command.
+const (
+	checkStatusWhitelisted = "whitelisted"
+	checkStatusBlocked     = "blocked"
+)
+
+var checkJSON bool
+
+var checkCmd = &cobra.Command{
+	Use:   "check [subject...]",
+	Short: "Check if the given subjects are whitelisted.",
+	Long: `Check if the given subjects are whitelisted.
+
+The rules are loaded once and each subject is reported as whitelisted or blocked.
+When no subject is given, the subjects are read from stdin, one per line.
+
+The exit code is 0 when all the subjects are whitelisted, 1 when at least one
+subject is blocked and 2 when an error occurred (e.g. a rule file can't be read)
+or when stdin holds no subject.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		errorExitCode = checkExitError
+
+		if !hasWhitelistFiles() {
+			fmt.Fprintln(os.Stderr, "Error: at least one whitelist file must be specified.")
+			os.Exit(checkExitError)
+		}
+
+		setupLogger()
+
+		os.Exit(processCheck(args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().BoolVarP(&checkJSON, "json", "j", false, "Whether to print the results as JSON (one object per line) with the matched rule or not.")
+
+	addRuleFlags(checkCmd)
+}
+
+// checkResult is the result of the check of a single subject.
+type checkResult struct {
+	// Subject is the subject, as given by the end-user.
+	Subject string `json:"subject"`
+	// Status is either "whitelisted" or "blocked".
+	Status string `json:"status"`
+	// Rule is the rule which whitelists the subject, empty when blocked.
+	Rule string `json:"rule,omitempty"`
+	// Kind is the kind of the rule which whitelists the subject, empty when blocked.
+	Kind string `json:"kind,omitempty"`
+}
+
+// processCheck checks the given subjects, or the ones read from stdin, and prints the results.
+//
+// Args:
+//
+//	subjects: The subjects to check, stdin is read when empty.
+//
+// Returns:
+//
+//	int: The exit code of the command.
+func processCheck(subjects []string) int {
+	options := newRuleOptions()
+	ruler := newRuler(options)
+	logger := ruler.Logger()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	// The errors are reported here rather than through exitOnError, so that
+	// the temporary directory is removed.
+	if err := loadRules(ruler, options, dirName, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return checkExitError
+	}
+
+	exitCode := checkExitWhitelisted
+	checked := 0
+	encoder := json.NewEncoder(os.Stdout)
+
+	check := func(subject string) {
+		checked++
+		result := checkResult{Subject: subject, Status: checkStatusBlocked}
+
+		if rule, whitelisted := ruler.WhitelistingRule(subject); whitelisted {
+			result.Status = checkStatusWhitelisted
+			result.Rule = rule.String()
+			result.Kind = rule.Kind
+		} else if exitCode == checkExitWhitelisted {
+			exitCode = checkExitBlocked
+		}
+
+		logger.Debug("Checked subject.", slog.String("subject", subject), slog.String("status", result.Status), slog.String("rule", result.Rule))
+
+		if checkJSON {
+			if err := encoder.Encode(result); err != nil {
+				logger.Error("Error writing result.", slog.String("error", err.Error()))
+				exitCode = checkExitError
+			}
+
+			return
+		}
+
+		if result.Rule != "" {
+			fmt.Printf("%s: %s by %s\n", result.Subject, result.Status, result.Rule)
+		} else {
+			fmt.Printf("%s: %s\n", result.Subject, result.Status)
+		}
+	}
+
+	if len(subjects) != 0 {
+		for _, subject := range subjects {
+			check(subject)
+		}
+
+		return exitCode
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+
+	for scanner.Scan() {
+		subject := strings.TrimSpace(scanner.Text())
+
+		if subject == "" || strings.HasPrefix(subject, "#") {
+			continue
+		}
+
+		check(subject)
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Error("Error reading subjects from stdin.", slog.String("error", err.Error()))
+		fmt.Fprintf(os.Stderr, "Error reading subjects from stdin: %v\n", err)
+
+		return checkExitError
+	}
+
+	if checked == 0 {
+		logger.Error("No subject read from stdin.")
+		fmt.Fprintln(os.Stderr, "Error: no subject to check.")
+
+		return checkExitError
+	}
+
+	return exitCode
+}
@@ -0,0 +1,1069 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/formats"
+	"github.com/funilrys/givilsta/internal/helpers"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// sourceInput is a source to cleanup.
+type sourceInput struct {
+	// Name is the name of the source, as given by the end-user.
+	Name string
+	// OutputName is the file name to use when writing the source into the output directory.
+	OutputName string
+	// open opens the (decompressed) stream of the source. Remote sources are
+	// downloaded and stdin is read while the stream is read: it can only be
+	// read once.
+	open func() (io.ReadCloser, error)
+}
+
+// resolveSources resolves the given sources into a list of streams to read.
+// Each source can be a file, a directory, a glob pattern, an URL or "-" for stdin.
+// Remote sources and stdin are streamed, without being stored into temporary files.
+//
+// Args:
+//
+//	sources: The sources given by the end-user.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	[]sourceInput: The resolved sources.
+//	error: An error if a source cannot be resolved.
+func resolveSources(sources []string, logger *slog.Logger) ([]sourceInput, error) {
+	var result []sourceInput
+
+	for index, source := range sources {
+		switch {
+		case source == "-":
+			result = append(result, sourceInput{Name: "stdin", OutputName: "stdin.list", open: func() (io.ReadCloser, error) {
+				logger.Debug("Reading source from stdin.")
+				return helpers.NewDecompressingReader(os.Stdin)
+			}})
+		case helpers.IsUrl(source):
+			outputName := fmt.Sprintf("source-%d.list", index)
+
+			if urlObj, err := url.Parse(source); err == nil && path.Base(urlObj.Path) != "/" && path.Base(urlObj.Path) != "." {
+				outputName = path.Base(urlObj.Path)
+			}
+
+			result = append(result, sourceInput{Name: source, OutputName: outputName, open: func() (io.ReadCloser, error) {
+				logger.Debug("Fetching source from URL.", slog.String("source", source))
+				return helpers.OpenURL(source)
+			}})
+		default:
+			matches := []string{source}
+
+			if strings.ContainsAny(source, "*?[") {
+				var err error
+
+				matches, err = filepath.Glob(source)
+
+				if err != nil || len(matches) == 0 {
+					logger.Error("Source pattern does not match any file.", slog.String("source", source))
+					return nil, fmt.Errorf("source pattern '%s' does not match any file", source)
+				}
+			}
+
+			for _, match := range matches {
+				inputs, err := resolveLocalSource(match, logger)
+				if err != nil {
+					return nil, err
+				}
+
+				result = append(result, inputs...)
+			}
+		}
+	}
+
+	// Sources sharing the same file name would overwrite each other in the output directory.
+	seen := make(map[string]bool)
+
+	for index := range result {
+		if seen[result[index].OutputName] {
+			result[index].OutputName = fmt.Sprintf("%d-%s", index, result[index].OutputName)
+		}
+
+		seen[result[index].OutputName] = true
+	}
+
+	return result, nil
+}
+
+// openLocalSource returns the function opening the given local source file.
+func openLocalSource(filePath string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return helpers.OpenFile(filePath)
+	}
+}
+
+// resolveLocalSource resolves a local file or directory into a list of sources.
+func resolveLocalSource(source string, logger *slog.Logger) ([]sourceInput, error) {
+	info, err := os.Stat(source)
+
+	if err != nil {
+		logger.Error("Source file does not exist.", slog.String("file", source))
+		return nil, fmt.Errorf("source file '%s' does not exist", source)
+	}
+
+	if !info.IsDir() {
+		return []sourceInput{{Name: source, OutputName: filepath.Base(source), open: openLocalSource(source)}}, nil
+	}
+
+	var result []sourceInput
+
+	err = filepath.WalkDir(source, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type().IsRegular() {
+			relative, _ := filepath.Rel(source, filePath)
+			result = append(result, sourceInput{
+				Name:       filePath,
+				OutputName: strings.ReplaceAll(relative, string(filepath.Separator), "-"),
+				open:       openLocalSource(filePath),
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logger.Error("Error reading source directory.", slog.String("dir", source), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("reading source directory '%s': %w", source, err)
+	}
+
+	return result, nil
+}
+
+// maxOffendingRules is the number of rules printed when a removal threshold is exceeded.
+const maxOffendingRules = 10
+
+// removalStats tracks what the whitelist removed from the sources.
+type removalStats struct {
+	// Lines is the number of non-empty lines read from the sources.
+	Lines int
+	// Skipped is the number of lines without any subject (comments, invalid entries).
+	Skipped int
+	// Subjects is the number of subjects read from the sources.
+	Subjects int
+	// Removed is the number of whitelisted (removed) subjects.
+	Removed int
+	// Rules counts the removed subjects per rule. Subjects whitelisted by
+	// an unknown rule are counted under the zero rule.
+	Rules map[givilsta.Rule]int
+	// Lossy counts the subjects the output format could not render as they
+	// are, per loss (see formats.Renderer.Lossy).
+	Lossy map[string]int
+}
+
+// newRemovalStats creates empty removal statistics.
+func newRemovalStats() *removalStats {
+	return &removalStats{Rules: make(map[givilsta.Rule]int), Lossy: make(map[string]int)}
+}
+
+// recordLossy records the subjects rendered with a loss by the given renderer.
+//
+// Args:
+//
+//	renderer: The renderer of the output format, nil when the source lines are written back.
+//	source: The format of the source the subjects come from.
+//	wildcard: Whether the subjects come from a wildcard record.
+//	count: The number of rendered subjects.
+func (s *removalStats) recordLossy(renderer formats.Renderer, source formats.Format, wildcard bool, count int) {
+	if renderer == nil || count == 0 {
+		return
+	}
+
+	if loss := renderer.Lossy(source, wildcard); loss != "" {
+		s.Lossy[loss] += count
+	}
+}
+
+// lossy returns the number of subjects rendered with a loss.
+func (s *removalStats) lossy() int {
+	total := 0
+
+	for _, count := range s.Lossy {
+		total += count
+	}
+
+	return total
+}
+
+// record records a removed subject and the rule which whitelisted it.
+func (s *removalStats) record(rule givilsta.Rule) {
+	s.Removed++
+	s.Rules[rule]++
+}
+
+// ratio returns the ratio of removed subjects.
+func (s *removalStats) ratio() float64 {
+	if s.Subjects == 0 {
+		return 0
+	}
+
+	return float64(s.Removed) / float64(s.Subjects)
+}
+
+// topRules returns the given number of rules which removed the most subjects.
+// A negative limit returns all of them.
+func (s *removalStats) topRules(limit int) []givilsta.Rule {
+	rules := make([]givilsta.Rule, 0, len(s.Rules))
+
+	for rule := range s.Rules {
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if s.Rules[rules[i]] != s.Rules[rules[j]] {
+			return s.Rules[rules[i]] > s.Rules[rules[j]]
+		}
+
+		return ruleName(rules[i]) < ruleName(rules[j])
+	})
+
+	if limit >= 0 && len(rules) > limit {
+		rules = rules[:limit]
+	}
+
+	return rules
+}
+
+// ruleName returns the printable name of the given rule.
+func ruleName(rule givilsta.Rule) string {
+	if rule.Kind == "" {
+		return "(unknown)"
+	}
+
+	return rule.String()
+}
+
+// checkRemovalThresholds checks the given statistics against --max-removal-ratio and --max-removed.
+//
+// Args:
+//
+//	stats: The statistics of the run.
+//	options: The options holding the thresholds.
+//
+// Returns:
+//
+//	error: An error describing the exceeded threshold, nil if the thresholds hold.
+func checkRemovalThresholds(stats *removalStats, options *cleanupOptions) error {
+	if options.MaxRemoved > 0 && stats.Removed > options.MaxRemoved {
+		return fmt.Errorf("the whitelist would remove %d of %d subjects, more than the allowed %d", stats.Removed, stats.Subjects, options.MaxRemoved)
+	}
+
+	if options.MaxRemovalRatio > 0 && stats.ratio() > options.MaxRemovalRatio {
+		return fmt.Errorf("the whitelist would remove %d of %d subjects (%.2f%%), more than the allowed %.2f%%", stats.Removed, stats.Subjects, stats.ratio()*100, options.MaxRemovalRatio*100)
+	}
+
+	return nil
+}
+
+// hasRemovalThresholds checks if at least one removal threshold was given.
+func (o *cleanupOptions) hasRemovalThresholds() bool {
+	return o.MaxRemovalRatio > 0 || o.MaxRemoved > 0
+}
+
+// cleanupLine checks the subjects of the given source line against the ruler.
+//
+// Args:
+//
+//	line: The line to cleanup.
+//	parser: The parser of the source format.
+//	renderer: The renderer of the output format, nil to write back the source lines.
+//	removedRenderer: The renderer of the removed entries, nil to write back the source lines.
+//	ruler: The ruler to check the subjects against.
+//	stats: The statistics to record the removed subjects into.
+//	form: The form to write the internationalized domains in, empty to keep them as found.
+//
+// Returns:
+//
+//	[]string: The lines to keep. When no renderer is given, it is the line itself,
+//	rewritten if only some of its subjects are whitelisted.
+//	[]string: The lines of the removed (whitelisted) entries, built the same way.
+func cleanupLine(line string, parser formats.Parser, renderer formats.Renderer, removedRenderer formats.Renderer, ruler givilsta.GivilstaRuler, stats *removalStats, form givilsta.LabelForm) ([]string, []string) {
+	if strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+
+	stats.Lines++
+
+	record := parser.Parse(line)
+
+	if len(record.Subjects) == 0 {
+		stats.Skipped++
+
+		if renderer != nil {
+			// Comments and records of the source format mean nothing in the output format.
+			return nil, nil
+		}
+
+		return []string{line}, nil
+	}
+
+	var blacklisted, whitelisted []string
+
+	for _, subject := range record.Subjects {
+		stats.Subjects++
+
+		rule, isWhitelisted := subjectWhitelistingRule(ruler, subject, record.Wildcard)
+
+		if !isWhitelisted {
+			blacklisted = append(blacklisted, subject)
+			continue
+		}
+
+		stats.record(rule)
+		whitelisted = append(whitelisted, subject)
+	}
+
+	stats.recordLossy(renderer, parser.Format(), record.Wildcard, len(blacklisted))
+	stats.recordLossy(removedRenderer, parser.Format(), record.Wildcard, len(whitelisted))
+
+	return renderSubjects(line, record, parser, renderer, blacklisted, form), renderSubjects(line, record, parser, removedRenderer, whitelisted, form)
+}
+
+// subjectWhitelistingRule checks if the given source subject is whitelisted.
+// A wildcard subject is whitelisted when the whole wildcard is, even if no single
+// rule can be attributed to it.
+//
+// Args:
+//
+//	ruler: The ruler to check the subject against.
+//	subject: The subject to check.
+//	wildcard: Whether the subject comes from a wildcard record.
+//
+// Returns:
+//
+//	givilsta.Rule: The rule which whitelists the subject, the zero rule when unknown.
+//	bool: true if the subject is whitelisted, false otherwise.
+func subjectWhitelistingRule(ruler givilsta.GivilstaRuler, subject string, wildcard bool) (givilsta.Rule, bool) {
+	if !wildcard {
+		return ruler.WhitelistingRule(subject)
+	}
+
+	if !ruler.IsSubjectWildcardWhitelisted(subject) {
+		return givilsta.Rule{}, false
+	}
+
+	rule, _ := ruler.WhitelistingRule(subject)
+
+	return rule, true
+}
+
+// renderSubjects renders the given subjects of a source line.
+//
+// Args:
+//
+//	line: The source line.
+//	record: The parsed source line.
+//	parser: The parser of the source format.
+//	renderer: The renderer of the output format, nil to write back the source line.
+//	subjects: The subjects of the line to render.
+//	form: The form to write the internationalized domains in, empty to keep them as found.
+//
+// Returns:
+//
+//	[]string: The lines to write. When no renderer is given, it is the line itself,
+//	rewritten if only some of its subjects are given.
+func renderSubjects(line string, record formats.Record, parser formats.Parser, renderer formats.Renderer, subjects []string, form givilsta.LabelForm) []string {
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	if renderer != nil {
+		var result []string
+
+		for _, subject := range subjects {
+			result = append(result, renderer.Render(givilsta.FormatDomain(subject, form), record.Wildcard)...)
+		}
+
+		return result
+	}
+
+	if len(subjects) == len(record.Subjects) {
+		return []string{line}
+	}
+
+	return []string{parser.Rebuild(record, subjects)}
+}
+
+// detectSourceFormat detects the format of the given source from the first
+// lines held by the buffer of its stream.
+//
+// Args:
+//
+//	source: The source to detect the format of.
+//	reader: The buffered stream of the source.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	formats.Format: The detected format.
+//	error: An error if the format cannot be detected.
+func detectSourceFormat(source sourceInput, reader *bufio.Reader, logger *slog.Logger) (formats.Format, error) {
+	format, reason, err := formats.DetectReader(reader, formats.DefaultDetectionSampleSize)
+
+	if err != nil {
+		logger.Error("Unable to detect the source format.", slog.String("source", source.Name), slog.String("error", err.Error()))
+		return "", err
+	}
+
+	logger.Info("Detected source format.", slog.String("source", source.Name), slog.String("format", string(format)), slog.String("reason", reason))
+
+	return format, nil
+}
+
+// newRenderer creates the renderer of the requested output format.
+//
+// Returns:
+//
+//	formats.Renderer: The renderer to use, nil if the lines are written back in their source format.
+//	error: An error if the output format is not supported.
+func newRenderer(options *cleanupOptions, logger *slog.Logger) (formats.Renderer, error) {
+	if options.OutputFormat == "" {
+		return nil, nil
+	}
+
+	renderer, err := formats.NewRenderer(formats.Format(options.OutputFormat), formats.RendererOptions{
+		SinkIP:       options.SinkIP,
+		HostsPerLine: options.HostsPerLine,
+		Serial:       uint32(time.Now().Unix()),
+	})
+
+	if err != nil {
+		logger.Error("Unsupported output format.", slog.String("format", options.OutputFormat))
+		return nil, err
+	}
+
+	return renderer, nil
+}
+
+// newParser creates the parser of the given source.
+//
+// Args:
+//
+//	source: The source to parse.
+//	reader: The buffered stream of the source, sampled to detect its format.
+//	sourceFormat: The format of the source, auto to detect it.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	formats.Parser: The parser of the source.
+//	error: An error if the format is not supported or cannot be detected.
+func newParser(source sourceInput, reader *bufio.Reader, sourceFormat string, logger *slog.Logger) (formats.Parser, error) {
+	format := formats.Format(strings.ToLower(sourceFormat))
+
+	if format == formats.FormatAuto {
+		var err error
+
+		if format, err = detectSourceFormat(source, reader, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	parser, err := formats.NewParser(format)
+
+	if err != nil {
+		logger.Error("Unsupported source format.", slog.String("format", sourceFormat))
+		return nil, err
+	}
+
+	return parser, nil
+}
+
+// readSource opens the stream of the given source and hands it to the given function.
+//
+// Args:
+//
+//	source: The source to read.
+//	logger: The logger to use.
+//	read: The function reading the stream.
+//
+// Returns:
+//
+//	error: An error if the source cannot be opened or closed, or the error of the function.
+func readSource(source sourceInput, logger *slog.Logger, read func(reader io.Reader) error) (err error) {
+	reader, err := source.open()
+	if err != nil {
+		logger.Error("Error opening source.", slog.String("source", source.Name), slog.String("error", err.Error()))
+		return fmt.Errorf("opening source '%s': %w", source.Name, err)
+	}
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("closing source '%s': %w", source.Name, closeErr)
+		}
+	}()
+
+	return read(reader)
+}
+
+// iterSource reads the given source line by line, with the parser of its format.
+// The format is detected from the first lines of the stream, which are then read
+// like the other ones: the source is only read once.
+//
+// Args:
+//
+//	source: The source to read.
+//	sourceFormat: The format of the source, auto to detect it.
+//	logger: The logger to use.
+//	yield: The function to call with the parser of the source and each of its lines.
+//
+// Returns:
+//
+//	error: An error if the source cannot be read, or if its format is not
+//	supported or cannot be detected.
+func iterSource(source sourceInput, sourceFormat string, logger *slog.Logger, yield func(parser formats.Parser, line string)) error {
+	return readSource(source, logger, func(reader io.Reader) error {
+		buffered := bufio.NewReaderSize(reader, formats.DetectionBufferSize)
+
+		parser, err := newParser(source, buffered, sourceFormat, logger)
+		if err != nil {
+			return err
+		}
+
+		logger.Debug("Reading source.", slog.String("source", source.Name), slog.String("format", string(parser.Format())))
+
+		err = helpers.IterReaderUntil(buffered, func(line string) bool {
+			yield(parser, line)
+			return true
+		})
+
+		if err != nil {
+			logger.Error("Error reading source.", slog.String("source", source.Name), slog.String("error", err.Error()))
+			return fmt.Errorf("reading source '%s': %w", source.Name, err)
+		}
+
+		return nil
+	})
+}
+
+// outputStream is a stream of lines to write, rendered with its own renderer.
+type outputStream struct {
+	// renderer is the renderer of the output format, nil to write back the source lines.
+	renderer formats.Renderer
+	// yield is the function to call with each line to write.
+	yield func(string)
+}
+
+// newOutputStream creates a stream writing to the given function.
+//
+// Returns:
+//
+//	*outputStream: The stream, nil when the given function is nil (the lines are discarded).
+//	error: An error if the output format is not supported.
+func newOutputStream(yield func(string), options *cleanupOptions, logger *slog.Logger) (*outputStream, error) {
+	if yield == nil {
+		return nil, nil
+	}
+
+	if options.Dedupe {
+		seen := make(map[string]struct{})
+		write := yield
+
+		yield = func(line string) {
+			if _, ok := seen[line]; ok {
+				return
+			}
+
+			seen[line] = struct{}{}
+			write(line)
+		}
+	}
+
+	renderer, err := newRenderer(options, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &outputStream{renderer: renderer, yield: yield}, nil
+}
+
+// getRenderer returns the renderer of the stream, nil for a discarded stream.
+func (o *outputStream) getRenderer() formats.Renderer {
+	if o == nil {
+		return nil
+	}
+
+	return o.renderer
+}
+
+// write writes the given lines into the stream.
+func (o *outputStream) write(lines []string) {
+	if o == nil {
+		return
+	}
+
+	for _, line := range lines {
+		o.yield(line)
+	}
+}
+
+// header writes the header of the output format into the stream.
+func (o *outputStream) header() {
+	if o != nil && o.renderer != nil {
+		o.write(o.renderer.Header())
+	}
+}
+
+// flush writes the pending lines of the output format into the stream.
+func (o *outputStream) flush() {
+	if o != nil && o.renderer != nil {
+		o.write(o.renderer.Flush())
+	}
+}
+
+// cleanupSources cleans up the given sources and yields the lines to write.
+//
+// Args:
+//
+//	sources: The sources to cleanup.
+//	options: The options of the cleanup.
+//	ruler: The ruler to check the subjects against.
+//	stats: The statistics to record the removed subjects into.
+//	logger: The logger to use.
+//	yield: The function to call with each line to keep, nil to discard them.
+//	yieldRemoved: The function to call with each removed line, nil to discard them.
+//
+// Returns:
+//
+//	error: An error if the output or source format is not supported, the source
+//	format cannot be detected or a source cannot be read.
+func cleanupSources(sources []sourceInput, options *cleanupOptions, ruler givilsta.GivilstaRuler, stats *removalStats, logger *slog.Logger, yield func(string), yieldRemoved func(string)) error {
+	kept, err := newOutputStream(yield, options, logger)
+	if err != nil {
+		return err
+	}
+
+	removed, err := newOutputStream(yieldRemoved, options, logger)
+	if err != nil {
+		return err
+	}
+
+	kept.header()
+	removed.header()
+
+	for _, source := range sources {
+		logger.Debug("Cleaning up source.", slog.String("source", source.Name))
+
+		err := iterSource(source, options.SourceFormat, logger, func(parser formats.Parser, line string) {
+			keptLines, removedLines := cleanupLine(line, parser, kept.getRenderer(), removed.getRenderer(), ruler, stats, givilsta.LabelForm(options.LabelForm))
+
+			kept.write(keptLines)
+			removed.write(removedLines)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	kept.flush()
+	removed.flush()
+
+	return nil
+}
+
+// stdoutLock serializes the writes to stdout of the cleanups running concurrently.
+var stdoutLock sync.Mutex
+
+// pendingOutput is an output written into a temporary file, which is copied
+// to the output file once committed.
+type pendingOutput struct {
+	// targetFile is the output file, stdout if empty.
+	targetFile string
+	// tempFile is the temporary file holding the lines to write.
+	tempFile string
+}
+
+// stageOutput writes the lines yielded by the given iterator into a temporary
+// file, to be committed to the given output file.
+//
+// Args:
+//
+//	targetFile: The output file, stdout if empty.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//	iter: The iterator yielding the lines to write.
+//
+// Returns:
+//
+//	*pendingOutput: The output to commit.
+//	error: An error if the iterator fails or the temporary file cannot be written.
+func stageOutput(targetFile string, dirName string, logger *slog.Logger, iter func(func(string)) error) (*pendingOutput, error) {
+	targetTempFile, err := os.CreateTemp(dirName, "output-*.list"+compressionExtension(targetFile))
+
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	if err := targetTempFile.Close(); err != nil {
+		return nil, fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	var iterErr error
+
+	err = helpers.WriteFileFromIter(targetTempFile.Name(), func(yield func(string)) {
+		logger.Debug("Writing output to file.", slog.String("file", targetFile))
+		iterErr = iter(yield)
+	})
+
+	if iterErr != nil {
+		return nil, iterErr
+	}
+
+	if err != nil {
+		logger.Error("Error writing temporary file.", slog.String("tempFile", targetTempFile.Name()), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("writing temporary file '%s': %w", targetTempFile.Name(), err)
+	}
+
+	return &pendingOutput{targetFile: targetFile, tempFile: targetTempFile.Name()}, nil
+}
+
+// commit writes the staged lines into the output file.
+//
+// Returns:
+//
+//	error: An error if the output file cannot be written.
+func (o *pendingOutput) commit(logger *slog.Logger) error {
+	if o.targetFile == "" {
+		logger.Debug("No output file specified, printing to stdout.")
+
+		stdoutLock.Lock()
+		defer stdoutLock.Unlock()
+
+		return helpers.IterFile(o.tempFile, func(line string) {
+			fmt.Println(line)
+		})
+	}
+
+	// We do not have the guarantee that both temp and output files are in
+	// the same filesystem, so we copy the temp file to the output file.
+	if err := helpers.CopyFile(o.tempFile, o.targetFile); err != nil {
+		logger.Error("Error copying temporary file to output file.", slog.String("tempFile", o.tempFile), slog.String("outputFile", o.targetFile), slog.String("error", err.Error()))
+		return fmt.Errorf("copying temporary file '%s' to output file '%s': %w", o.tempFile, o.targetFile, err)
+	}
+
+	return nil
+}
+
+// writeOutput writes the lines yielded by the given iterator into the given output file.
+// The lines are first written into a temporary file, which is then copied to the output file.
+// Without output file, the lines are printed to stdout as they are yielded.
+//
+// Args:
+//
+//	targetFile: The output file, stdout if empty.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//	iter: The iterator yielding the lines to write.
+//
+// Returns:
+//
+//	error: An error if the iterator fails or the output cannot be written.
+func writeOutput(targetFile string, dirName string, logger *slog.Logger, iter func(func(string)) error) error {
+	if targetFile == "" {
+		logger.Debug("No output file specified, printing to stdout.")
+
+		stdoutLock.Lock()
+		defer stdoutLock.Unlock()
+
+		return iter(func(line string) {
+			fmt.Println(line)
+		})
+	}
+
+	output, err := stageOutput(targetFile, dirName, logger, iter)
+	if err != nil {
+		return err
+	}
+
+	return output.commit(logger)
+}
+
+// compressionExtension returns the extension of the given file when it is a
+// compression extension, so that temporary files get compressed the same way.
+func compressionExtension(targetFile string) string {
+	if helpers.CompressionFromPath(targetFile) == helpers.CompressionNone {
+		return ""
+	}
+
+	return filepath.Ext(targetFile)
+}
+
+// processCleanup loads the rules given by the flags and runs the cleanup.
+//
+// Returns:
+//
+//	error: An error if the rules cannot be loaded or the cleanup failed.
+func processCleanup() error {
+	options := newCleanupOptions()
+	ruler := newRuler(options.Rules)
+	logger := ruler.Logger()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	start := time.Now()
+	err := loadRules(ruler, options.Rules, dirName, logger)
+	loadTime := elapsedMilliseconds(start)
+
+	if err != nil {
+		return err
+	}
+
+	return runCleanup(&options, ruler, loadTime, dirName, logger)
+}
+
+// runCleanup cleans up the sources against the given, already loaded, ruler.
+//
+// Args:
+//
+//	options: The options of the cleanup.
+//	ruler: The ruler to check the subjects against.
+//	loadTime: The time spent loading the rules, in milliseconds, for the report.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	error: An error if the cleanup failed. When a removal threshold is
+//	exceeded, no output is written but the report is.
+func runCleanup(options *cleanupOptions, ruler givilsta.GivilstaRuler, loadTime int64, dirName string, logger *slog.Logger) error {
+	timings := reportTimings{Load: loadTime}
+
+	start := time.Now()
+	sources, err := resolveSources(options.Sources, logger)
+	timings.Fetch = elapsedMilliseconds(start)
+
+	if err != nil {
+		return err
+	}
+
+	total := newRemovalStats()
+	start = time.Now()
+
+	// refused is the error of the removal thresholds, set when the outputs are left untouched.
+	var refused error
+
+	// The removed entries of all the sources are merged into the removed output.
+	err = writeRemovedOutput(options, dirName, logger, func(yieldRemoved func(string)) error {
+		// Without thresholds to check first, stdout receives the lines as they come.
+		if options.OutputDir == "" && options.Output == "" && !options.hasRemovalThresholds() {
+			return writeOutput("", dirName, logger, func(yield func(string)) error {
+				kept, removed := outputStreams(options, yield, yieldRemoved)
+				return cleanupSources(sources, options, ruler, total, logger, kept, removed)
+			})
+		}
+
+		outputs, err := stageCleanupOutputs(options, sources, ruler, total, dirName, logger, yieldRemoved)
+		if err != nil {
+			return err
+		}
+
+		// Every output is committed, or none of them.
+		if refused = checkRemovalGuard(total, options); refused != nil {
+			logger.Error("Refusing to commit the outputs.", slog.String("error", refused.Error()))
+			return fmt.Errorf("output left untouched, %w", refused)
+		}
+
+		for _, output := range outputs {
+			if err := output.commit(logger); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	timings.Filter = elapsedMilliseconds(start)
+
+	warnLossy(total, options, logger)
+
+	if err != nil && refused == nil {
+		return err
+	}
+
+	// The report describes the refused run too, to find the offending rules.
+	if reportErr := writeReport(newRunReport(ruler, total, timings), options.Report, logger); reportErr != nil && err == nil {
+		return reportErr
+	}
+
+	return err
+}
+
+// warnLossy warns about the subjects the output format could not render as
+// they are, e.g. RPZ wildcards written into a hosts file.
+//
+// Args:
+//
+//	stats: The statistics of the run.
+//	options: The options holding the output format.
+//	logger: The logger to use.
+func warnLossy(stats *removalStats, options *cleanupOptions, logger *slog.Logger) {
+	losses := make([]string, 0, len(stats.Lossy))
+
+	for loss := range stats.Lossy {
+		losses = append(losses, loss)
+	}
+
+	sort.Strings(losses)
+
+	for _, loss := range losses {
+		logger.Warn("Lossy output format conversion.", slog.String("format", options.OutputFormat), slog.String("loss", loss), slog.Int("subjects", stats.Lossy[loss]))
+	}
+}
+
+// stageCleanupOutputs cleans up the sources into the outputs to commit: one
+// output per source with --output-dir, a single one otherwise.
+//
+// Args:
+//
+//	options: The options of the cleanup.
+//	sources: The sources to cleanup.
+//	ruler: The ruler to check the subjects against.
+//	stats: The statistics to record the removed subjects of all the sources into.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//	yieldRemoved: The function to call with each removed line, nil to discard them.
+//
+// Returns:
+//
+//	[]*pendingOutput: The outputs to commit.
+//	error: An error if a source cannot be cleaned up or an output cannot be staged.
+func stageCleanupOutputs(options *cleanupOptions, sources []sourceInput, ruler givilsta.GivilstaRuler, stats *removalStats, dirName string, logger *slog.Logger, yieldRemoved func(string)) ([]*pendingOutput, error) {
+	if options.OutputDir == "" {
+		output, err := stageOutput(options.Output, dirName, logger, func(yield func(string)) error {
+			kept, removed := outputStreams(options, yield, yieldRemoved)
+			return cleanupSources(sources, options, ruler, stats, logger, kept, removed)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []*pendingOutput{output}, nil
+	}
+
+	if err := os.MkdirAll(options.OutputDir, 0o755); err != nil {
+		logger.Error("Error creating output directory.", slog.String("dir", options.OutputDir), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("creating output directory '%s': %w", options.OutputDir, err)
+	}
+
+	outputs := make([]*pendingOutput, 0, len(sources))
+
+	for index, source := range sources {
+		output, err := stageOutput(filepath.Join(options.OutputDir, source.OutputName), dirName, logger, func(yield func(string)) error {
+			kept, removed := outputStreams(options, yield, yieldRemoved)
+			return cleanupSources(sources[index:index+1], options, ruler, stats, logger, kept, removed)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// outputStreams returns the functions receiving the kept and the removed
+// lines. In invert mode, the output only receives the removed lines.
+//
+// Args:
+//
+//	options: The options of the cleanup.
+//	yield: The function writing into the output.
+//	yieldRemoved: The function writing into the removed output, nil if there is none.
+//
+// Returns:
+//
+//	func(string): The function to call with each kept line, nil to discard them.
+//	func(string): The function to call with each removed line, nil to discard them.
+func outputStreams(options *cleanupOptions, yield func(string), yieldRemoved func(string)) (func(string), func(string)) {
+	if options.Invert {
+		return nil, yield
+	}
+
+	return yield, yieldRemoved
+}
+
+// writeRemovedOutput writes the removed lines yielded by the given iterator into
+// the removed output. When no removed output is requested, the iterator is
+// called with a nil function.
+//
+// Args:
+//
+//	options: The options of the cleanup.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//	iter: The iterator yielding the removed lines.
+//
+// Returns:
+//
+//	error: The error of the iterator, or of the removed output.
+func writeRemovedOutput(options *cleanupOptions, dirName string, logger *slog.Logger, iter func(func(string)) error) error {
+	if options.RemovedOutput == "" {
+		return iter(nil)
+	}
+
+	return writeOutput(options.RemovedOutput, dirName, logger, iter)
+}
+
+// checkRemovalGuard enforces the removal thresholds on the given statistics.
+//
+// Returns:
+//
+//	error: An error listing the rules which removed the most subjects when a
+//	threshold is exceeded, nil otherwise.
+func checkRemovalGuard(stats *removalStats, options *cleanupOptions) error {
+	err := checkRemovalThresholds(stats, options)
+
+	if err == nil {
+		return nil
+	}
+
+	var message strings.Builder
+
+	fmt.Fprintf(&message, "%v.\nTop rules by removed subjects:", err)
+
+	for _, rule := range stats.topRules(maxOffendingRules) {
+		fmt.Fprintf(&message, "\n  %8d  %s", stats.Rules[rule], ruleName(rule))
+	}
+
+	return errors.New(message.String())
+}
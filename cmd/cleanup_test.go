@@ -0,0 +1,197 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/formats"
+)
+
+func TestRunCleanupOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	outputDir, report := filepath.Join(dir, "output"), filepath.Join(dir, "report.json")
+	first, second := filepath.Join(dir, "first.list"), filepath.Join(dir, "second.list")
+
+	writeTestFile(t, first, "a.example.com", "b.example.com", "c.example.com")
+	writeTestFile(t, second, "example.org", "example.net")
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"first.list", "second.list"} {
+		writeTestFile(t, filepath.Join(outputDir, name), "previous")
+	}
+
+	ruler := newRuler(ruleOptions{})
+	ruler.AddRule("example.org")
+	ruler.AddRule("example.net")
+
+	options := cleanupOptions{
+		Sources:      []string{first, second},
+		SourceFormat: string(formats.FormatAuto),
+		OutputDir:    outputDir,
+		Report:       report,
+	}
+
+	// The first source removes nothing, the second one only holds removed
+	// subjects: the thresholds apply to the sources together.
+
+	tests := []struct {
+		name      string
+		maxRemove int
+		refused   bool
+		want      map[string]string
+	}{
+		{"threshold exceeded", 1, true, map[string]string{"first.list": "previous\n", "second.list": "previous\n"}},
+		{"threshold respected", 2, false, map[string]string{"first.list": "a.example.com\nb.example.com\nc.example.com\n", "second.list": ""}},
+	}
+
+	for _, test := range tests {
+		options.MaxRemoved = test.maxRemove
+
+		if err := os.Remove(report); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+
+		err := runCleanup(&options, ruler, 0, t.TempDir(), slog.New(slog.DiscardHandler))
+
+		if (err != nil) != test.refused {
+			t.Errorf("%s: runCleanup() = %v; want refused: %v", test.name, err, test.refused)
+		}
+
+		for name, want := range test.want {
+			if content, _ := os.ReadFile(filepath.Join(outputDir, name)); string(content) != want {
+				t.Errorf("%s: %s = %q; want %q", test.name, name, content, want)
+			}
+		}
+
+		var written runReport
+
+		content, err := os.ReadFile(report)
+		if err != nil {
+			t.Errorf("%s: no report written: %v", test.name, err)
+			continue
+		}
+
+		if err := json.Unmarshal(content, &written); err != nil || written.Totals.Removed != 2 || written.Totals.Subjects != 5 {
+			t.Errorf("%s: report totals = %+v (%v); want 2 of 5 subjects removed", test.name, written.Totals, err)
+		}
+	}
+}
+
+func TestCleanupLineLossy(t *testing.T) {
+	ruler := newRuler(ruleOptions{})
+	ruler.AddRule("example.org")
+
+	tests := []struct {
+		sourceFormat formats.Format
+		outputFormat formats.Format
+		line         string
+		expected     map[string]int
+	}{
+		{formats.FormatRPZ, formats.FormatHosts, "*.example.com CNAME .", map[string]int{formats.LossSubdomainsDropped: 1}},
+		{formats.FormatRPZ, formats.FormatRPZ, "*.example.com CNAME .", map[string]int{}},
+		{formats.FormatHosts, formats.FormatABP, "0.0.0.0 example.com example.net", map[string]int{formats.LossExactWidened: 2}},
+		{formats.FormatHosts, formats.FormatABP, "0.0.0.0 example.com example.org", map[string]int{formats.LossExactWidened: 2}},
+		{formats.FormatABP, formats.FormatUnbound, "||example.com^", map[string]int{}},
+		{formats.FormatABP, formats.FormatHosts, "||example.com^", map[string]int{formats.LossSubdomainsDropped: 1}},
+		{formats.FormatHosts, formats.FormatHosts, "0.0.0.0 example.com", map[string]int{}},
+	}
+
+	for _, test := range tests {
+		parser, err := formats.NewParser(test.sourceFormat)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		renderer, _ := formats.NewRenderer(test.outputFormat, formats.RendererOptions{})
+		removedRenderer, _ := formats.NewRenderer(test.outputFormat, formats.RendererOptions{})
+
+		stats := newRemovalStats()
+		cleanupLine(test.line, parser, renderer, removedRenderer, ruler, stats, "")
+
+		if !reflect.DeepEqual(stats.Lossy, test.expected) {
+			t.Errorf("cleanupLine(%q, %s -> %s) lossy = %v; want %v", test.line, test.sourceFormat, test.outputFormat, stats.Lossy, test.expected)
+		}
+	}
+}
+
+func TestRunCleanupRPZPassthru(t *testing.T) {
+	dir := t.TempDir()
+	source, output := filepath.Join(dir, "zone.rpz"), filepath.Join(dir, "clean.rpz")
+
+	writeTestFile(t, source, "*.example.com CNAME .", "good.example.com CNAME rpz-passthru.", "ads.example.org CNAME .")
+
+	ruler := newRuler(ruleOptions{})
+	ruler.AddRule("good.example.com")
+	ruler.AddRule("ads.example.org")
+
+	options := cleanupOptions{
+		Sources:      []string{source},
+		SourceFormat: string(formats.FormatRPZ),
+		Output:       output,
+	}
+
+	if err := runCleanup(&options, ruler, 0, t.TempDir(), slog.New(slog.DiscardHandler)); err != nil {
+		t.Fatalf("runCleanup() returned error: %v", err)
+	}
+
+	// The passthru exception is kept, only the blocking record is removed.
+	want := "*.example.com CNAME .\ngood.example.com CNAME rpz-passthru.\n"
+
+	if content, _ := os.ReadFile(output); string(content) != want {
+		t.Errorf("runCleanup() output = %q; want %q", content, want)
+	}
+}
+
+func TestIterSourceURL(t *testing.T) {
+	lines := []string{"# comment", "0.0.0.0 example.com", "0.0.0.0 example.org"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	}))
+	defer server.Close()
+
+	sources, err := resolveSources([]string{server.URL + "/hosts.txt"}, slog.New(slog.DiscardHandler))
+	if err != nil || len(sources) != 1 || sources[0].OutputName != "hosts.txt" {
+		t.Fatalf("resolveSources() = %+v, %v; want the hosts.txt source", sources, err)
+	}
+
+	var read []string
+
+	// The detection samples the first lines of the stream, which are still read.
+	err = iterSource(sources[0], string(formats.FormatAuto), slog.New(slog.DiscardHandler), func(parser formats.Parser, line string) {
+		if parser.Format() != formats.FormatHosts {
+			t.Errorf("iterSource() parser = %q; want %q", parser.Format(), formats.FormatHosts)
+		}
+
+		read = append(read, line)
+	})
+
+	if err != nil || !reflect.DeepEqual(read, lines) {
+		t.Errorf("iterSource() = %q, %v; want %q", read, err, lines)
+	}
+}
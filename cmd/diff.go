@@ -0,0 +1,419 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/funilrys/givilsta/internal/formats"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+	"github.com/spf13/cobra"
+)
+
+// Changes reported by the diff command.
+const (
+	// diffChangeWhitelisted: the subject is whitelisted by the new rules only.
+	diffChangeWhitelisted = "whitelisted"
+	// diffChangeBlocked: the subject is whitelisted by the old rules only.
+	diffChangeBlocked = "blocked"
+)
+
+var diffOldFiles []string
+var diffNewFiles []string
+var diffAddRules []string
+var diffRemoveRules []string
+var diffJSON bool
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Preview the effect of a whitelist change on the sources.",
+	Long: `Preview the effect of a whitelist change on the sources.
+
+Two rulers are built: the old one from the --old files, the new one from the
+--new files (the --old ones when none is given), the --add-rule and the
+--remove-rule. The rules of the usual whitelist and bypass flags are loaded
+into both of them, the bypass files last: they also remove the rules of the
+--old and --new files.
+
+An --old or --new file is read as a whitelist file, unless prefixed by the type
+of its rules (e.g. 'ALL:whitelist-all.list', 'REG:regex.list'). A local file
+can be read at a git revision with the 'file@revision' form (e.g.
+'whitelist.list@v1', 'whitelist.list@HEAD~1').
+
+Each source subject whose status differs between the two rulers is reported,
+with the rule responsible for the change: the new rule when the subject becomes
+whitelisted, the old rule when it becomes blocked again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(sourceFiles) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: source must be specified.")
+			os.Exit(1)
+		}
+
+		if len(diffNewFiles) == 0 && len(diffAddRules) == 0 && len(diffRemoveRules) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: at least one of new, add-rule or remove-rule must be specified.")
+			os.Exit(1)
+		}
+
+		setupLogger()
+
+		exitOnError(processDiff())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringSliceVarP(&sourceFiles, "source", "s", []string{}, `The source to preview the change on. Can be a file, a directory, a glob pattern, an URL or '-' to read from stdin.
+Can be specified multiple times.`)
+	diffCmd.Flags().StringVarP(&sourceFormat, "source-format", "f", string(formats.FormatAuto), `The format of the source file. Can be one of: auto, plain, hosts, abp, dnsmasq, unbound, rpz.`)
+	diffCmd.Flags().StringSliceVar(&diffOldFiles, "old", []string{}, "The whitelist file describing the old rules, optionally prefixed by its type (e.g. 'ALL:whitelist-all.list') or\nsuffixed by a git revision (e.g. 'whitelist.list@v1').\nCan be specified multiple times.")
+	diffCmd.Flags().StringSliceVar(&diffNewFiles, "new", []string{}, "The whitelist file describing the new rules, optionally prefixed by its type (e.g. 'ALL:whitelist-all.list') or\nsuffixed by a git revision (e.g. 'whitelist.list@v2').\nCan be specified multiple times.")
+	diffCmd.Flags().StringArrayVar(&diffAddRules, "add-rule", []string{}, "A rule to add to the new rules (e.g. 'ALL .example.org').\nCan be specified multiple times.")
+	diffCmd.Flags().StringArrayVar(&diffRemoveRules, "remove-rule", []string{}, "A rule to remove from the new rules.\nCan be specified multiple times.")
+	diffCmd.Flags().BoolVarP(&diffJSON, "json", "j", false, "Whether to print the changes as JSON (one object per line) or not.")
+
+	addRuleFlags(diffCmd)
+}
+
+// diffEntry is a source subject whose status changes between the old and the new rules.
+type diffEntry struct {
+	// Subject is the source subject.
+	Subject string `json:"subject"`
+	// Source is the source the subject was first read from.
+	Source string `json:"source"`
+	// Change is either "whitelisted" or "blocked".
+	Change string `json:"change"`
+	// Rule is the rule responsible for the change, empty when unknown.
+	Rule string `json:"rule,omitempty"`
+	// Kind is the kind of the rule responsible for the change.
+	Kind string `json:"kind,omitempty"`
+	// Origin is where the rule responsible for the change comes from.
+	Origin string `json:"origin,omitempty"`
+}
+
+// newDiffEntry describes the change of a subject, attributed to the given rule.
+func newDiffEntry(subject string, source string, change string, ruler givilsta.GivilstaRuler, rule givilsta.Rule) diffEntry {
+	entry := diffEntry{Subject: subject, Source: source, Change: change}
+
+	if rule.Kind != "" {
+		entry.Rule = rule.String()
+		entry.Kind = rule.Kind
+		entry.Origin = ruler.RuleOrigin(rule)
+	}
+
+	return entry
+}
+
+// diffOptions describes a diff run.
+type diffOptions struct {
+	// Rules are the rules loaded into both rulers.
+	Rules ruleOptions
+	// Sources are the sources to preview the change on.
+	Sources []string
+	// SourceFormat is the format of the sources.
+	SourceFormat string
+	// OldFiles are the whitelist files of the old rules.
+	OldFiles []string
+	// NewFiles are the whitelist files of the new rules, the old ones when empty.
+	NewFiles []string
+	// AddRules are the rules added to the new rules.
+	AddRules []string
+	// RemoveRules are the rules removed from the new rules.
+	RemoveRules []string
+}
+
+// newDiffOptions returns the diff options given by the flags.
+func newDiffOptions() diffOptions {
+	return diffOptions{
+		Rules:        newRuleOptions(),
+		Sources:      sourceFiles,
+		SourceFormat: sourceFormat,
+		OldFiles:     diffOldFiles,
+		NewFiles:     diffNewFiles,
+		AddRules:     diffAddRules,
+		RemoveRules:  diffRemoveRules,
+	}
+}
+
+// processDiff builds the old and the new rulers and reports the source subjects
+// whose status differs between them.
+//
+// Returns:
+//
+//	error: An error if the rules cannot be loaded, the sources read or the changes written.
+func processDiff() error {
+	options := newDiffOptions()
+	logger := slog.Default()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	encoder := json.NewEncoder(os.Stdout)
+
+	whitelisted, blocked, err := runDiff(options, dirName, logger, func(entry diffEntry) error {
+		return printDiffEntry(entry, encoder)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if !diffJSON {
+		fmt.Printf("%d newly whitelisted, %d newly blocked.\n", whitelisted, blocked)
+	}
+
+	return nil
+}
+
+// parseDiffRuleFile parses a --old or --new value: a whitelist file, optionally
+// prefixed by the type of its rules (e.g. "ALL:whitelist-all.list").
+//
+// Args:
+//
+//	value: The value to parse.
+//
+// Returns:
+//
+//	string: The whitelist file.
+//	givilsta.Flags: The flag to load its rules with.
+func parseDiffRuleFile(value string) (string, givilsta.Flags) {
+	if ruleType, file, ok := strings.Cut(value, ":"); ok {
+		if flag, ok := ruleFileFlags[strings.ToUpper(ruleType)]; ok {
+			return file, flag
+		}
+	}
+
+	return value, givilsta.NoFlag
+}
+
+// loadDiffRules loads the rules of one side of a diff into the given ruler: the
+// usual whitelist files, the given files and rules, then the bypass files, which
+// therefore also remove the rules of the given files.
+//
+// Args:
+//
+//	ruler: The ruler to load the rules into.
+//	options: The usual whitelist and bypass files.
+//	files: The --old or --new values.
+//	addRules: The rules to add.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	error: An error if a rule file cannot be read or a rule is refused.
+func loadDiffRules(ruler givilsta.GivilstaRuler, options ruleOptions, files []string, addRules []string, dirName string, logger *slog.Logger) error {
+	fileSets := options.fileSets()
+
+	for _, fileSet := range fileSets {
+		if fileSet.bypass {
+			continue
+		}
+
+		for index, targetFile := range fileSet.files {
+			if err := processRuleFile(targetFile, fileSet.flag, index, ruler, logger, dirName, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	for index, value := range files {
+		targetFile, flag := parseDiffRuleFile(value)
+
+		if err := processRuleFile(targetFile, flag, index, ruler, logger, dirName, false); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range addRules {
+		if err := applyRule(ruler, rule, givilsta.NoFlag, false, "--add-rule"); err != nil {
+			return err
+		}
+	}
+
+	for _, fileSet := range fileSets {
+		if !fileSet.bypass {
+			continue
+		}
+
+		for index, targetFile := range fileSet.files {
+			if err := processRuleFile(targetFile, fileSet.flag, index, ruler, logger, dirName, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildDiffRulers builds the old and the new rulers of the given diff.
+//
+// Returns:
+//
+//	givilsta.GivilstaRuler: The old ruler.
+//	givilsta.GivilstaRuler: The new ruler.
+//	error: An error if the rules cannot be loaded, or if a removed rule is not loaded.
+func buildDiffRulers(options diffOptions, dirName string, logger *slog.Logger) (givilsta.GivilstaRuler, givilsta.GivilstaRuler, error) {
+	oldRuler := newRuler(options.Rules)
+	nextRuler := newRuler(options.Rules)
+
+	if err := loadDiffRules(oldRuler, options.Rules, options.OldFiles, nil, dirName, logger); err != nil {
+		return nil, nil, err
+	}
+
+	newFiles := options.NewFiles
+
+	if len(newFiles) == 0 {
+		// Without new files, the change is previewed on top of the old rules.
+		newFiles = options.OldFiles
+	}
+
+	if err := loadDiffRules(nextRuler, options.Rules, newFiles, options.AddRules, dirName, logger); err != nil {
+		return nil, nil, err
+	}
+
+	for _, rule := range options.RemoveRules {
+		loaded := len(nextRuler.Rules())
+
+		if err := applyRule(nextRuler, rule, givilsta.NoFlag, true, "--remove-rule"); err != nil {
+			return nil, nil, err
+		}
+
+		// A rule which is not loaded would silently preview no change.
+		if len(nextRuler.Rules()) == loaded {
+			logger.Error("Removed rule not loaded.", slog.String("rule", rule))
+			return nil, nil, fmt.Errorf("--remove-rule %q matches no loaded rule", rule)
+		}
+	}
+
+	return oldRuler, nextRuler, nil
+}
+
+// runDiff reports the source subjects whose status differs between the old and
+// the new rules.
+//
+// Args:
+//
+//	options: The options of the diff.
+//	dirName: The temporary directory.
+//	logger: The logger to use.
+//	emit: The function to call with each change.
+//
+// Returns:
+//
+//	int: The number of newly whitelisted subjects.
+//	int: The number of newly blocked subjects.
+//	error: An error if the rules cannot be loaded, the sources read or a change emitted.
+func runDiff(options diffOptions, dirName string, logger *slog.Logger, emit func(diffEntry) error) (int, int, error) {
+	oldRuler, nextRuler, err := buildDiffRulers(options, dirName, logger)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sources, err := resolveSources(options.Sources, logger)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	seen := make(map[string]struct{})
+	whitelisted, blocked := 0, 0
+
+	var emitErr error
+
+	for _, source := range sources {
+		err := iterSource(source, options.SourceFormat, logger, func(parser formats.Parser, line string) {
+			if emitErr != nil {
+				return
+			}
+
+			record := parser.Parse(line)
+
+			for _, subject := range record.Subjects {
+				if _, ok := seen[subject]; ok {
+					continue
+				}
+
+				seen[subject] = struct{}{}
+
+				oldRule, oldWhitelisted := subjectWhitelistingRule(oldRuler, subject, record.Wildcard)
+				newRule, newWhitelisted := subjectWhitelistingRule(nextRuler, subject, record.Wildcard)
+
+				var entry diffEntry
+
+				switch {
+				case newWhitelisted && !oldWhitelisted:
+					entry = newDiffEntry(subject, source.Name, diffChangeWhitelisted, nextRuler, newRule)
+					whitelisted++
+				case oldWhitelisted && !newWhitelisted:
+					entry = newDiffEntry(subject, source.Name, diffChangeBlocked, oldRuler, oldRule)
+					blocked++
+				default:
+					continue
+				}
+
+				logger.Debug("Subject changed.", slog.String("subject", subject), slog.String("change", entry.Change), slog.String("rule", entry.Rule))
+
+				if emitErr = emit(entry); emitErr != nil {
+					return
+				}
+			}
+		})
+
+		if emitErr != nil {
+			logger.Error("Error writing change.", slog.String("error", emitErr.Error()))
+			return 0, 0, fmt.Errorf("writing change: %w", emitErr)
+		}
+
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return whitelisted, blocked, nil
+}
+
+// printDiffEntry prints the given change, as JSON when requested.
+func printDiffEntry(entry diffEntry, encoder *json.Encoder) error {
+	if diffJSON {
+		return encoder.Encode(entry)
+	}
+
+	sign := "+"
+	verb := "whitelisted by"
+
+	if entry.Change == diffChangeBlocked {
+		sign = "-"
+		verb = "blocked, was whitelisted by"
+	}
+
+	if entry.Rule == "" {
+		fmt.Printf("%s %s: %s\n", sign, entry.Subject, entry.Change)
+		return nil
+	}
+
+	if entry.Origin != "" {
+		fmt.Printf("%s %s: %s %s (%s)\n", sign, entry.Subject, verb, entry.Rule, entry.Origin)
+		return nil
+	}
+
+	fmt.Printf("%s %s: %s %s\n", sign, entry.Subject, verb, entry.Rule)
+
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/formats"
+)
+
+// runTestDiff runs the given diff and returns the changes it reported.
+func runTestDiff(t *testing.T, options diffOptions) ([]diffEntry, error) {
+	t.Helper()
+
+	resetRuleFileCache(t)
+
+	options.SourceFormat = string(formats.FormatAuto)
+
+	var entries []diffEntry
+
+	whitelisted, blocked, err := runDiff(options, t.TempDir(), slog.New(slog.DiscardHandler), func(entry diffEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+
+	if err == nil && whitelisted+blocked != len(entries) {
+		t.Errorf("runDiff() counted %d whitelisted and %d blocked subjects; want %d changes", whitelisted, blocked, len(entries))
+	}
+
+	return entries, err
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := t.TempDir()
+	oldFile, newFile, source := filepath.Join(dir, "old.list"), filepath.Join(dir, "new.list"), filepath.Join(dir, "source.list")
+
+	allFile, bypassFile := filepath.Join(dir, "all.list"), filepath.Join(dir, "bypass.list")
+
+	writeTestFile(t, oldFile, "example.org", "ALL .ads.example.net")
+	writeTestFile(t, newFile, "example.org", "example.com")
+	writeTestFile(t, allFile, "foo.bar")
+	writeTestFile(t, bypassFile, "example.com")
+	writeTestFile(t, source, "example.org", "example.com", "track.ads.example.net", "foo.bar", "www.foo.bar", "example.com")
+
+	tests := []struct {
+		name    string
+		options diffOptions
+		want    []diffEntry
+	}{
+		{
+			"new files",
+			diffOptions{OldFiles: []string{oldFile}, NewFiles: []string{newFile}},
+			[]diffEntry{
+				{Subject: "example.com", Source: source, Change: diffChangeWhitelisted, Rule: "example.com", Kind: "PLAIN", Origin: newFile + ":2"},
+				{Subject: "track.ads.example.net", Source: source, Change: diffChangeBlocked, Rule: "ALL .ads.example.net", Kind: "ALL", Origin: oldFile + ":2"},
+			},
+		},
+		{
+			"added and removed rules",
+			diffOptions{OldFiles: []string{oldFile}, AddRules: []string{"foo.bar"}, RemoveRules: []string{"example.org"}},
+			[]diffEntry{
+				{Subject: "example.org", Source: source, Change: diffChangeBlocked, Rule: "example.org", Kind: "PLAIN", Origin: oldFile + ":1"},
+				{Subject: "foo.bar", Source: source, Change: diffChangeWhitelisted, Rule: "foo.bar", Kind: "PLAIN", Origin: "--add-rule"},
+			},
+		},
+		{
+			"bypassed new rule",
+			diffOptions{Rules: ruleOptions{Bypass: []string{bypassFile}}, OldFiles: []string{oldFile}, NewFiles: []string{newFile}},
+			[]diffEntry{
+				{Subject: "track.ads.example.net", Source: source, Change: diffChangeBlocked, Rule: "ALL .ads.example.net", Kind: "ALL", Origin: oldFile + ":2"},
+			},
+		},
+		{
+			"typed new file",
+			diffOptions{OldFiles: []string{oldFile}, NewFiles: []string{oldFile, "all:" + allFile}},
+			[]diffEntry{
+				{Subject: "foo.bar", Source: source, Change: diffChangeWhitelisted, Rule: "ALL foo.bar", Kind: "ALL", Origin: allFile + ":1"},
+				{Subject: "www.foo.bar", Source: source, Change: diffChangeWhitelisted, Rule: "ALL foo.bar", Kind: "ALL", Origin: allFile + ":1"},
+			},
+		},
+		{
+			"no change",
+			diffOptions{OldFiles: []string{oldFile}, NewFiles: []string{oldFile}},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		test.options.Sources = []string{source}
+
+		entries, err := runTestDiff(t, test.options)
+
+		if err != nil {
+			t.Errorf("%s: runDiff() returned error: %v", test.name, err)
+			continue
+		}
+
+		if !slices.Equal(entries, test.want) {
+			t.Errorf("%s: runDiff() = %+v; want %+v", test.name, entries, test.want)
+		}
+	}
+}
+
+func TestRunDiffErrors(t *testing.T) {
+	dir := t.TempDir()
+	oldFile, source := filepath.Join(dir, "old.list"), filepath.Join(dir, "source.list")
+
+	writeTestFile(t, oldFile, "example.org")
+	writeTestFile(t, source, "example.org")
+
+	tests := []struct {
+		name    string
+		options diffOptions
+		want    string
+	}{
+		{"unknown removed rule", diffOptions{OldFiles: []string{oldFile}, RemoveRules: []string{"example.com"}}, `"example.com" matches no loaded rule`},
+		{"over-broad added rule", diffOptions{OldFiles: []string{oldFile}, AddRules: []string{"ALL .com"}}, "over-broad rule"},
+		{"missing new file", diffOptions{OldFiles: []string{oldFile}, NewFiles: []string{filepath.Join(dir, "missing.list")}}, "does not exist"},
+		{"missing source", diffOptions{OldFiles: []string{oldFile}, AddRules: []string{"example.com"}, Sources: []string{filepath.Join(dir, "missing.list")}}, "does not exist"},
+	}
+
+	for _, test := range tests {
+		if test.options.Sources == nil {
+			test.options.Sources = []string{source}
+		}
+
+		if _, err := runTestDiff(t, test.options); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: runDiff() = %v; want an error containing %q", test.name, err, test.want)
+		}
+	}
+}
+
+func TestRunDiffGitRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir := t.TempDir()
+	whitelistFile, source := filepath.Join(dir, "whitelist.list"), filepath.Join(t.TempDir(), "source.list")
+
+	git := func(args ...string) {
+		command := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.org"}, args...)...)
+		command.Dir = dir
+
+		if output, err := command.CombinedOutput(); err != nil {
+			t.Fatalf("git %s returned error: %v: %s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	git("init", "--quiet")
+	writeTestFile(t, whitelistFile, "example.org")
+	git("add", "whitelist.list")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	writeTestFile(t, whitelistFile, "example.com")
+	writeTestFile(t, source, "example.org", "example.com")
+
+	entries, err := runTestDiff(t, diffOptions{Sources: []string{source}, OldFiles: []string{whitelistFile + "@v1"}, NewFiles: []string{whitelistFile}})
+	if err != nil {
+		t.Fatalf("runDiff() returned error: %v", err)
+	}
+
+	want := []diffEntry{
+		{Subject: "example.org", Source: source, Change: diffChangeBlocked, Rule: "example.org", Kind: "PLAIN", Origin: whitelistFile + "@v1:1"},
+		{Subject: "example.com", Source: source, Change: diffChangeWhitelisted, Rule: "example.com", Kind: "PLAIN", Origin: whitelistFile + ":1"},
+	}
+
+	if !slices.Equal(entries, want) {
+		t.Errorf("runDiff() = %+v; want %+v", entries, want)
+	}
+
+	if _, err := runTestDiff(t, diffOptions{Sources: []string{source}, OldFiles: []string{whitelistFile + "@v2"}, AddRules: []string{"example.com"}}); err == nil {
+		t.Errorf("runDiff() at an unknown revision returned no error")
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/dnsfilter"
+	"github.com/funilrys/givilsta/internal/formats"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+	"github.com/spf13/cobra"
+)
+
+var dnsListen string
+var dnsUpstream string
+var dnsBlockMode string
+var dnsUpstreamTimeout time.Duration
+var dnsMaxQueries int
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Answer DNS queries, blocking the names of the sources.",
+	Long: `Answer DNS queries, blocking the names of the sources.
+
+The sources are the blocklists and the whitelist and bypass files the rules, as
+for a cleanup: a name is blocked when a source lists it and no rule whitelists
+it. The blocked names are answered with NXDOMAIN, or with 0.0.0.0 and :: when
+the block mode is 'null'. The other queries are forwarded to the upstream server.
+
+The queries are answered over UDP and TCP on the same address.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(sourceFiles) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: source must be specified.")
+			os.Exit(1)
+		}
+
+		if dnsMaxQueries < 1 {
+			fmt.Fprintln(os.Stderr, "Error: max-queries must be at least 1.")
+			os.Exit(1)
+		}
+
+		if dnsBlockMode != string(dnsfilter.BlockModeNXDomain) && dnsBlockMode != string(dnsfilter.BlockModeNull) {
+			fmt.Fprintf(os.Stderr, "Error: unsupported block mode: %s.\n", dnsBlockMode)
+			os.Exit(1)
+		}
+
+		setupLogger()
+
+		exitOnError(processDNS())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dnsCmd)
+
+	dnsCmd.Flags().StringSliceVarP(&sourceFiles, "source", "s", []string{}, `The blocklist to answer from. Can be a file, a directory, a glob pattern, an URL or '-' to read from stdin.
+Can be specified multiple times.`)
+	dnsCmd.Flags().StringVarP(&sourceFormat, "source-format", "f", string(formats.FormatAuto), `The format of the source file. Can be one of: auto, plain, hosts, abp, dnsmasq, unbound, rpz.`)
+	dnsCmd.Flags().StringVar(&dnsListen, "listen", "127.0.0.1:5353", "The address to answer the queries on, over UDP and TCP.")
+	dnsCmd.Flags().StringVar(&dnsUpstream, "upstream", "1.1.1.1:53", "The address of the server the queries which are not blocked are forwarded to.")
+	dnsCmd.Flags().StringVar(&dnsBlockMode, "block-mode", string(dnsfilter.BlockModeNXDomain), `How to answer the blocked queries. Can be one of: nxdomain, null.
+With 'null', the A and AAAA queries are answered with 0.0.0.0 and ::, the other ones with an empty answer.`)
+	dnsCmd.Flags().DurationVar(&dnsUpstreamTimeout, "upstream-timeout", 5*time.Second, "The maximum time to wait for the upstream server.")
+	dnsCmd.Flags().IntVar(&dnsMaxQueries, "max-queries", 256, "The maximum number of UDP queries, and of TCP connections, answered concurrently.")
+
+	addRuleFlags(dnsCmd)
+}
+
+// processDNS answers the DNS queries until interrupted.
+//
+// Returns:
+//
+//	error: An error if the rules or the sources cannot be loaded, or the address cannot be listened on.
+func processDNS() error {
+	options := newRuleOptions()
+	ruler := newRuler(options)
+	logger := ruler.Logger()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	if err := loadRules(ruler, options, dirName, logger); err != nil {
+		return err
+	}
+
+	// The queries are answered concurrently.
+	ruler.Freeze()
+
+	blocklist, err := loadBlocklist(ruler, logger)
+	if err != nil {
+		return err
+	}
+
+	server := dnsfilter.New(blocklist, ruler.IsSubjectWhitelisted, dnsfilter.Options{
+		Upstream:   dnsUpstream,
+		Mode:       dnsfilter.BlockMode(dnsBlockMode),
+		Timeout:    dnsUpstreamTimeout,
+		MaxQueries: dnsMaxQueries,
+	}, logger)
+
+	conn, err := net.ListenPacket("udp", dnsListen)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", dnsListen, err)
+	}
+
+	listener, err := net.Listen("tcp", dnsListen)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("listening on %s: %w", dnsListen, err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	go func() {
+		<-signals
+		conn.Close()
+		listener.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Answering DNS queries on %s, %d names blocked.\n", dnsListen, blocklist.Len())
+
+	var wait sync.WaitGroup
+	errs := make([]error, 2)
+
+	wait.Add(2)
+
+	go func() {
+		defer wait.Done()
+		errs[0] = server.ServeUDP(conn)
+	}()
+
+	go func() {
+		defer wait.Done()
+		errs[1] = server.ServeTCP(listener)
+	}()
+
+	wait.Wait()
+
+	for _, err := range errs {
+		if !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadBlocklist loads the subjects of the sources which are not whitelisted,
+// as the cleanup would keep them.
+//
+// Args:
+//
+//	ruler: The ruler to check the subjects against.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	*dnsfilter.Blocklist: The blocked names.
+//	error: An error if a source cannot be read.
+func loadBlocklist(ruler givilsta.GivilstaRuler, logger *slog.Logger) (*dnsfilter.Blocklist, error) {
+	sources, err := resolveSources(sourceFiles, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	blocklist := dnsfilter.NewBlocklist()
+
+	for _, source := range sources {
+		err := iterSource(source, sourceFormat, logger, func(parser formats.Parser, line string) {
+			record := parser.Parse(line)
+
+			for _, subject := range record.Subjects {
+				if _, whitelisted := subjectWhitelistingRule(ruler, subject, record.Wildcard); whitelisted {
+					continue
+				}
+
+				if !blocklist.Add(subject, record.Wildcard) {
+					logger.Debug("Skipping subject which is not a domain.", slog.String("subject", subject))
+				}
+			}
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Info("Loaded the blocklist.", slog.Int("names", blocklist.Len()))
+
+	return blocklist, nil
+}
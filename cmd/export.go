@@ -0,0 +1,147 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/interop"
+	"github.com/spf13/cobra"
+)
+
+var exportTo string
+var exportOutputFile string
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Convert the loaded whitelist into allowlists of other tools.",
+	Long: `Convert the loaded whitelist into allowlists of other tools.
+
+The whitelist and bypass files are loaded exactly like during a cleanup, so
+that the exported allowlist reflects the effective rules.
+The rules which could not be translated faithfully are reported to stderr.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if exportTo == "" {
+			log.Fatal("Error: the format to export to must be specified.")
+		}
+
+		if !hasWhitelistFiles() {
+			log.Fatal("Error: at least one whitelist file must be specified.")
+		}
+
+		setupLogger()
+
+		exitOnError(processExport())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportTo, "format", "F", "", "The format to export to. Can be one of: adguard, pihole-regex, dnsmasq, unbound, rpz-passthru.")
+	exportCmd.Flags().StringVarP(&exportOutputFile, "output", "o", "", "The output file to write the allowlist to. If not specified, we will print to stdout.")
+
+	addRuleFlags(exportCmd)
+}
+
+// processExport converts the loaded rules into the allowlist of the requested tool.
+//
+// Returns:
+//
+//	error: An error if the format is not supported, the rules cannot be loaded or the allowlist written.
+func processExport() error {
+	options := newRuleOptions()
+	ruler := newRuler(options)
+	logger := ruler.Logger()
+
+	exporter, err := interop.NewExporter(interop.ExportFormat(exportTo), interop.ExportOptions{
+		ComplementPrefixes: options.complementPrefixes(),
+		Extensions:         ruler.KnownExtensions,
+		Serial:             uint32(time.Now().Unix()),
+	})
+
+	if err != nil {
+		logger.Error("Unsupported export format.", slog.String("format", exportTo))
+		return err
+	}
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	if err := loadRules(ruler, options, dirName, logger); err != nil {
+		return err
+	}
+
+	var issues []string
+	exported, skipped, approximated := 0, 0, 0
+
+	err = writeOutput(exportOutputFile, dirName, logger, func(yield func(string)) error {
+		seen := make(map[string]struct{})
+
+		for _, line := range exporter.Header() {
+			yield(line)
+		}
+
+		for _, rule := range ruler.Rules() {
+			lines, issue := exporter.Export(rule)
+
+			if len(lines) != 0 {
+				exported++
+			}
+
+			for _, line := range lines {
+				if _, ok := seen[line]; ok {
+					continue
+				}
+
+				seen[line] = struct{}{}
+				yield(line)
+			}
+
+			if issue == nil {
+				continue
+			}
+
+			status := "approximated"
+
+			if issue.Skipped {
+				status = "skipped"
+				skipped++
+			} else {
+				approximated++
+			}
+
+			issues = append(issues, fmt.Sprintf("%s: %s: %s", status, issue.Input, issue.Message))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stderr, issue)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d rules: %d rules skipped, %d approximated.\n", exported, skipped, approximated)
+
+	return nil
+}
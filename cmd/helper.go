@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/internal/squid"
+	"github.com/spf13/cobra"
+)
+
+var helperConcurrent bool
+
+var helperCmd = &cobra.Command{
+	Use:   "helper",
+	Short: "Answer Squid's external ACL requests on stdin.",
+	Long: `Answer Squid's external ACL requests on stdin.
+
+Each line read from stdin is a request whose first field is the URL or host to
+check (e.g. %URI or %DST). The helper replies OK when it is whitelisted and ERR
+otherwise. With --concurrent, the requests and the replies start with the
+channel ID, as Squid sends them when the concurrency option is above 0.
+
+The rules are reloaded on SIGHUP. A failed reload keeps the current rules.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !hasWhitelistFiles() {
+			fmt.Fprintln(os.Stderr, "Error: at least one whitelist file must be specified.")
+			os.Exit(errorExitCode)
+		}
+
+		setupLogger()
+
+		exitOnError(processHelper())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(helperCmd)
+
+	helperCmd.Flags().BoolVar(&helperConcurrent, "concurrent", false, "Whether the requests start with a channel ID (Squid's concurrency option above 0) or not.")
+
+	addRuleFlags(helperCmd)
+}
+
+// processHelper answers the requests read from stdin until its end.
+//
+// Returns:
+//
+//	error: An error if the rules cannot be loaded or the requests answered.
+func processHelper() error {
+	options := newRuleOptions()
+	logger := slog.Default()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	rules := ruleset.NewHolder(newRulesLoader(options, dirName, logger), logger)
+
+	if err := rules.Reload(); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	go func() {
+		for range signals {
+			logger.Info("Reloading the rules on SIGHUP.")
+
+			// The error is logged, the helper keeps answering with the current rules.
+			_ = rules.Reload()
+		}
+	}()
+
+	return squid.New(rules, helperConcurrent, logger).Serve(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/funilrys/givilsta/internal/helpers"
+	"github.com/funilrys/givilsta/internal/interop"
+	"github.com/spf13/cobra"
+)
+
+var importFrom string
+var importOutputFile string
+var importReportFile string
+
+var importCmd = &cobra.Command{
+	Use:   "import [flags] [file...]",
+	Short: "Convert allowlists of other tools into Givilsta rules.",
+	Long: `Convert allowlists of other tools into Givilsta rules.
+
+The files can be local files, URLs or '-' to read from stdin (default).
+The constructs which could not be translated faithfully are reported to stderr,
+or to the report file when specified.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if importFrom == "" {
+			log.Fatal("Error: the format to import from must be specified.")
+		}
+
+		setupLogger()
+
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+
+		exitOnError(processImport(args))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importFrom, "from", "F", "", "The format to import from. Can be one of: adguard, pihole-regex, pihole-exact, hosts.")
+	importCmd.Flags().StringVarP(&importOutputFile, "output", "o", "", "The output file to write the rules to. If not specified, we will print to stdout.")
+	importCmd.Flags().StringVar(&importReportFile, "report", "", "The file to write the report of the untranslated constructs to. If not specified, we will print to stderr.")
+}
+
+// processImport converts the given files into Givilsta rules.
+//
+// Args:
+//
+//	files: The files to import.
+//
+// Returns:
+//
+//	error: An error if the format is not supported, or the files cannot be read or the rules written.
+func processImport(files []string) error {
+	logger := slog.Default()
+
+	importer, err := interop.NewImporter(interop.ImportFormat(importFrom))
+
+	if err != nil {
+		logger.Error("Unsupported import format.", slog.String("format", importFrom))
+		return err
+	}
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	sources, err := resolveSources(files, logger)
+	if err != nil {
+		return err
+	}
+
+	var issues []string
+	imported, skipped, approximated := 0, 0, 0
+
+	err = writeOutput(importOutputFile, dirName, logger, func(yield func(string)) error {
+		for _, source := range sources {
+			err := readSource(source, logger, func(reader io.Reader) error {
+				err := interop.ImportReader(importer, reader, func(rule string) {
+					imported++
+					yield(rule)
+				}, func(issue *interop.Issue) {
+					status := "approximated"
+
+					if issue.Skipped {
+						status = "skipped"
+						skipped++
+					} else {
+						approximated++
+					}
+
+					issues = append(issues, fmt.Sprintf("%s:%d: %s: %s: %s", source.Name, issue.Line, status, issue.Input, issue.Message))
+				})
+
+				if err != nil {
+					return fmt.Errorf("reading source '%s': %w", source.Name, err)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if importReportFile != "" {
+		err := helpers.WriteFileFromIter(importReportFile, func(yield func(string)) {
+			for _, issue := range issues {
+				yield(issue)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Fprintln(os.Stderr, issue)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d rules: %d constructs skipped, %d approximated.\n", imported, skipped, approximated)
+
+	return nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// ruleOptions describes the rules to load into a ruler.
+type ruleOptions struct {
+	Whitelist     []string
+	WhitelistAll  []string
+	WhitelistReg  []string
+	WhitelistRzdb []string
+	WhitelistURL  []string
+	WhitelistRoot []string
+
+	Bypass     []string
+	BypassAll  []string
+	BypassReg  []string
+	BypassRzdb []string
+	BypassURL  []string
+	BypassRoot []string
+
+	HandleComplement   bool
+	ComplementPrefixes []string
+	AllowBroadRules    bool
+}
+
+// ruleFileSet is a list of rule files sharing the same flag.
+type ruleFileSet struct {
+	// files are the rule files.
+	files []string
+	// flag is the flag to load the rules with.
+	flag givilsta.Flags
+	// bypass tells if the rules are removed instead of added.
+	bypass bool
+}
+
+// cleanupOptions describes a cleanup run.
+type cleanupOptions struct {
+	Sources         []string
+	SourceFormat    string
+	Output          string
+	OutputDir       string
+	RemovedOutput   string
+	Invert          bool
+	Dedupe          bool
+	OutputFormat    string
+	SinkIP          string
+	HostsPerLine    int
+	LabelForm       string
+	MaxRemovalRatio float64
+	MaxRemoved      int
+	Report          string
+
+	Rules ruleOptions
+}
+
+// newRuleOptions returns the rule options given by the flags.
+func newRuleOptions() ruleOptions {
+	return ruleOptions{
+		Whitelist:          whitelistFiles,
+		WhitelistAll:       whitelistALLFiles,
+		WhitelistReg:       whitelistREGFiles,
+		WhitelistRzdb:      whitelistRZDBFiles,
+		WhitelistURL:       whitelistURLFiles,
+		WhitelistRoot:      whitelistROOTFiles,
+		Bypass:             bypassFiles,
+		BypassAll:          bypassALLFiles,
+		BypassReg:          bypassREGFiles,
+		BypassRzdb:         bypassRZDBFiles,
+		BypassURL:          bypassURLFiles,
+		BypassRoot:         bypassROOTFiles,
+		HandleComplement:   handleComplement,
+		ComplementPrefixes: complementPrefixes,
+		AllowBroadRules:    allowBroadRules,
+	}
+}
+
+// newCleanupOptions returns the cleanup options given by the flags.
+func newCleanupOptions() cleanupOptions {
+	return cleanupOptions{
+		Sources:         sourceFiles,
+		SourceFormat:    sourceFormat,
+		Output:          outputFile,
+		OutputDir:       outputDir,
+		RemovedOutput:   removedOutputFile,
+		Invert:          invert,
+		Dedupe:          dedupe,
+		OutputFormat:    outputFormat,
+		SinkIP:          sinkIP,
+		HostsPerLine:    hostsPerLine,
+		LabelForm:       labelForm,
+		MaxRemovalRatio: maxRemovalRatio,
+		MaxRemoved:      maxRemoved,
+		Report:          reportFile,
+		Rules:           newRuleOptions(),
+	}
+}
+
+// fileSets returns the rule files to load, in loading order: the whitelist
+// files first, then the bypass files.
+func (o ruleOptions) fileSets() []ruleFileSet {
+	return []ruleFileSet{
+		{files: o.Whitelist, flag: givilsta.NoFlag},
+		{files: o.WhitelistAll, flag: givilsta.FlagAll},
+		{files: o.WhitelistReg, flag: givilsta.FlagReg},
+		{files: o.WhitelistRzdb, flag: givilsta.FlagRzdb},
+		{files: o.WhitelistURL, flag: givilsta.FlagURL},
+		{files: o.WhitelistRoot, flag: givilsta.FlagRoot},
+		{files: o.Bypass, flag: givilsta.NoFlag, bypass: true},
+		{files: o.BypassAll, flag: givilsta.FlagAll, bypass: true},
+		{files: o.BypassReg, flag: givilsta.FlagReg, bypass: true},
+		{files: o.BypassRzdb, flag: givilsta.FlagRzdb, bypass: true},
+		{files: o.BypassURL, flag: givilsta.FlagURL, bypass: true},
+		{files: o.BypassRoot, flag: givilsta.FlagRoot, bypass: true},
+	}
+}
+
+// key returns a key identifying the ruleset described by the options: two
+// options with the same key build the same ruler.
+func (o ruleOptions) key() string {
+	content, _ := json.Marshal(o)
+
+	return string(content)
+}
+
+// complementPrefixes returns the complement prefixes requested by the end-user.
+//
+// Returns:
+//
+//	[]string: The complement prefixes, nil when the complements are not handled.
+func (o ruleOptions) complementPrefixes() []string {
+	if len(o.ComplementPrefixes) > 0 {
+		return o.ComplementPrefixes
+	}
+
+	if o.HandleComplement {
+		return givilsta.DefaultComplementPrefixes
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// runReport is the machine-readable summary of a cleanup run, written by --report.
+type runReport struct {
+	// Totals holds the line and subject counters of the run.
+	Totals reportTotals `json:"totals"`
+	// Timings holds the duration, in milliseconds, of each phase of the run.
+	Timings reportTimings `json:"timings_ms"`
+	// Rules holds the rules which removed at least one subject, the most hit first.
+	Rules []reportRule `json:"rules"`
+	// UnmatchedRules holds the loaded rules which removed nothing.
+	UnmatchedRules []reportRule `json:"unmatched_rules"`
+}
+
+// reportTotals holds the counters of a run.
+type reportTotals struct {
+	SourceLines  int `json:"source_lines"`
+	Subjects     int `json:"subjects"`
+	Kept         int `json:"kept"`
+	Removed      int `json:"removed"`
+	SkippedLines int `json:"skipped_lines"`
+	// Lossy is the number of subjects the output format could not render as they are.
+	Lossy int `json:"lossy"`
+}
+
+// reportTimings holds the duration of the phases of a run.
+type reportTimings struct {
+	Fetch  int64 `json:"fetch"`
+	Load   int64 `json:"load"`
+	Filter int64 `json:"filter"`
+}
+
+// reportRule describes a rule and the number of subjects it removed.
+type reportRule struct {
+	Rule   string `json:"rule"`
+	Kind   string `json:"kind"`
+	Origin string `json:"origin,omitempty"`
+	Hits   int    `json:"hits"`
+}
+
+// newRunReport builds the report of a run.
+//
+// Args:
+//
+//	ruler: The ruler the run used.
+//	stats: The statistics of the run.
+//	timings: The duration of each phase of the run.
+//
+// Returns:
+//
+//	*runReport: The report of the run.
+func newRunReport(ruler givilsta.GivilstaRuler, stats *removalStats, timings reportTimings) *runReport {
+	report := &runReport{
+		Totals: reportTotals{
+			SourceLines:  stats.Lines,
+			Subjects:     stats.Subjects,
+			Kept:         stats.Subjects - stats.Removed,
+			Removed:      stats.Removed,
+			SkippedLines: stats.Skipped,
+			Lossy:        stats.lossy(),
+		},
+		Timings:        timings,
+		Rules:          []reportRule{},
+		UnmatchedRules: []reportRule{},
+	}
+
+	for _, rule := range stats.topRules(-1) {
+		report.Rules = append(report.Rules, newReportRule(ruler, rule, stats.Rules[rule]))
+	}
+
+	for _, rule := range ruler.Rules() {
+		if _, ok := stats.Rules[rule]; ok {
+			continue
+		}
+
+		report.UnmatchedRules = append(report.UnmatchedRules, newReportRule(ruler, rule, 0))
+	}
+
+	return report
+}
+
+// newReportRule describes the given rule for the report.
+func newReportRule(ruler givilsta.GivilstaRuler, rule givilsta.Rule, hits int) reportRule {
+	return reportRule{
+		Rule:   ruleName(rule),
+		Kind:   rule.Kind,
+		Origin: ruler.RuleOrigin(rule),
+		Hits:   hits,
+	}
+}
+
+// elapsedMilliseconds returns the milliseconds elapsed since the given time.
+func elapsedMilliseconds(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
+
+// writeReport writes the given report into the given file.
+//
+// Args:
+//
+//	report: The report to write.
+//	targetFile: The file to write the report into, empty to skip the report.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	error: An error if the report cannot be written.
+func writeReport(report *runReport, targetFile string, logger *slog.Logger) error {
+	if targetFile == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(report, "", "  ")
+
+	if err != nil {
+		logger.Error("Error encoding report.", slog.String("error", err.Error()))
+		return fmt.Errorf("encoding report: %w", err)
+	}
+
+	if err := os.WriteFile(targetFile, append(content, '\n'), 0o644); err != nil {
+		logger.Error("Error writing report.", slog.String("file", targetFile), slog.String("error", err.Error()))
+		return fmt.Errorf("writing report '%s': %w", targetFile, err)
+	}
+
+	logger.Info("Report written.", slog.String("file", targetFile))
+
+	return nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/formats"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+func TestNewRunReport(t *testing.T) {
+	ruler := givilsta.NewGivilstaRuler(false, slog.New(slog.DiscardHandler))
+
+	for index, rule := range []string{"example.org", "ALL .example.net", "REG ^track"} {
+		if err := applyRule(ruler, rule, givilsta.NoFlag, false, fmt.Sprintf("whitelist.list:%d", index+1)); err != nil {
+			t.Fatalf("applyRule(%q) returned error: %v", rule, err)
+		}
+	}
+
+	stats := newRemovalStats()
+	stats.Lines, stats.Skipped, stats.Subjects = 7, 1, 6
+
+	for _, subject := range []string{"a.example.net", "example.org", "b.example.net"} {
+		rule, ok := ruler.WhitelistingRule(subject)
+
+		if !ok {
+			t.Fatalf("WhitelistingRule(%q) = false; want the subject whitelisted", subject)
+		}
+
+		stats.record(rule)
+	}
+
+	stats.Lossy[formats.LossExactWidened] = 2
+	stats.Lossy[formats.LossSubdomainsDropped] = 1
+
+	// A subject whitelisted by an unknown rule.
+	stats.record(givilsta.Rule{})
+
+	timings := reportTimings{Fetch: 1, Load: 2, Filter: 3}
+
+	expected := &runReport{
+		Totals:  reportTotals{SourceLines: 7, Subjects: 6, Kept: 2, Removed: 4, SkippedLines: 1, Lossy: 3},
+		Timings: timings,
+		Rules: []reportRule{
+			{Rule: "ALL .example.net", Kind: "ALL", Origin: "whitelist.list:2", Hits: 2},
+			{Rule: "(unknown)", Hits: 1},
+			{Rule: "example.org", Kind: "PLAIN", Origin: "whitelist.list:1", Hits: 1},
+		},
+		UnmatchedRules: []reportRule{
+			{Rule: "REG ^track", Kind: "REG", Origin: "whitelist.list:3"},
+		},
+	}
+
+	if report := newRunReport(ruler, stats, timings); !reflect.DeepEqual(report, expected) {
+		t.Errorf("newRunReport() = %+v; want %+v", report, expected)
+	}
+
+	// Without any rule nor subject, the rule lists are empty, not null.
+	empty := newRunReport(givilsta.NewGivilstaRuler(false, slog.New(slog.DiscardHandler)), newRemovalStats(), reportTimings{})
+
+	if empty.Rules == nil || empty.UnmatchedRules == nil || len(empty.Rules)+len(empty.UnmatchedRules) != 0 {
+		t.Errorf("newRunReport() = %+v; want empty rule lists", empty)
+	}
+}
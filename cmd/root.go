@@ -16,34 +16,62 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/funilrys/givilsta/internal/formats"
 	"github.com/funilrys/givilsta/internal/helpers"
+	"github.com/funilrys/givilsta/internal/rulefile"
 	"github.com/funilrys/givilsta/pkg/givilsta"
 	"github.com/spf13/cobra"
 )
 
 var ProjectVersion string
-var sourceFile string
+var sourceFiles []string
+var sourceFormat string
 var outputFile string
+var outputDir string
+var dedupe bool
+var outputFormat string
+var sinkIP string
+var hostsPerLine int
+var labelForm string
+var removedOutputFile string
+var invert bool
+var maxRemovalRatio float64
+var maxRemoved int
+var reportFile string
+var watch bool
+var pollInterval time.Duration
+var refreshInterval time.Duration
 var whitelistFiles []string
 var whitelistALLFiles []string
 var whitelistREGFiles []string
 var whitelistRZDBFiles []string
+var whitelistURLFiles []string
+var whitelistROOTFiles []string
 
 var bypassFiles []string
 var bypassALLFiles []string
 var bypassREGFiles []string
 var bypassRZDBFiles []string
+var bypassURLFiles []string
+var bypassROOTFiles []string
 
 var handleComplement bool
+var complementPrefixes []string
+var allowBroadRules bool
 var logLevel string
 
+// errorExitCode is the exit code used when the rules can't be loaded.
+var errorExitCode = 1
+
 var rootCmd = &cobra.Command{
 	Use:   "givilsta",
 	Short: "A different whitelisting mechanism for blocklist maintainers.",
@@ -53,37 +81,24 @@ It tries to provide a more flexible and powerful approach to maintaining
 whitelist lists for blocklist maintainers.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
-		if sourceFile == "" {
-			log.Fatal("Error: source must be specified.")
+		if err := validateCleanupFlags(); err != nil {
+			log.Fatalf("Error: %v.", err)
 		}
 
-		if len(whitelistFiles) == 0 && len(whitelistALLFiles) == 0 &&
-			len(whitelistREGFiles) == 0 && len(whitelistRZDBFiles) == 0 {
-			log.Fatal("Error: at least one whitelist file must be specified.")
+		if watch {
+			if err := validateWatchFlags(); err != nil {
+				log.Fatalf("Error: %v.", err)
+			}
 		}
 
-		var slogLevel slog.Level
-
-		switch strings.ToLower(logLevel) {
-		case "debug":
-			slogLevel = slog.LevelDebug
-		case "info":
-			slogLevel = slog.LevelInfo
-		case "warn":
-			slogLevel = slog.LevelWarn
-		case "error":
-			slogLevel = slog.LevelError
-		default:
-			fmt.Fprintf(os.Stderr, "Warning: Unrecognized log-level '%s' from config. Defaulting to 'error'.\n", logLevel)
-			slogLevel = slog.LevelError
-		}
+		setupLogger()
 
-		logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-			Level: slogLevel,
-		}))
-		slog.SetDefault(logger)
+		if watch {
+			processWatch()
+			return
+		}
 
-		processCleanup()
+		exitOnError(processCleanup())
 	},
 }
 
@@ -96,9 +111,111 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// validateCleanupFlags checks the flags of a cleanup run.
+//
+// Returns:
+//
+//	error: An error describing the first invalid flag, nil if they are valid.
+func validateCleanupFlags() error {
+	if len(sourceFiles) == 0 {
+		return errors.New("source must be specified")
+	}
+
+	if outputFile != "" && outputDir != "" {
+		return errors.New("output and output-dir cannot be used together")
+	}
+
+	if invert && removedOutputFile != "" {
+		return errors.New("invert and removed-output cannot be used together")
+	}
+
+	if labelForm != "" && outputFormat == "" {
+		return errors.New("label-form requires an output-format")
+	}
+
+	if labelForm != "" && labelForm != string(givilsta.LabelFormALabel) && labelForm != string(givilsta.LabelFormULabel) {
+		return fmt.Errorf("unsupported label form: %s", labelForm)
+	}
+
+	if !hasWhitelistFiles() {
+		return errors.New("at least one whitelist file must be specified")
+	}
+
+	if maxRemovalRatio < 0 || maxRemovalRatio > 1 {
+		return errors.New("max-removal-ratio must be between 0 and 1")
+	}
+
+	if maxRemoved < 0 {
+		return errors.New("max-removed cannot be negative")
+	}
+
+	return nil
+}
+
+// setupLogger configures the default logger according to the requested log level.
+func setupLogger() {
+	var slogLevel slog.Level
+
+	switch strings.ToLower(logLevel) {
+	case "debug":
+		slogLevel = slog.LevelDebug
+	case "info":
+		slogLevel = slog.LevelInfo
+	case "warn":
+		slogLevel = slog.LevelWarn
+	case "error":
+		slogLevel = slog.LevelError
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: Unrecognized log-level '%s' from config. Defaulting to 'error'.\n", logLevel)
+		slogLevel = slog.LevelError
+	}
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slogLevel,
+	}))
+	slog.SetDefault(logger)
+}
+
+// exitOnError prints the given error, if any, and exits with the error exit code.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(errorExitCode)
+}
+
+// createTempDir creates the temporary directory of the run.
+//
+// Returns:
+//
+//	string: The path of the temporary directory.
+//	func(): The function to call to remove the temporary directory.
+func createTempDir(logger *slog.Logger) (string, func()) {
+	dirName, err := os.MkdirTemp("", "givilsta")
+
+	if err != nil {
+		logger.Error("Error creating temporary directory.", slog.String("error", err.Error()))
+		exitOnError(fmt.Errorf("creating temporary directory: %w", err))
+	}
+
+	return dirName, func() {
+		if err := os.RemoveAll(dirName); err != nil {
+			logger.Error("Error removing temporary directory.", slog.String("dir", dirName), slog.String("error", err.Error()))
+			fmt.Printf("Error removing temporary directory '%s': %v\n", dirName, err)
+			os.Exit(errorExitCode)
+		}
+	}
+}
+
 func Execute() {
-	err := rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
 	if err != nil {
+		if cmd == checkCmd {
+			os.Exit(checkExitError)
+		}
+
 		os.Exit(1)
 	}
 }
@@ -106,34 +223,252 @@ func Execute() {
 func init() {
 	rootCmd.AddCommand(versionCmd)
 
-	rootCmd.Flags().StringVarP(&sourceFile, "source", "s", "", "The source file to cleanup.")
+	addCleanupFlags(rootCmd)
 
-	rootCmd.Flags().StringSliceVarP(&whitelistFiles, "whitelist", "w", []string{}, "The whitelist file to use for the cleanup.\nCan be specified multiple times.")
-	rootCmd.Flags().StringSliceVarP(&whitelistALLFiles, "whitelist-all", "a", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'ALL' flag.\nCan be specified multiple times.")
-	rootCmd.Flags().StringSliceVarP(&whitelistREGFiles, "whitelist-regex", "r", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'REG' flag.\nCan be specified multiple times.")
-	rootCmd.Flags().StringSliceVarP(&whitelistRZDBFiles, "whitelist-rzdb", "z", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'RZDB' flag.\nCan be specified multiple times.")
+	rootCmd.Flags().BoolVar(&watch, "watch", false, `Whether to keep running and clean up the sources again each time a whitelist, bypass or source file changes.
+Only the rules of the changed files are reloaded.`)
+	rootCmd.Flags().DurationVar(&pollInterval, "poll-interval", time.Second, "The interval between two checks of the watched files. On Linux, inotify also reports the changes right away.")
+	rootCmd.Flags().DurationVar(&refreshInterval, "refresh-interval", 0, "The interval between two fetches of the remote rule files and sources in watch mode. 0 disables the refresh.")
 
-	rootCmd.Flags().StringSliceVarP(&bypassFiles, "bypass", "B", []string{}, `The bypass file to use for the cleanup. This file(s) is used to ensure that some some whitelisting rules are never applied.
-Simply put any of the known rules in this file(s) and they will be ignored during the cleanup process.
+	rootCmd.PersistentFlags().StringVarP(&logLevel, "log-level", "l", "error", "The log level to use. Can be one of: debug, info, warn, error.")
+}
+
+// addCleanupFlags adds the flags describing a cleanup run to the given command.
+func addCleanupFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&sourceFiles, "source", "s", []string{}, `The source to cleanup. Can be a file, a directory, a glob pattern, an URL or '-' to read from stdin.
 Can be specified multiple times.`)
-	rootCmd.Flags().StringSliceVarP(&bypassALLFiles, "bypass-all", "A", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'ALL' flag.\nCan be specified multiple times.")
-	rootCmd.Flags().StringSliceVarP(&bypassREGFiles, "bypass-regex", "R", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'REG' flag.\nCan be specified multiple times.")
-	rootCmd.Flags().StringSliceVarP(&bypassRZDBFiles, "bypass-rzdb", "Z", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'RZDB' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringVarP(&sourceFormat, "source-format", "f", string(formats.FormatAuto), `The format of the source file. Can be one of: auto, plain, hosts, abp, dnsmasq, unbound, rpz.
+When set to 'auto', the format is detected from the first lines of the source file.`)
+
+	addRuleFlags(cmd)
+
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "The output file to write the cleaned up subjects to. If not specified, we will print to stdout.")
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "D", "", "The directory to write each cleaned up source to. If not specified, all sources are merged into the output.")
+	cmd.Flags().StringVar(&removedOutputFile, "removed-output", "", "The output file to write the removed (whitelisted) entries to. The removed entries of all sources are merged into it.")
+	cmd.Flags().BoolVar(&invert, "invert", false, "Whether to write only the removed (whitelisted) entries into the output, instead of the kept ones.")
+	cmd.Flags().BoolVarP(&dedupe, "dedupe", "u", false, "Whether to remove duplicate lines when merging multiple sources or not.")
+	cmd.Flags().StringVarP(&outputFormat, "output-format", "O", "", `The format to convert the cleaned up subjects to. Can be one of: hosts, domains, abp, dnsmasq, unbound, rpz.
+If not specified, the surviving lines are written back in the source format.`)
+	cmd.Flags().StringVar(&sinkIP, "sink-ip", formats.DefaultSinkIP, "The IP to write in front of the subjects when the output format is 'hosts' or 'dnsmasq'.")
+	cmd.Flags().IntVar(&hostsPerLine, "hosts-per-line", 1, "The number of hostnames to write per line when the output format is 'hosts'.")
+	cmd.Flags().StringVar(&labelForm, "label-form", "", `The form to write the internationalized domains in. Can be one of: a-label, u-label.
+Requires an output-format. If not specified, the domains are written as found in the sources.`)
+
+	cmd.Flags().Float64Var(&maxRemovalRatio, "max-removal-ratio", 0, `The maximum ratio (between 0 and 1) of source subjects the whitelist may remove.
+When exceeded, the output is left untouched and the top offending rules are printed. 0 disables the check.`)
+	cmd.Flags().IntVar(&maxRemoved, "max-removed", 0, `The maximum number of source subjects the whitelist may remove.
+When exceeded, the output is left untouched and the top offending rules are printed. 0 disables the check.`)
+	cmd.Flags().StringVar(&reportFile, "report", "", `The file to write a JSON report of the run to: totals, timings per phase,
+hits per rule (with the rule origin) and the rules which matched nothing.`)
+}
 
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "", "The output file to write the cleaned up subjects to. If not specified, we will print to stdout.")
+// addRuleFlags adds the flags describing the rules to load to the given command.
+func addRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&whitelistFiles, "whitelist", "w", []string{}, "The whitelist file to use for the cleanup.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&whitelistALLFiles, "whitelist-all", "a", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'ALL' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&whitelistREGFiles, "whitelist-regex", "r", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'REG' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&whitelistRZDBFiles, "whitelist-rzdb", "z", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'RZDB' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&whitelistURLFiles, "whitelist-url", "p", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'URL' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&whitelistROOTFiles, "whitelist-root", "e", []string{}, "The whitelist file to use for the cleanup. Any entries in this file-s will be prefixed with the 'ROOT' flag.\nCan be specified multiple times.")
 
-	rootCmd.Flags().BoolVarP(&handleComplement, "handle-complement", "c", false, `Whether to handle complements subjects or not.
+	cmd.Flags().StringSliceVarP(&bypassFiles, "bypass", "B", []string{}, `The bypass file to use for the cleanup. This file(s) is used to ensure that some some whitelisting rules are never applied.
+Simply put any of the known rules in this file(s) and they will be ignored during the cleanup process.
+Can be specified multiple times.`)
+	cmd.Flags().StringSliceVarP(&bypassALLFiles, "bypass-all", "A", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'ALL' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&bypassREGFiles, "bypass-regex", "R", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'REG' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&bypassRZDBFiles, "bypass-rzdb", "Z", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'RZDB' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&bypassURLFiles, "bypass-url", "P", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'URL' flag.\nCan be specified multiple times.")
+	cmd.Flags().StringSliceVarP(&bypassROOTFiles, "bypass-root", "E", []string{}, "The bypass file to use for the cleanup. Any entries in this file-s will be prefixed with the 'ROOT' flag.\nCan be specified multiple times.")
+
+	cmd.Flags().BoolVarP(&handleComplement, "handle-complement", "c", false, `Whether to handle complements subjects or not.
 A complement subject is www.example.com when the subject is example.com - and vice-versa.
 is useful for domains that have a 'www' subdomain and want them to be whitelisted when the domain
 without 'wwww' prefix is whitelist listed.`)
+	cmd.Flags().StringSliceVar(&complementPrefixes, "complement-prefix", []string{}, `The prefixes to handle as complements (e.g. www., m., mobile., amp., www2., ww.).
+Implies handle-complement. If not specified, only 'www.' is handled.
+Can be specified multiple times.`)
+	cmd.Flags().BoolVar(&allowBroadRules, "allow-broad-rules", false, `Whether to load the over-broad rules or not.
+Rules which whitelist a public suffix (e.g. 'ALL .com') or match any subject (e.g. 'REG .*') are refused unless
+this flag is given or the rule carries the 'force' modifier (e.g. 'ALL[force] .com').`)
+}
+
+// newRuler creates the ruler the given rules are loaded into.
+func newRuler(options ruleOptions) givilsta.GivilstaRuler {
+	ruler := givilsta.NewGivilstaRulerWithComplementPrefixes(options.complementPrefixes(), slog.Default())
+	ruler.SetAllowBroadRules(options.AllowBroadRules)
+
+	return ruler
+}
+
+// hasWhitelistFiles checks if at least one whitelist file was given.
+func hasWhitelistFiles() bool {
+	return len(whitelistFiles) != 0 || len(whitelistALLFiles) != 0 ||
+		len(whitelistREGFiles) != 0 || len(whitelistRZDBFiles) != 0 ||
+		len(whitelistURLFiles) != 0 || len(whitelistROOTFiles) != 0
+}
+
+// loadRules loads the rules of all the whitelist and bypass files into the given ruler.
+//
+// Args:
+//
+//	ruler: The ruler to load the rules into.
+//	options: The rules to load.
+//	dirName: The temporary directory to store remote rule files into.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	error: An error if a rule file cannot be read or holds an over-broad rule.
+func loadRules(ruler givilsta.GivilstaRuler, options ruleOptions, dirName string, logger *slog.Logger) error {
+	for _, fileSet := range options.fileSets() {
+		for index, targetFile := range fileSet.files {
+			if err := processRuleFile(targetFile, fileSet.flag, index, ruler, logger, dirName, fileSet.bypass); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// newRulesLoader returns a function loading the rules into a new ruler, for
+// the long-running commands which reload them. The rule files are read again on
+// each call.
+//
+// Args:
+//
+//	options: The rules to load.
+//	dirName: The temporary directory to store remote rule files into.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	func() (givilsta.GivilstaRuler, error): The loader. The calls must not overlap.
+func newRulesLoader(options ruleOptions, dirName string, logger *slog.Logger) func() (givilsta.GivilstaRuler, error) {
+	return func() (givilsta.GivilstaRuler, error) {
+		clear(ruleFileCache)
+
+		ruler := newRuler(options)
+
+		if err := loadRules(ruler, options, dirName, logger); err != nil {
+			return nil, err
+		}
+
+		return ruler, nil
+	}
+}
+
+// ruleFileFlags maps the rule types of the structured rule files to their flag.
+var ruleFileFlags = map[string]givilsta.Flags{
+	rulefile.TypePlain: givilsta.NoFlag,
+	rulefile.TypeAll:   givilsta.FlagAll,
+	rulefile.TypeReg:   givilsta.FlagReg,
+	rulefile.TypeRzdb:  givilsta.FlagRzdb,
+	rulefile.TypeURL:   givilsta.FlagURL,
+	rulefile.TypeRoot:  givilsta.FlagRoot,
+}
+
+// applyRule adds the given rule to the ruler, or removes it when it comes from a bypass file.
+// An invalid or over-broad rule is refused with an error reporting its origin (e.g. "whitelist.list:3"),
+// as is any rule when the ruler is frozen.
+func applyRule(ruler givilsta.GivilstaRuler, rule string, whitelistFlag givilsta.Flags, bypass bool, origin string) error {
+	if !bypass {
+		if _, err := ruler.AddRuleWithOrigin(rule, whitelistFlag, origin); err != nil {
+			return refusedRuleError(ruler, err, origin)
+		}
+
+		return nil
+	}
+
+	var err error
+
+	if whitelistFlag == givilsta.NoFlag {
+		_, err = ruler.RemoveRule(rule)
+	} else {
+		_, err = ruler.RemoveRuleWithFlag(rule, whitelistFlag)
+	}
+
+	if err != nil {
+		ruler.Logger().Error("Unable to remove rule.", slog.String("origin", origin), slog.String("error", err.Error()))
+		return fmt.Errorf("removing rule %q (from %s): %w", rule, origin, err)
+	}
+
+	return nil
+}
+
+// refusedRuleError logs why a rule was refused by the ruler and wraps the error with the origin of the rule.
+func refusedRuleError(ruler givilsta.GivilstaRuler, err error, origin string) error {
+	var broadErr *givilsta.BroadRuleError
+	var invalidErr *givilsta.InvalidRuleError
+
+	switch {
+	case errors.As(err, &broadErr):
+		ruler.Logger().Error("Refusing over-broad rule.", slog.String("origin", origin), slog.String("error", err.Error()))
+		return fmt.Errorf("refusing %w (from %s). Use --allow-broad-rules or the 'force' modifier to load it anyway", err, origin)
+	case errors.As(err, &invalidErr):
+		ruler.Logger().Error("Refusing invalid rule.", slog.String("origin", origin), slog.String("error", err.Error()))
+		return fmt.Errorf("refusing %w (from %s)", err, origin)
+	case errors.Is(err, givilsta.ErrFrozenRuler):
+		ruler.Logger().Error("Refusing rule of a frozen ruler.", slog.String("origin", origin), slog.String("error", err.Error()))
+		return fmt.Errorf("adding rule (from %s): %w", origin, err)
+	default:
+		ruler.Logger().Error("Unable to check rule.", slog.String("origin", origin), slog.String("error", err.Error()))
+		return fmt.Errorf("checking rule (from %s): %w", origin, err)
+	}
+}
+
+// ruleFileEntry is a rule read from a rule file.
+type ruleFileEntry struct {
+	// rule is the rule, as found in the file.
+	rule string
+	// flag is the flag to load the rule with.
+	flag givilsta.Flags
+	// origin is where the rule comes from (e.g. "whitelist.list:3").
+	origin string
+}
+
+// ruleFileCache holds the rules of the rule files already read, keyed by flag
+// and file, so that the rule files shared by several pipelines are fetched and
+// parsed only once.
+var ruleFileCache = make(map[string][]ruleFileEntry)
 
-	rootCmd.Flags().StringVarP(&logLevel, "log-level", "l", "error", "The log level to use. Can be one of: debug, info, warn, error.")
+// ruleFileCacheKey returns the key of the given rule file in the rule file cache.
+func ruleFileCacheKey(targetFile string, whitelistFlag givilsta.Flags) string {
+	return fmt.Sprintf("%s %s", whitelistFlag, targetFile)
 }
 
-func processRuleFile(targetFile string, whitelistFlag givilsta.Flags, index int, ruler givilsta.GivilstaRuler, logger *slog.Logger, dirName string, bypass bool) {
+// processRuleFile loads the rules of the given rule file into the ruler, or
+// removes them when it is a bypass file.
+func processRuleFile(targetFile string, whitelistFlag givilsta.Flags, index int, ruler givilsta.GivilstaRuler, logger *slog.Logger, dirName string, bypass bool) error {
+	entries, err := readRuleFile(targetFile, whitelistFlag, index, logger, dirName, bypass)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := applyRule(ruler, entry.rule, entry.flag, bypass, entry.origin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readRuleFile reads the rules of the given rule file, from the cache when it was already read.
+func readRuleFile(targetFile string, whitelistFlag givilsta.Flags, index int, logger *slog.Logger, dirName string, bypass bool) ([]ruleFileEntry, error) {
+	cacheKey := ruleFileCacheKey(targetFile, whitelistFlag)
+
+	if entries, ok := ruleFileCache[cacheKey]; ok {
+		logger.Debug("Reusing already read rule file.", slog.String("file", targetFile), slog.String("flag", string(whitelistFlag)))
+		return entries, nil
+	}
+
 	logger.Debug("Processing whitelist file.", slog.String("file", targetFile), slog.String("flag", string(whitelistFlag)))
 
-	var targetFileName string
+	targetFileName := targetFile
+	// formatName is the name the format of a structured rule file is detected from.
+	formatName := targetFile
+
 	if helpers.IsUrl(targetFile) {
 		if !bypass {
 			targetFileName = filepath.Join(dirName, fmt.Sprintf("whitelist-%s-%d.list", strings.ToLower(string(whitelistFlag)), index))
@@ -147,131 +482,117 @@ func processRuleFile(targetFile string, whitelistFlag givilsta.Flags, index int,
 
 		if err != nil {
 			logger.Error("Error fetching file from URL.", slog.String("file", targetFile), slog.String("error", err.Error()))
-			fmt.Printf("Error fetching file from URL '%s': %v\n", targetFile, err)
-			os.Exit(1)
+			return nil, fmt.Errorf("fetching file from URL '%s': %w", targetFile, err)
 		}
 
 		logger.Debug("Processing file from URL.", slog.String("file", targetFile), slog.String("targetFile", targetFileName))
+	} else if filePath, revision, ok := helpers.SplitGitRevision(targetFile); ok {
+		revisionFile, err := os.CreateTemp(dirName, "revision-*.list")
+		if err != nil {
+			return nil, fmt.Errorf("creating file for '%s': %w", targetFile, err)
+		}
 
-		helpers.IterFile(targetFileName, func(line string) {
-			if !bypass {
-				if whitelistFlag == givilsta.NoFlag {
-					ruler.AddRule(line)
-				} else {
-					ruler.AddRuleWithFlag(line, whitelistFlag)
-				}
-			} else {
-				if whitelistFlag == givilsta.NoFlag {
-					ruler.RemoveRule(line)
-				} else {
-					ruler.RemoveRuleWithFlag(line, whitelistFlag)
-				}
-			}
-		})
+		_ = revisionFile.Close()
+		targetFileName = revisionFile.Name()
+		formatName = filePath
+
+		logger.Debug("Fetching file from git revision.", slog.String("file", filePath), slog.String("revision", revision))
+
+		if err := helpers.FetchGitRevisionToFile(filePath, revision, targetFileName); err != nil {
+			logger.Error("Error fetching file from git revision.", slog.String("file", targetFile), slog.String("error", err.Error()))
+			return nil, fmt.Errorf("fetching file from git revision '%s': %w", targetFile, err)
+		}
 	} else {
 		if _, err := os.Stat(targetFile); os.IsNotExist(err) {
 			logger.Error("Whitelist file does not exist.", slog.String("file", targetFile))
-			fmt.Printf("Error: Whitelist file '%s' does not exist.\n", targetFile)
-			os.Exit(1)
+			return nil, fmt.Errorf("whitelist file '%s' does not exist", targetFile)
 		}
 
 		logger.Debug("Processing whitelist file.", slog.String("file", targetFile))
-
-		helpers.IterFile(targetFile, func(line string) {
-			if !bypass {
-				if whitelistFlag == givilsta.NoFlag {
-					ruler.AddRule(line)
-				} else {
-					ruler.AddRuleWithFlag(line, whitelistFlag)
-				}
-			} else {
-				if whitelistFlag == givilsta.NoFlag {
-					ruler.RemoveRule(line)
-				} else {
-					ruler.RemoveRuleWithFlag(line, whitelistFlag)
-				}
-			}
-		})
 	}
-}
 
-func processCleanup() {
-	ruler := givilsta.NewGivilstaRuler(handleComplement, slog.Default())
-	logger := ruler.Logger()
+	var entries []ruleFileEntry
 
-	dirName, err := os.MkdirTemp("", "givilsta")
-	defer func() {
-		if err := os.RemoveAll(dirName); err != nil {
-			logger.Error("Error removing temporary directory.", slog.String("dir", dirName), slog.String("error", err.Error()))
-			fmt.Printf("Error removing temporary directory '%s': %v\n", dirName, err)
-			os.Exit(1)
+	if format := rulefile.FormatOf(formatName, targetFileName); format != "" {
+		var err error
+
+		if entries, err = readStructuredRuleFile(targetFile, targetFileName, format, whitelistFlag, logger); err != nil {
+			return nil, err
 		}
-	}()
+	} else {
+		lineNumber := 0
 
-	if err != nil {
-		log.Fatal("Failed to create temporary directory:", err)
-	}
+		err := helpers.IterFile(targetFileName, func(line string) {
+			lineNumber++
+			entries = append(entries, ruleFileEntry{rule: line, flag: whitelistFlag, origin: fmt.Sprintf("%s:%d", targetFile, lineNumber)})
+		})
 
-	for index, whitelistFile := range whitelistFiles {
-		processRuleFile(whitelistFile, givilsta.NoFlag, index, ruler, logger, dirName, false)
+		if err != nil {
+			logger.Error("Error reading whitelist file.", slog.String("file", targetFile), slog.String("error", err.Error()))
+			return nil, fmt.Errorf("reading whitelist file '%s': %w", targetFile, err)
+		}
 	}
 
-	for index, whitelistALLFile := range whitelistALLFiles {
-		processRuleFile(whitelistALLFile, givilsta.FlagAll, index, ruler, logger, dirName, false)
-	}
+	ruleFileCache[cacheKey] = entries
 
-	for index, whitelistREGFile := range whitelistREGFiles {
-		processRuleFile(whitelistREGFile, givilsta.FlagReg, index, ruler, logger, dirName, false)
-	}
+	return entries, nil
+}
 
-	for index, whitelistRZDBFile := range whitelistRZDBFiles {
-		processRuleFile(whitelistRZDBFile, givilsta.FlagRzdb, index, ruler, logger, dirName, false)
-	}
+// readStructuredRuleFile reads the rules of a structured rule file. The type of
+// each rule takes precedence over the flag of the file. Unlike in the line based
+// rule files, an invalid rule (e.g. invalid domain) is refused with its JSON path.
+func readStructuredRuleFile(targetFile string, targetFileName string, format string, whitelistFlag givilsta.Flags, logger *slog.Logger) ([]ruleFileEntry, error) {
+	logger.Debug("Processing structured rule file.", slog.String("file", targetFile), slog.String("format", format))
 
-	for index, bypassFile := range bypassFiles {
-		processRuleFile(bypassFile, givilsta.NoFlag, index, ruler, logger, dirName, true)
-	}
+	document, err := rulefile.Load(targetFileName, format)
 
-	for index, bypassALLFile := range bypassALLFiles {
-		processRuleFile(bypassALLFile, givilsta.FlagAll, index, ruler, logger, dirName, true)
+	if err != nil {
+		logger.Error("Invalid structured rule file.", slog.String("file", targetFile), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("invalid rule file '%s': %w", targetFile, err)
 	}
 
-	for index, bypassREGFile := range bypassREGFiles {
-		processRuleFile(bypassREGFile, givilsta.FlagReg, index, ruler, logger, dirName, true)
-	}
+	now := time.Now()
+	entries := make([]ruleFileEntry, 0, len(document.Rules))
 
-	for index, bypassRZDBFile := range bypassRZDBFiles {
-		processRuleFile(bypassRZDBFile, givilsta.FlagReg, index, ruler, logger, dirName, true)
-	}
+	for _, rule := range document.Rules {
+		ruleLogger := logger.With(
+			slog.String("file", targetFile),
+			slog.String("path", rule.Path),
+			slog.String("value", rule.Value),
+		)
 
-	if outputFile != "" {
-		targetTempFile := filepath.Join(dirName, "output.list")
+		flag := whitelistFlag
 
-		helpers.WriteFileFromIter(targetTempFile, func(yield func(string)) {
-			logger.Debug("Writing output to file.", slog.String("file", outputFile))
-			helpers.IterFile(sourceFile, func(line string) {
-				if strings.TrimSpace(line) != "" && ruler.IsSubjectBlacklisted(line) {
-					yield(line)
-				}
-			})
-		})
+		if rule.Type != "" {
+			flag = ruleFileFlags[rule.Type]
+		}
 
-		// We do not have the guarantee that both temp and output files are in
-		// the same filesystem, so we copy the temp file to the output file.
-		err := helpers.CopyFile(targetTempFile, outputFile)
+		if err := givilsta.ValidateRule(rule.Value, flag); err != nil {
+			var invalidErr *givilsta.InvalidRuleError
 
-		if err != nil {
-			logger.Error("Error copying temporary file to output file.", slog.String("tempFile", targetTempFile), slog.String("outputFile", outputFile), slog.String("error", err.Error()))
-			fmt.Printf("Error copying temporary file '%s' to output file '%s': %v\n", targetTempFile, outputFile, err)
-			os.Exit(1)
+			if errors.As(err, &invalidErr) {
+				err = &rulefile.ValidationError{Path: rule.Path + ".value", Message: invalidErr.Reason}
+			}
+
+			ruleLogger.Error("Invalid structured rule.", slog.String("error", err.Error()))
+			return nil, fmt.Errorf("invalid rule file '%s': %w", targetFile, err)
 		}
-	} else {
-		logger.Debug("No output file specified, printing to stdout.")
 
-		helpers.IterFile(sourceFile, func(line string) {
-			if strings.TrimSpace(line) != "" && ruler.IsSubjectBlacklisted(line) {
-				fmt.Println(line)
-			}
-		})
+		if rule.IsExpired(now) {
+			ruleLogger.Warn("Skipping expired rule.", slog.String("expires", rule.Expires), slog.String("owner", rule.Owner))
+			continue
+		}
+
+		ruleLogger.Debug("Processing structured rule.",
+			slog.String("type", rule.Type),
+			slog.String("reason", rule.Reason),
+			slog.String("owner", rule.Owner),
+			slog.Any("tags", rule.Tags),
+			slog.Any("scope", rule.Scope),
+		)
+
+		entries = append(entries, ruleFileEntry{rule: rule.Value, flag: flag, origin: fmt.Sprintf("%s %s", targetFile, rule.Path)})
 	}
+
+	return entries, nil
 }
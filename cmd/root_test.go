@@ -0,0 +1,97 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+func TestReadStructuredRuleFile(t *testing.T) {
+	resetRuleFileCache(t)
+
+	dir := t.TempDir()
+	logger := slog.New(slog.DiscardHandler)
+
+	valid := filepath.Join(dir, "whitelist.toml")
+	writeTestFile(t, valid,
+		"version = 1",
+		"[[rules]]",
+		`value = "example.org"`,
+		"[[rules]]",
+		`type = "REG"`,
+		`value = "^ads\\."`,
+		"[[rules]]",
+		`value = "expired.example.org"`,
+		"expires = 2000-01-01",
+	)
+
+	entries, err := readRuleFile(valid, givilsta.FlagAll, 0, logger, dir, false)
+
+	if err != nil {
+		t.Fatalf("readRuleFile(%q) returned error: %v", valid, err)
+	}
+
+	expected := []ruleFileEntry{
+		{rule: "example.org", flag: givilsta.FlagAll, origin: valid + " $.rules[0]"},
+		{rule: `^ads\.`, flag: givilsta.FlagReg, origin: valid + " $.rules[1]"},
+	}
+
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("readRuleFile(%q) = %+v; want %+v", valid, entries, expected)
+	}
+
+	tests := []struct {
+		name  string
+		lines []string
+		path  string
+	}{
+		{"domain.yaml", []string{"version: 1", "rules:", "  - value: example.org", "  - value: exa mple.org"}, "$.rules[1].value"},
+		{"regex.json", []string{`{"version": 1, "rules": [{"type": "REG", "value": "([a-z"}]}`}, "$.rules[0].value"},
+		{"root.yml", []string{"version: 1", "rules:", "  - type: ROOT", "    value: co.uk"}, "$.rules[0].value"},
+		{"schema.toml", []string{"version = 1", "[[rules]]", `type = "FOO"`, `value = "example.org"`}, "$.rules[0].type"},
+	}
+
+	for _, test := range tests {
+		invalid := filepath.Join(dir, test.name)
+		writeTestFile(t, invalid, test.lines...)
+
+		_, err := readRuleFile(invalid, givilsta.NoFlag, 0, logger, dir, false)
+
+		if err == nil || !strings.Contains(err.Error(), test.path+": ") {
+			t.Errorf("readRuleFile(%q) error = %v; want an error at %s", test.name, err, test.path)
+		}
+	}
+}
+
+func TestApplyRuleFrozenRuler(t *testing.T) {
+	ruler := givilsta.NewGivilstaRuler(false, slog.New(slog.DiscardHandler))
+	ruler.SetKnownExtensions([]string{"com", "org"})
+	ruler.AddRule("example.org")
+	ruler.Freeze()
+
+	for _, bypass := range []bool{false, true} {
+		if err := applyRule(ruler, "example.org", givilsta.NoFlag, bypass, "whitelist.list:1"); !errors.Is(err, givilsta.ErrFrozenRuler) {
+			t.Errorf("applyRule(bypass=%v) on a frozen ruler = %v; want ErrFrozenRuler", bypass, err)
+		}
+	}
+}
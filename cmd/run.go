@@ -0,0 +1,333 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/config"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+	"github.com/spf13/cobra"
+)
+
+var configFile string
+var runJobs int
+
+var runCmd = &cobra.Command{
+	Use:   "run [pipeline...]",
+	Short: "Run the pipelines of a configuration file.",
+	Long: `Run the pipelines of a configuration file.
+
+The configuration file (givilsta.toml or givilsta.json) describes named
+pipelines, whose options are named after the long flags of the cleanup. When no
+pipeline is given, all of them are run, by name.
+
+The flags given on the command line override the options of every pipeline.
+The rule files shared by several pipelines are only fetched and parsed once, and
+the pipelines sharing the same rules share the same (frozen) ruler.
+
+The pipelines run concurrently, at most --jobs at a time. A failing pipeline is
+reported without stopping the other ones.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		setupLogger()
+
+		processRun(cmd, args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	runCmd.Flags().StringVarP(&configFile, "config", "C", "", `The configuration file describing the pipelines.
+If not specified, givilsta.toml or givilsta.json is looked up in the current directory.`)
+	runCmd.Flags().IntVarP(&runJobs, "jobs", "J", runtime.NumCPU(), "The maximum number of pipelines to run concurrently.")
+
+	addCleanupFlags(runCmd)
+}
+
+// sliceFlagValue is a flag value holding a list, which can be replaced as a whole.
+type sliceFlagValue interface {
+	Replace([]string) error
+}
+
+// pipelineJob is a pipeline ready to run.
+type pipelineJob struct {
+	// name is the name of the pipeline.
+	name string
+	// options are the options of the pipeline, the command line flags included.
+	options cleanupOptions
+	// ruleset is the ruleset the pipeline runs against, nil if the options are invalid.
+	ruleset *sharedRuleset
+	// err is the error which prevents the pipeline from running.
+	err error
+}
+
+// sharedRuleset is a frozen ruler shared by the pipelines loading the same rules.
+type sharedRuleset struct {
+	// ruler is the frozen ruler.
+	ruler givilsta.GivilstaRuler
+	// loadTime is the time spent loading the rules, in milliseconds.
+	loadTime int64
+	// err is the error which occurred while loading the rules.
+	err error
+}
+
+// processRun runs the requested pipelines of the configuration file.
+//
+// Args:
+//
+//	cmd: The run command, whose flags receive the options of each pipeline.
+//	names: The names of the pipelines to run, all of them when empty.
+func processRun(cmd *cobra.Command, names []string) {
+	logger := slog.Default()
+
+	if runJobs < 1 {
+		fmt.Fprintln(os.Stderr, "Error: jobs must be at least 1.")
+		os.Exit(1)
+	}
+
+	if configFile == "" {
+		configFile = config.Find(".")
+
+		if configFile == "" {
+			fmt.Fprintf(os.Stderr, "Error: no configuration file specified and none of %v found.\n", config.DefaultFiles)
+			os.Exit(1)
+		}
+	}
+
+	configuration, err := config.Load(configFile)
+
+	if err != nil {
+		logger.Error("Invalid configuration file.", slog.String("file", configFile), slog.String("error", err.Error()))
+		fmt.Fprintf(os.Stderr, "Error: Invalid configuration file '%s': %v\n", configFile, err)
+		os.Exit(1)
+	}
+
+	pipelines, err := configuration.Select(names)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v.\n", err)
+		os.Exit(1)
+	}
+
+	jobs := make([]*pipelineJob, 0, len(pipelines))
+
+	for _, pipeline := range pipelines {
+		job := &pipelineJob{name: pipeline.Name}
+
+		if job.err = applyPipeline(cmd, pipeline); job.err == nil {
+			job.err = validateCleanupFlags()
+		}
+
+		job.options = newCleanupOptions()
+		jobs = append(jobs, job)
+	}
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	buildRulesets(jobs, dirName, logger)
+
+	failed := runPipelines(jobs, logger)
+
+	for _, job := range jobs {
+		if job.err != nil {
+			fmt.Fprintf(os.Stderr, "Error: pipeline %q: %v\n", job.name, job.err)
+		}
+	}
+
+	if failed > 0 {
+		logger.Error("Some pipelines failed.", slog.Int("failed", failed), slog.Int("pipelines", len(jobs)))
+		removeTempDir()
+		os.Exit(1)
+	}
+}
+
+// buildRulesets builds, once, each distinct ruleset of the given jobs and freezes it.
+//
+// Args:
+//
+//	jobs: The jobs to build the rulesets of.
+//	dirName: The temporary directory to store the remote rule files into.
+//	logger: The logger to use.
+func buildRulesets(jobs []*pipelineJob, dirName string, logger *slog.Logger) {
+	rulesets := make(map[string]*sharedRuleset)
+
+	for _, job := range jobs {
+		if job.err != nil {
+			continue
+		}
+
+		key := job.options.Rules.key()
+
+		if existing, ok := rulesets[key]; ok {
+			logger.Debug("Sharing ruleset.", slog.String("pipeline", job.name))
+			job.ruleset = existing
+
+			continue
+		}
+
+		logger.Debug("Building ruleset.", slog.String("pipeline", job.name))
+
+		built := &sharedRuleset{ruler: newRuler(job.options.Rules)}
+
+		start := time.Now()
+		built.err = loadRules(built.ruler, job.options.Rules, dirName, logger)
+		built.loadTime = elapsedMilliseconds(start)
+		built.ruler.Freeze()
+
+		rulesets[key] = built
+		job.ruleset = built
+	}
+}
+
+// runPipelines runs the given jobs concurrently, at most --jobs at a time.
+//
+// Args:
+//
+//	jobs: The jobs to run. The error of each failing job is recorded into it.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	int: The number of failed jobs.
+func runPipelines(jobs []*pipelineJob, logger *slog.Logger) int {
+	semaphore := make(chan struct{}, runJobs)
+
+	var wait sync.WaitGroup
+
+	for _, job := range jobs {
+		if job.err == nil && job.ruleset.err != nil {
+			job.err = job.ruleset.err
+		}
+
+		if job.err != nil {
+			continue
+		}
+
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			job.err = runPipeline(job, logger.With(slog.String("pipeline", job.name)))
+		}()
+	}
+
+	wait.Wait()
+
+	failed := 0
+
+	for _, job := range jobs {
+		if job.err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// runPipeline runs a single job against its ruleset.
+func runPipeline(job *pipelineJob, logger *slog.Logger) error {
+	logger.Info("Running pipeline.")
+
+	dirName, err := os.MkdirTemp("", "givilsta")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := os.RemoveAll(dirName); err != nil {
+			logger.Error("Error removing temporary directory.", slog.String("dir", dirName), slog.String("error", err.Error()))
+		}
+	}()
+
+	if err := runCleanup(&job.options, job.ruleset.ruler, job.ruleset.loadTime, dirName, logger); err != nil {
+		logger.Error("Pipeline failed.", slog.String("error", err.Error()))
+		return err
+	}
+
+	logger.Info("Pipeline done.")
+
+	return nil
+}
+
+// applyPipeline sets the flags of the given command to the options of the
+// pipeline. The flags given on the command line are kept, the other ones are
+// reset to their default value when the pipeline does not set them.
+//
+// Args:
+//
+//	cmd: The command whose flags receive the options.
+//	pipeline: The pipeline to apply.
+//
+// Returns:
+//
+//	error: An error if an option cannot be set.
+func applyPipeline(cmd *cobra.Command, pipeline config.Pipeline) error {
+	for _, option := range config.Options() {
+		flag := cmd.Flags().Lookup(option)
+
+		if flag == nil {
+			return fmt.Errorf("unknown option %q", option)
+		}
+
+		if flag.Changed {
+			continue
+		}
+
+		var err error
+
+		switch value := pipeline.Values[option].(type) {
+		case nil:
+			if slice, ok := flag.Value.(sliceFlagValue); ok {
+				err = slice.Replace([]string{})
+			} else {
+				err = flag.Value.Set(flag.DefValue)
+			}
+		case []string:
+			slice, ok := flag.Value.(sliceFlagValue)
+
+			if !ok {
+				return fmt.Errorf("option %q does not accept a list", option)
+			}
+
+			err = slice.Replace(value)
+		case string:
+			err = flag.Value.Set(value)
+		case bool:
+			err = flag.Value.Set(strconv.FormatBool(value))
+		case json.Number:
+			err = flag.Value.Set(value.String())
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid option %q: %v", option, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/internal/server"
+	"github.com/spf13/cobra"
+)
+
+// serveShutdownTimeout is the time given to the requests in flight to complete on shutdown.
+const serveShutdownTimeout = 10 * time.Second
+
+var serveListen string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Answer whitelist checks over HTTP.",
+	Long: `Answer whitelist checks over HTTP.
+
+The rules are loaded from the whitelist and bypass files and served through the
+following endpoints:
+
+  GET  /v1/check?subject=...    Check a single subject.
+  POST /v1/check                Check the subjects of a {"subjects": [...]} body.
+  GET  /v1/explain?subject=...  Explain why a subject is whitelisted or blocked.
+  POST /v1/reload               Reload the rules from the files.
+  GET  /healthz                 Tell that the server is alive.
+  GET  /readyz                  Tell whether the rules are loaded.
+
+The rules are also reloaded on SIGHUP. A failed reload keeps the current rules.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !hasWhitelistFiles() {
+			fmt.Fprintln(os.Stderr, "Error: at least one whitelist file must be specified.")
+			os.Exit(errorExitCode)
+		}
+
+		setupLogger()
+
+		exitOnError(processServe())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "The address to listen on.")
+
+	addRuleFlags(serveCmd)
+}
+
+// processServe serves the whitelist checks until interrupted.
+//
+// Returns:
+//
+//	error: An error if the server cannot listen.
+func processServe() error {
+	options := newRuleOptions()
+	logger := slog.Default()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	rules := ruleset.NewHolder(newRulesLoader(options, dirName, logger), logger)
+
+	httpServer := &http.Server{Addr: serveListen, Handler: server.New(rules, logger), ReadHeaderTimeout: 10 * time.Second}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	shutdown := make(chan struct{})
+
+	go func() {
+		defer close(shutdown)
+
+		for received := range signals {
+			if received == syscall.SIGHUP {
+				logger.Info("Reloading the rules on SIGHUP.")
+
+				if err := rules.Reload(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: reloading the rules: %v\n", err)
+				}
+
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+
+			if err := httpServer.Shutdown(ctx); err != nil {
+				logger.Error("Error shutting down the server.", slog.String("error", err.Error()))
+			}
+
+			cancel()
+
+			return
+		}
+	}()
+
+	// The server answers the health checks while the rules are loading.
+	go func() {
+		if err := rules.Reload(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: loading the rules: %v\n", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on %s.\n", serveListen)
+
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("listening on %s: %w", serveListen, err)
+	}
+
+	// The requests in flight complete before the server is closed.
+	<-shutdown
+
+	return nil
+}
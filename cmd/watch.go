@@ -0,0 +1,419 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/helpers"
+	"github.com/funilrys/givilsta/internal/watcher"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// watchSettleDelay is the time given to an editor to finish writing a file
+// before the changes are processed.
+const watchSettleDelay = 100 * time.Millisecond
+
+// validateWatchFlags checks the flags of the watch mode.
+//
+// Returns:
+//
+//	error: An error describing the first invalid flag, nil if they are valid.
+func validateWatchFlags() error {
+	if slices.Contains(sourceFiles, "-") {
+		return errors.New("the stdin source cannot be watched")
+	}
+
+	if pollInterval <= 0 {
+		return errors.New("poll-interval must be positive")
+	}
+
+	if refreshInterval < 0 {
+		return errors.New("refresh-interval cannot be negative")
+	}
+
+	return nil
+}
+
+// watchedRuleFile is a rule file loaded by the watch mode.
+type watchedRuleFile struct {
+	// file is the rule file, as given by the end-user.
+	file string
+	// flag is the flag to load the rules with.
+	flag givilsta.Flags
+	// bypass tells whether the rules are removed from the ruler.
+	bypass bool
+	// index is the position of the file among the files of the same flag.
+	index int
+	// entries are the rules currently loaded from the file.
+	entries []ruleFileEntry
+}
+
+// watchSession holds the state of the watch mode between two runs.
+type watchSession struct {
+	options  cleanupOptions
+	dirName  string
+	logger   *slog.Logger
+	ruler    givilsta.GivilstaRuler
+	files    []*watchedRuleFile
+	loadTime int64
+}
+
+// newWatchSession creates a watch session for the given options. The rules
+// are not loaded yet.
+func newWatchSession(options cleanupOptions, dirName string, logger *slog.Logger) *watchSession {
+	session := &watchSession{options: options, dirName: dirName, logger: logger}
+
+	for _, fileSet := range options.Rules.fileSets() {
+		for index, targetFile := range fileSet.files {
+			session.files = append(session.files, &watchedRuleFile{
+				file:   targetFile,
+				flag:   fileSet.flag,
+				bypass: fileSet.bypass,
+				index:  index,
+			})
+		}
+	}
+
+	return session
+}
+
+// hasBypassFiles checks if the session loads at least one bypass file.
+func (s *watchSession) hasBypassFiles() bool {
+	return slices.ContainsFunc(s.files, func(file *watchedRuleFile) bool { return file.bypass })
+}
+
+// read reads the given rule files, from the cache when they were already read.
+//
+// Args:
+//
+//	files: The rule files to read.
+//
+// Returns:
+//
+//	map[*watchedRuleFile][]ruleFileEntry: The rules read from each file.
+//	error: An error if a file cannot be read.
+func (s *watchSession) read(files []*watchedRuleFile) (map[*watchedRuleFile][]ruleFileEntry, error) {
+	result := make(map[*watchedRuleFile][]ruleFileEntry, len(files))
+
+	for _, file := range files {
+		entries, err := readRuleFile(file.file, file.flag, file.index, s.logger, s.dirName, file.bypass)
+		if err != nil {
+			return nil, err
+		}
+
+		result[file] = entries
+	}
+
+	return result, nil
+}
+
+// reload reloads the given rule files into the ruler. On error, the ruler is
+// left as it was.
+//
+// Without bypass files, only the rules of the given files are replaced in the
+// ruler. A bypass only removes what was loaded before it, so with bypass files
+// the ruler is rebuilt instead, the other files being taken from the cache.
+//
+// Args:
+//
+//	files: The rule files which changed. nil loads all of them.
+//
+// Returns:
+//
+//	error: An error if a file cannot be read or holds an over-broad rule.
+func (s *watchSession) reload(files []*watchedRuleFile) error {
+	start := time.Now()
+
+	for _, file := range files {
+		delete(ruleFileCache, ruleFileCacheKey(file.file, file.flag))
+	}
+
+	if s.ruler == nil || files == nil || s.hasBypassFiles() {
+		return s.rebuild(start)
+	}
+
+	next, err := s.read(files)
+	if err != nil {
+		return err
+	}
+
+	// Removing every rule of a file before adding the new ones back keeps the
+	// origins in line with the edited file.
+	for index, file := range files {
+		for _, entry := range file.entries {
+			// Removing a rule, as a bypass does, never fails.
+			_ = applyRule(s.ruler, entry.rule, entry.flag, true, entry.origin)
+		}
+
+		for added, entry := range next[file] {
+			if err := applyRule(s.ruler, entry.rule, entry.flag, false, entry.origin); err != nil {
+				s.rollback(files[:index+1], next, added)
+				return err
+			}
+		}
+	}
+
+	for _, file := range files {
+		file.entries = next[file]
+	}
+
+	s.loadTime = elapsedMilliseconds(start)
+	s.logger.Info("Reloaded the changed rule files.", slog.Int("files", len(files)))
+
+	return nil
+}
+
+// rollback restores the rules of the given files after a refused rule: the new
+// rules added so far are removed and the previous ones are added back.
+//
+// Args:
+//
+//	files: The files whose rules were replaced, the last one partially.
+//	next: The new rules of the files.
+//	added: The number of new rules of the last file which were added.
+func (s *watchSession) rollback(files []*watchedRuleFile, next map[*watchedRuleFile][]ruleFileEntry, added int) {
+	for index, file := range files {
+		entries := next[file]
+
+		if index == len(files)-1 {
+			entries = entries[:added]
+		}
+
+		for _, entry := range entries {
+			_ = applyRule(s.ruler, entry.rule, entry.flag, true, entry.origin)
+		}
+
+		for _, entry := range file.entries {
+			// The previous rules were accepted when they were first added.
+			_ = applyRule(s.ruler, entry.rule, entry.flag, false, entry.origin)
+		}
+	}
+}
+
+// rebuild loads all the rule files into a new ruler.
+func (s *watchSession) rebuild(start time.Time) error {
+	next, err := s.read(s.files)
+	if err != nil {
+		return err
+	}
+
+	ruler := newRuler(s.options.Rules)
+
+	for _, file := range s.files {
+		for _, entry := range next[file] {
+			if err := applyRule(ruler, entry.rule, entry.flag, file.bypass, entry.origin); err != nil {
+				return err
+			}
+		}
+	}
+
+	for file, entries := range next {
+		file.entries = entries
+	}
+
+	s.ruler = ruler
+	s.loadTime = elapsedMilliseconds(start)
+	s.logger.Info("Rebuilt the ruler.", slog.Int("files", len(s.files)))
+
+	return nil
+}
+
+// run runs the cleanup against the current ruler.
+func (s *watchSession) run() error {
+	if s.ruler == nil {
+		return errors.New("no rules loaded, fix the rule files to resume")
+	}
+
+	return runCleanup(&s.options, s.ruler, s.loadTime, s.dirName, s.logger)
+}
+
+// remoteFiles returns the rule files fetched from an URL.
+func (s *watchSession) remoteFiles() []*watchedRuleFile {
+	var result []*watchedRuleFile
+
+	for _, file := range s.files {
+		if helpers.IsUrl(file.file) {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// hasRemoteFiles checks if a rule file or a source is fetched from an URL.
+func (s *watchSession) hasRemoteFiles() bool {
+	return len(s.remoteFiles()) != 0 || slices.ContainsFunc(s.options.Sources, helpers.IsUrl)
+}
+
+// changedFiles returns the local rule files among the given changed paths.
+func (s *watchSession) changedFiles(changed []string) []*watchedRuleFile {
+	var result []*watchedRuleFile
+
+	for _, file := range s.files {
+		if slices.Contains(changed, file.file) {
+			result = append(result, file)
+		}
+	}
+
+	return result
+}
+
+// watchedPaths returns the local files to watch: the rule files and the
+// sources. The directories of the directory and glob sources are watched too,
+// so that the files added to them are noticed.
+func (s *watchSession) watchedPaths() []string {
+	var paths []string
+
+	for _, file := range s.files {
+		if !helpers.IsUrl(file.file) {
+			paths = append(paths, file.file)
+		}
+	}
+
+	for _, source := range s.options.Sources {
+		if helpers.IsUrl(source) {
+			continue
+		}
+
+		matches := []string{source}
+
+		if strings.ContainsAny(source, "*?[") {
+			matches, _ = filepath.Glob(source)
+			paths = append(paths, filepath.Dir(source))
+		}
+
+		for _, match := range matches {
+			paths = append(paths, match)
+
+			_ = filepath.WalkDir(match, func(filePath string, entry fs.DirEntry, err error) error {
+				if err == nil && filePath != match {
+					paths = append(paths, filePath)
+				}
+
+				return nil
+			})
+		}
+	}
+
+	slices.Sort(paths)
+
+	return slices.Compact(paths)
+}
+
+// processWatch runs the cleanup, then runs it again each time a rule file or a
+// source changes, until interrupted.
+func processWatch() {
+	options := newCleanupOptions()
+	logger := slog.Default()
+
+	dirName, removeTempDir := createTempDir(logger)
+	defer removeTempDir()
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	session := newWatchSession(options, dirName, logger)
+	fileWatcher := watcher.New(pollInterval)
+	defer fileWatcher.Close()
+
+	fileWatcher.Watch(session.watchedPaths())
+
+	if err := fileWatcher.EnableNotify(); err != nil {
+		logger.Debug("File system notifications unavailable, polling only.", slog.String("error", err.Error()))
+	}
+
+	if err := session.reload(nil); err != nil {
+		printWatchError(err)
+	} else if err := session.run(); err != nil {
+		printWatchError(err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Watching for changes. Press Ctrl+C to stop.")
+
+	lastRefresh := time.Now()
+
+	for fileWatcher.Wait(stop) {
+		changed := fileWatcher.Changes()
+		refresh := refreshInterval > 0 && time.Since(lastRefresh) >= refreshInterval && session.hasRemoteFiles()
+
+		if len(changed) == 0 && !refresh {
+			continue
+		}
+
+		if len(changed) != 0 {
+			time.Sleep(watchSettleDelay)
+			changed = append(changed, fileWatcher.Changes()...)
+			slices.Sort(changed)
+			changed = slices.Compact(changed)
+		}
+
+		files := session.changedFiles(changed)
+		reason := fmt.Sprintf("changes to %s", strings.Join(changed, ", "))
+
+		if refresh {
+			lastRefresh = time.Now()
+			files = append(files, session.remoteFiles()...)
+
+			if len(changed) == 0 {
+				reason = "refreshing the remote files"
+			}
+		}
+
+		logger.Info("Running the cleanup again.", slog.String("reason", reason))
+
+		var err error
+
+		// A source change alone reuses the loaded rules.
+		if len(files) != 0 || session.ruler == nil {
+			err = session.reload(files)
+		}
+
+		if err == nil {
+			err = session.run()
+		}
+
+		fileWatcher.Watch(session.watchedPaths())
+
+		if err != nil {
+			printWatchError(err)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Output updated after %s.\n", reason)
+	}
+}
+
+// printWatchError prints an error of the watch mode, which keeps watching.
+func printWatchError(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+}
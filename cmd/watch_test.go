@@ -0,0 +1,226 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/formats"
+)
+
+// writeTestFile writes the given lines into the given file.
+func writeTestFile(t *testing.T, filePath string, lines ...string) {
+	t.Helper()
+
+	if err := os.WriteFile(filePath, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// resetRuleFileCache empties the rule file cache before and after the test.
+func resetRuleFileCache(t *testing.T) {
+	clear(ruleFileCache)
+	t.Cleanup(func() { clear(ruleFileCache) })
+}
+
+// newTestWatchSession creates a watch session loading the given rule files.
+func newTestWatchSession(t *testing.T, rules ruleOptions) *watchSession {
+	t.Helper()
+
+	resetRuleFileCache(t)
+
+	return newWatchSession(cleanupOptions{Rules: rules}, t.TempDir(), slog.New(slog.DiscardHandler))
+}
+
+// assertWhitelisted checks the status of the given subjects against the ruler of the session.
+func assertWhitelisted(t *testing.T, session *watchSession, expected map[string]bool) {
+	t.Helper()
+
+	for subject, want := range expected {
+		if got := session.ruler.IsSubjectWhitelisted(subject); got != want {
+			t.Errorf("IsSubjectWhitelisted(%q) = %v; want %v", subject, got, want)
+		}
+	}
+}
+
+func TestWatchSessionReloadIncremental(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.list"), filepath.Join(dir, "second.list")
+
+	writeTestFile(t, first, "example.org", "example.net")
+	writeTestFile(t, second, "example.org", "example.com")
+
+	session := newTestWatchSession(t, ruleOptions{Whitelist: []string{first, second}})
+
+	if err := session.reload(nil); err != nil {
+		t.Fatalf("reload(nil) returned error: %v", err)
+	}
+
+	loaded := session.ruler
+
+	writeTestFile(t, first, "example.net", "ALL .example.info")
+
+	if err := session.reload(session.files[:1]); err != nil {
+		t.Fatalf("reload() returned error: %v", err)
+	}
+
+	if session.ruler != loaded {
+		t.Errorf("reload() without bypass files rebuilt the ruler; want the rules of the changed file replaced")
+	}
+
+	// example.org is still whitelisted by the second file.
+	assertWhitelisted(t, session, map[string]bool{
+		"example.org":      true,
+		"example.net":      true,
+		"example.com":      true,
+		"api.example.info": true,
+	})
+
+	rule, _ := session.ruler.WhitelistingRule("api.example.info")
+
+	if origin := session.ruler.RuleOrigin(rule); origin != first+":2" {
+		t.Errorf("RuleOrigin(%q) = %q; want %q", rule, origin, first+":2")
+	}
+
+	// Neither an over-broad rule nor a vanished file change the loaded rules:
+	// the rules added before the over-broad one are rolled back.
+	rules := len(session.ruler.Rules())
+
+	writeTestFile(t, first, "example.io", "ALL .com")
+
+	if err := session.reload(session.files[:1]); err == nil {
+		t.Errorf("reload() with an over-broad rule returned no error")
+	}
+
+	if err := os.Remove(second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.reload(session.files[1:]); err == nil {
+		t.Errorf("reload() of a removed file returned no error")
+	}
+
+	assertWhitelisted(t, session, map[string]bool{
+		"example.org":      true,
+		"example.net":      true,
+		"example.com":      true,
+		"api.example.info": true,
+		"example.io":       false,
+		"other.com":        false,
+	})
+
+	if loaded := len(session.ruler.Rules()); loaded != rules {
+		t.Errorf("len(Rules()) = %d after a refused reload; want %d", loaded, rules)
+	}
+
+	if origin := session.ruler.RuleOrigin(rule); origin != first+":2" {
+		t.Errorf("RuleOrigin(%q) = %q after a refused reload; want %q", rule, origin, first+":2")
+	}
+}
+
+func TestWatchSessionReloadRebuild(t *testing.T) {
+	dir := t.TempDir()
+	whitelist, bypass := filepath.Join(dir, "whitelist.list"), filepath.Join(dir, "bypass.list")
+
+	writeTestFile(t, whitelist, "example.org", "example.net")
+	writeTestFile(t, bypass, "example.net")
+
+	session := newTestWatchSession(t, ruleOptions{Whitelist: []string{whitelist}, Bypass: []string{bypass}})
+
+	if err := session.reload(nil); err != nil {
+		t.Fatalf("reload(nil) returned error: %v", err)
+	}
+
+	assertWhitelisted(t, session, map[string]bool{"example.org": true, "example.net": false})
+
+	loaded := session.ruler
+
+	// The bypass file, unchanged, is applied again from the cache.
+	writeTestFile(t, whitelist, "example.org", "example.net", "example.com")
+
+	if err := session.reload(session.files[:1]); err != nil {
+		t.Fatalf("reload() returned error: %v", err)
+	}
+
+	if session.ruler == loaded {
+		t.Errorf("reload() with bypass files kept the ruler; want it rebuilt")
+	}
+
+	assertWhitelisted(t, session, map[string]bool{"example.org": true, "example.net": false, "example.com": true})
+
+	writeTestFile(t, bypass, "example.org")
+
+	if err := session.reload(session.files[1:]); err != nil {
+		t.Fatalf("reload() returned error: %v", err)
+	}
+
+	assertWhitelisted(t, session, map[string]bool{"example.org": false, "example.net": true, "example.com": true})
+
+	// A failed rebuild keeps the current ruler.
+	loaded = session.ruler
+
+	writeTestFile(t, whitelist, "ALL .com")
+
+	if err := session.reload(session.files[:1]); err == nil {
+		t.Errorf("reload() with an over-broad rule returned no error")
+	}
+
+	if session.ruler != loaded {
+		t.Errorf("reload() with an over-broad rule replaced the ruler; want it kept")
+	}
+}
+
+func TestWatchSessionRunWithoutSource(t *testing.T) {
+	dir := t.TempDir()
+	whitelist, source, output := filepath.Join(dir, "whitelist.list"), filepath.Join(dir, "source.list"), filepath.Join(dir, "output.list")
+
+	writeTestFile(t, whitelist, "example.org")
+	writeTestFile(t, source, "example.org", "example.com")
+
+	session := newTestWatchSession(t, ruleOptions{Whitelist: []string{whitelist}})
+	session.options.Sources = []string{source}
+	session.options.SourceFormat = string(formats.FormatAuto)
+	session.options.Output = output
+
+	if err := session.run(); err == nil {
+		t.Errorf("run() before loading the rules returned no error")
+	}
+
+	if err := session.reload(nil); err != nil {
+		t.Fatalf("reload(nil) returned error: %v", err)
+	}
+
+	if err := session.run(); err != nil {
+		t.Fatalf("run() returned error: %v", err)
+	}
+
+	if content, _ := os.ReadFile(output); string(content) != "example.com\n" {
+		t.Errorf("run() wrote %q; want %q", content, "example.com\n")
+	}
+
+	// A source removed between two runs is reported, the watch goes on.
+	if err := os.Remove(source); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := session.run(); err == nil {
+		t.Errorf("run() with a removed source returned no error")
+	}
+}
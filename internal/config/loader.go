@@ -0,0 +1,462 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/funilrys/givilsta/internal/helpers"
+)
+
+// configKeys are the keys accepted at the root of a configuration file.
+var configKeys = []string{"$schema", "defaults", "pipelines"}
+
+// ValidationError describes why a configuration file is invalid.
+type ValidationError struct {
+	// Path is the path of the invalid value (e.g. "$.pipelines.ads.source").
+	Path string
+	// Message explains what is wrong with the value.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// FormatOf returns the format of the given configuration file, from its extension.
+//
+// Args:
+//
+//	filePath: The path to the configuration file.
+//
+// Returns:
+//
+//	string: The format of the file, empty when unsupported.
+func FormatOf(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".toml":
+		return FormatTOML
+	case ".json":
+		return FormatJSON
+	}
+
+	return ""
+}
+
+// Find returns the first default configuration file found in the given directory.
+//
+// Args:
+//
+//	dirName: The directory to look into.
+//
+// Returns:
+//
+//	string: The path to the configuration file, empty when none exists.
+func Find(dirName string) string {
+	for _, name := range DefaultFiles {
+		filePath := filepath.Join(dirName, name)
+
+		if _, err := os.Stat(filePath); err == nil {
+			return filePath
+		}
+	}
+
+	return ""
+}
+
+// Load reads and validates a configuration file. Its format is given by its extension.
+// The relative files of its pipelines are resolved against the directory of the
+// file, so that the pipelines do not depend on the working directory.
+//
+// Args:
+//
+//	filePath: The path to the file to load.
+//
+// Returns:
+//
+//	*Config: The loaded configuration.
+//	error: An error if the file cannot be read or is not valid.
+func Load(filePath string) (*Config, error) {
+	format := FormatOf(filePath)
+
+	if format == "" {
+		return nil, fmt.Errorf("unsupported configuration file %q, expected a .toml or a .json file", filePath)
+	}
+
+	file, err := helpers.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Panic("error closing file:", err)
+		}
+	}()
+
+	config, err := Parse(file, format)
+	if err != nil {
+		return nil, err
+	}
+
+	config.resolvePaths(filepath.Dir(filePath))
+
+	return config, nil
+}
+
+// Parse reads and validates a configuration document.
+//
+// Args:
+//
+//	reader: The reader to read the document from.
+//	format: The format of the document, FormatTOML or FormatJSON.
+//
+// Returns:
+//
+//	*Config: The parsed configuration.
+//	error: An error if the document is malformed or does not describe valid pipelines.
+func Parse(reader io.Reader, format string) (*Config, error) {
+	var raw any
+
+	switch format {
+	case FormatTOML:
+		var table map[string]any
+
+		if _, err := toml.NewDecoder(reader).Decode(&table); err != nil {
+			var parseErr toml.ParseError
+
+			if errors.As(err, &parseErr) {
+				return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid TOML: line %d: %s", parseErr.Position.Line, parseErr.Message)}
+			}
+
+			return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid TOML: %v", err)}
+		}
+
+		raw = fromTOML(table)
+	case FormatJSON:
+		decoder := json.NewDecoder(reader)
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported configuration format %q", format)
+	}
+
+	return parseConfig(raw)
+}
+
+// Select returns the pipelines with the given names, all of them when no name is given.
+//
+// Args:
+//
+//	names: The names of the pipelines to select.
+//
+// Returns:
+//
+//	[]Pipeline: The selected pipelines, in the given order.
+//	error: An error if a pipeline does not exist.
+func (c *Config) Select(names []string) ([]Pipeline, error) {
+	if len(names) == 0 {
+		return c.Pipelines, nil
+	}
+
+	selected := make([]Pipeline, 0, len(names))
+
+	for _, name := range names {
+		index := slices.IndexFunc(c.Pipelines, func(pipeline Pipeline) bool {
+			return pipeline.Name == name
+		})
+
+		if index < 0 {
+			return nil, fmt.Errorf("unknown pipeline %q", name)
+		}
+
+		selected = append(selected, c.Pipelines[index])
+	}
+
+	return selected, nil
+}
+
+// resolvePaths joins the relative files of the pipelines to the given directory.
+// The URLs and the standard input ("-") are kept as they are.
+func (c *Config) resolvePaths(dirName string) {
+	for _, pipeline := range c.Pipelines {
+		for _, option := range pathOptions {
+			switch value := pipeline.Values[option].(type) {
+			case string:
+				pipeline.Values[option] = resolvePath(dirName, value)
+			case []string:
+				// The values of the defaults are shared by the pipelines.
+				resolved := make([]string, 0, len(value))
+
+				for _, item := range value {
+					resolved = append(resolved, resolvePath(dirName, item))
+				}
+
+				pipeline.Values[option] = resolved
+			}
+		}
+	}
+}
+
+// resolvePath joins the given file to the given directory, unless it is
+// absolute, an URL, the standard input ("-") or empty.
+func resolvePath(dirName string, value string) string {
+	if value == "" || value == "-" || filepath.IsAbs(value) || helpers.IsUrl(value) {
+		return value
+	}
+
+	return filepath.Join(dirName, value)
+}
+
+// Options returns the names of the options a pipeline accepts, sorted.
+func Options() []string {
+	options := make([]string, 0, len(optionKinds))
+
+	for option := range optionKinds {
+		options = append(options, option)
+	}
+
+	slices.Sort(options)
+
+	return options
+}
+
+// fromTOML converts a decoded TOML value into the value the JSON decoder gives
+// with UseNumber, so both formats share the same validation. Infinities, NaNs
+// and dates are kept as they are and refused by the validation.
+func fromTOML(raw any) any {
+	switch value := raw.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(value))
+
+		for key, item := range value {
+			result[key] = fromTOML(item)
+		}
+
+		return result
+	case []map[string]any:
+		result := make([]any, 0, len(value))
+
+		for _, item := range value {
+			result = append(result, fromTOML(item))
+		}
+
+		return result
+	case []any:
+		result := make([]any, 0, len(value))
+
+		for _, item := range value {
+			result = append(result, fromTOML(item))
+		}
+
+		return result
+	case int64:
+		return json.Number(strconv.FormatInt(value, 10))
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return value
+		}
+
+		number := strconv.FormatFloat(value, 'g', -1, 64)
+
+		// A TOML float (e.g. 5.0) is not an integer, even without a fraction.
+		if !strings.ContainsAny(number, ".e") {
+			number += ".0"
+		}
+
+		return json.Number(number)
+	}
+
+	return raw
+}
+
+func parseConfig(raw any) (*Config, error) {
+	object, ok := raw.(map[string]any)
+	if !ok {
+		return nil, &ValidationError{Path: "$", Message: "must be an object"}
+	}
+
+	if err := checkKeys("$", object, configKeys); err != nil {
+		return nil, err
+	}
+
+	defaults := map[string]any{}
+
+	if rawDefaults, found := object["defaults"]; found {
+		var err error
+
+		if defaults, err = parseOptions("$.defaults", rawDefaults); err != nil {
+			return nil, err
+		}
+	}
+
+	pipelines, ok := object["pipelines"].(map[string]any)
+	if !ok || len(pipelines) == 0 {
+		return nil, &ValidationError{Path: "$.pipelines", Message: "is required and must be a non-empty object"}
+	}
+
+	names := make([]string, 0, len(pipelines))
+
+	for name := range pipelines {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	config := &Config{Pipelines: make([]Pipeline, 0, len(names))}
+
+	for _, name := range names {
+		path := fmt.Sprintf("$.pipelines.%s", name)
+
+		if strings.TrimSpace(name) == "" {
+			return nil, &ValidationError{Path: path, Message: "the name of a pipeline cannot be empty"}
+		}
+
+		options, err := parseOptions(path, pipelines[name])
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]any, len(defaults)+len(options))
+
+		for option, value := range defaults {
+			values[option] = value
+		}
+
+		for option, value := range options {
+			values[option] = value
+		}
+
+		config.Pipelines = append(config.Pipelines, Pipeline{Name: name, Values: values})
+	}
+
+	return config, nil
+}
+
+// parseOptions validates the options of a pipeline (or of the defaults).
+func parseOptions(path string, raw any) (map[string]any, error) {
+	object, ok := raw.(map[string]any)
+	if !ok {
+		return nil, &ValidationError{Path: path, Message: "must be an object"}
+	}
+
+	if err := checkKeys(path, object, Options()); err != nil {
+		return nil, err
+	}
+
+	options := make(map[string]any, len(object))
+
+	for option, rawValue := range object {
+		value, err := parseOption(fmt.Sprintf("%s.%s", path, option), optionKinds[option], rawValue)
+		if err != nil {
+			return nil, err
+		}
+
+		options[option] = value
+	}
+
+	return options, nil
+}
+
+// parseOption validates the value of an option against its kind. A single
+// string is accepted where a list of strings is expected.
+func parseOption(path string, kind string, raw any) (any, error) {
+	switch kind {
+	case kindString:
+		if value, ok := raw.(string); ok {
+			return value, nil
+		}
+
+		return nil, &ValidationError{Path: path, Message: "must be a string"}
+	case kindStrings:
+		if value, ok := raw.(string); ok {
+			return []string{value}, nil
+		}
+
+		values, ok := raw.([]any)
+		if !ok {
+			return nil, &ValidationError{Path: path, Message: "must be a string or an array of strings"}
+		}
+
+		result := make([]string, 0, len(values))
+
+		for index, rawValue := range values {
+			value, ok := rawValue.(string)
+			if !ok {
+				return nil, &ValidationError{Path: fmt.Sprintf("%s[%d]", path, index), Message: "must be a string"}
+			}
+
+			result = append(result, value)
+		}
+
+		return result, nil
+	case kindBool:
+		if value, ok := raw.(bool); ok {
+			return value, nil
+		}
+
+		return nil, &ValidationError{Path: path, Message: "must be a boolean"}
+	case kindInt:
+		if value, ok := raw.(json.Number); ok {
+			if _, err := value.Int64(); err == nil {
+				return value, nil
+			}
+		}
+
+		return nil, &ValidationError{Path: path, Message: "must be an integer"}
+	case kindFloat:
+		if value, ok := raw.(json.Number); ok {
+			if _, err := value.Float64(); err == nil {
+				return value, nil
+			}
+		}
+
+		return nil, &ValidationError{Path: path, Message: "must be a number"}
+	}
+
+	return nil, &ValidationError{Path: path, Message: "unknown key"}
+}
+
+func checkKeys(path string, object map[string]any, allowed []string) error {
+	keys := make([]string, 0, len(object))
+
+	for key := range object {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if !slices.Contains(allowed, key) {
+			return &ValidationError{Path: fmt.Sprintf("%s.%s", path, key), Message: "unknown key"}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	documents := map[string]string{
+		FormatTOML: `
+[defaults]
+whitelist = ["common.list"]
+handle-complement = true
+
+[pipelines.ads]
+source = "ads.list"
+output = "out/ads.list"
+max-removal-ratio = 0.05
+
+[pipelines.malware]
+source = ["malware.list", "https://example.org/malware.list"]
+whitelist = ["common.list", "malware-whitelist.list"]
+handle-complement = false
+hosts-per-line = 9
+`,
+		FormatJSON: `{
+	"defaults": {"whitelist": ["common.list"], "handle-complement": true},
+	"pipelines": {
+		"malware": {
+			"source": ["malware.list", "https://example.org/malware.list"],
+			"whitelist": ["common.list", "malware-whitelist.list"],
+			"handle-complement": false,
+			"hosts-per-line": 9
+		},
+		"ads": {"source": "ads.list", "output": "out/ads.list", "max-removal-ratio": 0.05}
+	}
+}`,
+	}
+
+	expected := []Pipeline{
+		{Name: "ads", Values: map[string]any{
+			"source":            []string{"ads.list"},
+			"output":            "out/ads.list",
+			"max-removal-ratio": json.Number("0.05"),
+			"whitelist":         []string{"common.list"},
+			"handle-complement": true,
+		}},
+		{Name: "malware", Values: map[string]any{
+			"source":            []string{"malware.list", "https://example.org/malware.list"},
+			"whitelist":         []string{"common.list", "malware-whitelist.list"},
+			"handle-complement": false,
+			"hosts-per-line":    json.Number("9"),
+		}},
+	}
+
+	for format, document := range documents {
+		config, err := Parse(strings.NewReader(document), format)
+
+		if err != nil {
+			t.Fatalf("Parse(%s) returned error: %v", format, err)
+		}
+
+		if !reflect.DeepEqual(config.Pipelines, expected) {
+			t.Errorf("Parse(%s).Pipelines = %#v; want %#v", format, config.Pipelines, expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		format   string
+		document string
+		path     string
+	}{
+		{FormatJSON, `[]`, "$"},
+		{FormatJSON, `{"pipelines": {}}`, "$.pipelines"},
+		{FormatJSON, `{"unknown": 1, "pipelines": {"a": {}}}`, "$.unknown"},
+		{FormatJSON, `{"pipelines": {"a": []}}`, "$.pipelines.a"},
+		{FormatJSON, `{"pipelines": {"a": {"sources": ["x"]}}}`, "$.pipelines.a.sources"},
+		{FormatJSON, `{"pipelines": {"a": {"source": [1]}}}`, "$.pipelines.a.source[0]"},
+		{FormatJSON, `{"pipelines": {"a": {"output": 1}}}`, "$.pipelines.a.output"},
+		{FormatJSON, `{"pipelines": {"a": {"dedupe": "yes"}}}`, "$.pipelines.a.dedupe"},
+		{FormatJSON, `{"pipelines": {"a": {"max-removed": 1.5}}}`, "$.pipelines.a.max-removed"},
+		{FormatJSON, `{"pipelines": {"a": {"max-removal-ratio": "0.1"}}}`, "$.pipelines.a.max-removal-ratio"},
+		{FormatJSON, `{"defaults": {"invert": 1}, "pipelines": {"a": {}}}`, "$.defaults.invert"},
+		{FormatJSON, `{"pipelines": {" ": {}}}`, "$.pipelines. "},
+		{FormatJSON, `{`, "$"},
+		{FormatTOML, "[pipelines.a]\nmax-removed = 5.0", "$.pipelines.a.max-removed"},
+		{FormatTOML, "[pipelines.a]\nmax-removal-ratio = inf", "$.pipelines.a.max-removal-ratio"},
+		{FormatTOML, "[pipelines.a]\noutput = 1979-05-27", "$.pipelines.a.output"},
+		{FormatTOML, "[[pipelines]]\nsource = \"a.list\"", "$.pipelines"},
+		{FormatTOML, "[pipelines.a]\nsource = [\"a.list\", 1]", "$.pipelines.a.source[1]"},
+		{FormatTOML, "[pipelines.a]\nsource = \"a.list\"\nsource = \"b.list\"", "$"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(strings.NewReader(test.document), test.format)
+
+		var validationError *ValidationError
+
+		if !errors.As(err, &validationError) {
+			t.Errorf("Parse(%q) error = %v; want a ValidationError", test.document, err)
+			continue
+		}
+
+		if validationError.Path != test.path {
+			t.Errorf("Parse(%q) error path = %q; want %q", test.document, validationError.Path, test.path)
+		}
+	}
+
+	_, err := Parse(strings.NewReader("[pipelines.a]\nsource = "), FormatTOML)
+
+	if err == nil || !strings.Contains(err.Error(), "invalid TOML: line 2") {
+		t.Errorf("Parse(TOML) error = %v; want an invalid TOML error", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	config := &Config{Pipelines: []Pipeline{{Name: "ads"}, {Name: "malware"}, {Name: "tracking"}}}
+
+	selected, err := config.Select(nil)
+
+	if err != nil || len(selected) != 3 {
+		t.Errorf("Select(nil) = %v, %v; want all the pipelines", selected, err)
+	}
+
+	selected, err = config.Select([]string{"tracking", "ads"})
+
+	if err != nil || len(selected) != 2 || selected[0].Name != "tracking" || selected[1].Name != "ads" {
+		t.Errorf("Select(tracking, ads) = %v, %v; want tracking and ads", selected, err)
+	}
+
+	if _, err := config.Select([]string{"unknown"}); err == nil {
+		t.Errorf("Select(unknown) returned no error")
+	}
+}
+
+func TestFormatOfAndFind(t *testing.T) {
+	tests := map[string]string{
+		"givilsta.toml":     FormatTOML,
+		"dir/Givilsta.JSON": FormatJSON,
+		"givilsta.yaml":     "",
+	}
+
+	for filePath, expected := range tests {
+		if result := FormatOf(filePath); result != expected {
+			t.Errorf("FormatOf(%q) = %q; want %q", filePath, result, expected)
+		}
+	}
+
+	dirName := t.TempDir()
+
+	if result := Find(dirName); result != "" {
+		t.Errorf("Find() = %q; want none", result)
+	}
+
+	for _, name := range []string{"givilsta.json", "givilsta.toml"} {
+		if err := os.WriteFile(filepath.Join(dirName, name), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if result := Find(dirName); result != filepath.Join(dirName, "givilsta.toml") {
+		t.Errorf("Find() = %q; want givilsta.toml", result)
+	}
+}
+
+func TestLoadResolvesPaths(t *testing.T) {
+	dirName := t.TempDir()
+	filePath := filepath.Join(dirName, "givilsta.toml")
+
+	document := `
+[defaults]
+whitelist = ["common.list", "https://example.org/common.list"]
+
+[pipelines.ads]
+source = ["ads.list", "-", "/srv/lists/ads.list"]
+whitelist-all = "lists/ads-all.list"
+output = "dist/ads.list"
+report = "dist/ads.report.json"
+output-format = "hosts"
+
+[pipelines.malware]
+source = "malware.list"
+output-dir = "dist"
+`
+
+	if err := os.WriteFile(filePath, []byte(document), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := Load(filePath)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	expected := []Pipeline{
+		{Name: "ads", Values: map[string]any{
+			"whitelist":     []string{filepath.Join(dirName, "common.list"), "https://example.org/common.list"},
+			"source":        []string{filepath.Join(dirName, "ads.list"), "-", "/srv/lists/ads.list"},
+			"whitelist-all": []string{filepath.Join(dirName, "lists/ads-all.list")},
+			"output":        filepath.Join(dirName, "dist/ads.list"),
+			"report":        filepath.Join(dirName, "dist/ads.report.json"),
+			"output-format": "hosts",
+		}},
+		{Name: "malware", Values: map[string]any{
+			"whitelist":  []string{filepath.Join(dirName, "common.list"), "https://example.org/common.list"},
+			"source":     []string{filepath.Join(dirName, "malware.list")},
+			"output-dir": filepath.Join(dirName, "dist"),
+		}},
+	}
+
+	if !reflect.DeepEqual(config.Pipelines, expected) {
+		t.Errorf("Load() = %+v; want %+v", config.Pipelines, expected)
+	}
+}
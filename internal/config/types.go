@@ -0,0 +1,105 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+// Formats of the configuration files.
+const (
+	FormatTOML = "toml"
+	FormatJSON = "json"
+)
+
+// DefaultFiles are the configuration files looked up, in order, when none is given.
+var DefaultFiles = []string{"givilsta.toml", "givilsta.json"}
+
+// Kinds of the option values.
+const (
+	kindString  = "string"
+	kindStrings = "strings"
+	kindBool    = "bool"
+	kindInt     = "int"
+	kindFloat   = "float"
+)
+
+// optionKinds maps the options of a pipeline to the kind of their value.
+// The options are named after the long flags of the CLI.
+var optionKinds = map[string]string{
+	"source":            kindStrings,
+	"source-format":     kindString,
+	"whitelist":         kindStrings,
+	"whitelist-all":     kindStrings,
+	"whitelist-regex":   kindStrings,
+	"whitelist-rzdb":    kindStrings,
+	"whitelist-url":     kindStrings,
+	"whitelist-root":    kindStrings,
+	"bypass":            kindStrings,
+	"bypass-all":        kindStrings,
+	"bypass-regex":      kindStrings,
+	"bypass-rzdb":       kindStrings,
+	"bypass-url":        kindStrings,
+	"bypass-root":       kindStrings,
+	"handle-complement": kindBool,
+	"complement-prefix": kindStrings,
+	"allow-broad-rules": kindBool,
+	"output":            kindString,
+	"output-dir":        kindString,
+	"output-format":     kindString,
+	"removed-output":    kindString,
+	"invert":            kindBool,
+	"dedupe":            kindBool,
+	"sink-ip":           kindString,
+	"hosts-per-line":    kindInt,
+	"label-form":        kindString,
+	"max-removal-ratio": kindFloat,
+	"max-removed":       kindInt,
+	"report":            kindString,
+}
+
+// pathOptions are the options whose values are files (or directories). Their
+// relative values are relative to the directory of the configuration file.
+var pathOptions = []string{
+	"source",
+	"whitelist",
+	"whitelist-all",
+	"whitelist-regex",
+	"whitelist-rzdb",
+	"whitelist-url",
+	"whitelist-root",
+	"bypass",
+	"bypass-all",
+	"bypass-regex",
+	"bypass-rzdb",
+	"bypass-url",
+	"bypass-root",
+	"output",
+	"output-dir",
+	"removed-output",
+	"report",
+}
+
+// Config represents a configuration file.
+type Config struct {
+	// Pipelines are the pipelines of the configuration, sorted by name.
+	Pipelines []Pipeline
+}
+
+// Pipeline is a named cleanup run.
+type Pipeline struct {
+	// Name is the name of the pipeline.
+	Name string
+	// Values maps the options of the pipeline, defaults included, to their value:
+	// a string, a []string, a bool or a json.Number.
+	Values map[string]any
+}
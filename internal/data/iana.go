@@ -41,10 +41,10 @@ func fetchIANAMapping() (map[string]*string, error) {
 	return mapping, nil
 }
 
-func NewIANAExtensions() *IANAExtensions {
+func NewIANAExtensions() (*IANAExtensions, error) {
 	mapping, err := fetchIANAMapping()
 	if err != nil {
-		panic(fmt.Sprintf("failed to fetch iana-domains-db: %v", err))
+		return nil, fmt.Errorf("failed to fetch iana-domains-db: %w", err)
 	}
 
 	extensions := make([]string, 0, len(mapping))
@@ -54,11 +54,14 @@ func NewIANAExtensions() *IANAExtensions {
 	}
 
 	regexPattern := `(?i)^(` + helpers.JoinWithPipe(extensions) + `)$`
-	regex := regexp.MustCompile(regexPattern)
+	regex, err := regexp.Compile(regexPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile iana-domains-db: %w", err)
+	}
 
 	return &IANAExtensions{
 		upstream:   mapping,
 		Extensions: extensions,
 		Regex:      regex,
-	}
+	}, nil
 }
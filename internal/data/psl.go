@@ -41,10 +41,10 @@ func fetchPSLMapping() (map[string][]string, error) {
 	return mapping, nil
 }
 
-func NewPSLExtensions() *PSLExtensions {
+func NewPSLExtensions() (*PSLExtensions, error) {
 	mapping, err := fetchPSLMapping()
 	if err != nil {
-		panic(fmt.Sprintf("failed to fetch public-suffix: %v", err))
+		return nil, fmt.Errorf("failed to fetch public-suffix: %w", err)
 	}
 
 	var extensions = make([]string, 0, len(mapping))
@@ -60,9 +60,20 @@ func NewPSLExtensions() *PSLExtensions {
 
 	regexPattern := `(?i)^(` + suffixesRegexPattern + `|` + extensionsRegexPattern + `)$`
 
-	regex := regexp.MustCompile(regexPattern)
-	suffixesRegex := regexp.MustCompile(`(?i)^(` + suffixesRegexPattern + `)$`)
-	extensionsRegex := regexp.MustCompile(`(?i)^(` + extensionsRegexPattern + `)$`)
+	regex, err := regexp.Compile(regexPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile public-suffix: %w", err)
+	}
+
+	suffixesRegex, err := regexp.Compile(`(?i)^(` + suffixesRegexPattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile public-suffix: %w", err)
+	}
+
+	extensionsRegex, err := regexp.Compile(`(?i)^(` + extensionsRegexPattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile public-suffix: %w", err)
+	}
 
 	return &PSLExtensions{
 		upstream:        mapping,
@@ -71,7 +82,7 @@ func NewPSLExtensions() *PSLExtensions {
 		Regex:           regex,
 		SuffixesRegex:   suffixesRegex,
 		ExtensionsRegex: extensionsRegex,
-	}
+	}, nil
 }
 
 func (fun *PSLExtensions) GetUpstream() map[string][]string {
@@ -0,0 +1,88 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package dnsfilter
+
+import (
+	"strings"
+
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// NewBlocklist creates an empty blocklist.
+func NewBlocklist() *Blocklist {
+	return &Blocklist{names: make(map[string]bool), wildcards: make(map[string]bool)}
+}
+
+// Add adds the given name to the blocklist.
+//
+// Args:
+//
+//	name: The name to block.
+//	wildcard: Whether all the subdomains of the name are blocked too.
+//
+// Returns:
+//
+//	bool: true if the name was added, false if it is not a valid domain.
+func (b *Blocklist) Add(name string, wildcard bool) bool {
+	canonicalName, err := givilsta.CanonicalizeDomain(name)
+
+	if err != nil || canonicalName == "" {
+		return false
+	}
+
+	if wildcard {
+		b.wildcards[canonicalName] = true
+	} else {
+		b.names[canonicalName] = true
+	}
+
+	return true
+}
+
+// Len returns the number of blocked names.
+func (b *Blocklist) Len() int {
+	return len(b.names) + len(b.wildcards)
+}
+
+// Blocked checks if the given name is blocked: listed alone, or listed along
+// with its subdomains, itself or one of its parents.
+//
+// Args:
+//
+//	name: The canonical name to check.
+//
+// Returns:
+//
+//	bool: true if the name is blocked, false otherwise.
+func (b *Blocklist) Blocked(name string) bool {
+	if b.names[name] {
+		return true
+	}
+
+	for {
+		if b.wildcards[name] {
+			return true
+		}
+
+		index := strings.IndexByte(name, '.')
+
+		if index < 0 {
+			return false
+		}
+
+		name = name[index+1:]
+	}
+}
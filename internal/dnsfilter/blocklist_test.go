@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package dnsfilter
+
+import "testing"
+
+func TestBlocklist(t *testing.T) {
+	blocklist := NewBlocklist()
+
+	blocklist.Add("Ads.Example.com", false)
+	blocklist.Add("tracker.example.net.", true)
+	blocklist.Add("bücher.example", false)
+
+	if blocklist.Add("", false) {
+		t.Errorf("Add(\"\") = true; want false")
+	}
+
+	if blocklist.Len() != 3 {
+		t.Errorf("Len() = %d; want 3", blocklist.Len())
+	}
+
+	tests := []struct {
+		name    string
+		blocked bool
+	}{
+		{"ads.example.com", true},
+		{"sub.ads.example.com", false},
+		{"example.com", false},
+		{"tracker.example.net", true},
+		{"a.b.tracker.example.net", true},
+		{"example.net", false},
+		{"xn--bcher-kva.example", true},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if blocked := blocklist.Blocked(test.name); blocked != test.blocked {
+			t.Errorf("Blocked(%q) = %t; want %t", test.name, blocked, test.blocked)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package dnsfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// New creates a server answering the queries against the given blocklist.
+//
+// Args:
+//
+//	blocklist: The blocked names.
+//	whitelisted: The function telling whether a name is whitelisted. It is
+//	checked for the blocked names, so that a whitelisted subdomain of a name
+//	blocked with its subdomains is still resolved.
+//	options: The options of the server.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	*Server: The server.
+func New(blocklist *Blocklist, whitelisted func(name string) bool, options Options, logger *slog.Logger) *Server {
+	if options.Mode == "" {
+		options.Mode = BlockModeNXDomain
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+
+	if options.MaxQueries <= 0 {
+		options.MaxQueries = defaultMaxQueries
+	}
+
+	return &Server{
+		blocklist:   blocklist,
+		whitelisted: whitelisted,
+		options:     options,
+		dialer:      net.Dialer{Timeout: options.Timeout},
+		logger:      logger,
+	}
+}
+
+// ServeUDP answers the queries received on the given connection, until it is
+// closed. At most Options.MaxQueries queries are answered concurrently: no
+// query is read while they are all in flight.
+//
+// Args:
+//
+//	conn: The connection to read the queries from.
+//
+// Returns:
+//
+//	error: The error which stopped the server, net.ErrClosed once the connection is closed.
+func (s *Server) ServeUDP(conn net.PacketConn) error {
+	buffer := make([]byte, maxMessageSize)
+	semaphore := make(chan struct{}, s.options.MaxQueries)
+
+	for {
+		semaphore <- struct{}{}
+
+		count, address, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return err
+		}
+
+		query := append([]byte(nil), buffer[:count]...)
+
+		go func() {
+			defer func() { <-semaphore }()
+
+			response, err := s.Resolve(query, "udp")
+
+			if err != nil {
+				s.logger.Debug("Dropping query.", slog.String("client", address.String()), slog.String("error", err.Error()))
+				return
+			}
+
+			if _, err := conn.WriteTo(response, address); err != nil {
+				s.logger.Debug("Error writing response.", slog.String("client", address.String()), slog.String("error", err.Error()))
+			}
+		}()
+	}
+}
+
+// ServeTCP answers the queries received on the connections of the given
+// listener, until it is closed. As a connection answers its queries one at a
+// time, at most Options.MaxQueries connections are served concurrently: no
+// connection is accepted while they are all open.
+//
+// Args:
+//
+//	listener: The listener to accept the connections from.
+//
+// Returns:
+//
+//	error: The error which stopped the server, net.ErrClosed once the listener is closed.
+func (s *Server) ServeTCP(listener net.Listener) error {
+	semaphore := make(chan struct{}, s.options.MaxQueries)
+
+	for {
+		semaphore <- struct{}{}
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer func() { <-semaphore }()
+
+			s.serveTCPConn(conn)
+		}()
+	}
+}
+
+// serveTCPConn answers the queries of a single TCP connection.
+func (s *Server) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		if err := conn.SetDeadline(time.Now().Add(tcpIdleTimeout)); err != nil {
+			return
+		}
+
+		query, err := readTCPMessage(conn)
+		if err != nil {
+			return
+		}
+
+		response, err := s.Resolve(query, "tcp")
+
+		if err != nil {
+			s.logger.Debug("Dropping query.", slog.String("client", conn.RemoteAddr().String()), slog.String("error", err.Error()))
+			return
+		}
+
+		if err := writeTCPMessage(conn, response); err != nil {
+			return
+		}
+	}
+}
+
+// Resolve answers the given query: blocked names are answered directly, the
+// other queries are forwarded to the upstream server.
+//
+// Args:
+//
+//	query: The query, as received.
+//	network: The network the query was received on ("udp" or "tcp"), used to reach the upstream server.
+//
+// Returns:
+//
+//	[]byte: The response.
+//	error: An error if the query cannot be answered at all.
+func (s *Server) Resolve(query []byte, network string) ([]byte, error) {
+	var parser dnsmessage.Parser
+
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, fmt.Errorf("parsing query: %w", err)
+	}
+
+	if header.Response {
+		return nil, errors.New("not a query")
+	}
+
+	questions, err := parser.AllQuestions()
+
+	if err != nil {
+		return errorResponse(header, nil, dnsmessage.RCodeFormatError)
+	}
+
+	// Only the standard queries of a single question can be blocked.
+	if header.OpCode == 0 && len(questions) == 1 {
+		question := questions[0]
+		name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+
+		if s.blocklist.Blocked(name) && !s.whitelisted(name) {
+			s.logger.Debug("Blocking query.", slog.String("name", name), slog.String("type", question.Type.String()))
+			return s.blockedResponse(header, question)
+		}
+	}
+
+	response, err := s.forward(query, network)
+
+	if err != nil {
+		s.logger.Error("Error forwarding query.", slog.String("upstream", s.options.Upstream), slog.String("error", err.Error()))
+		return errorResponse(header, questions, dnsmessage.RCodeServerFailure)
+	}
+
+	return response, nil
+}
+
+// blockedResponse builds the response to a blocked query.
+func (s *Server) blockedResponse(header dnsmessage.Header, question dnsmessage.Question) ([]byte, error) {
+	if s.options.Mode == BlockModeNXDomain {
+		return errorResponse(header, []dnsmessage.Question{question}, dnsmessage.RCodeNameError)
+	}
+
+	builder := newResponseBuilder(header, dnsmessage.RCodeSuccess)
+
+	if err := addQuestions(&builder, []dnsmessage.Question{question}); err != nil {
+		return nil, err
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	resourceHeader := dnsmessage.ResourceHeader{Name: question.Name, Class: question.Class, TTL: blockedTTL}
+
+	switch question.Type {
+	case dnsmessage.TypeA:
+		if err := builder.AResource(resourceHeader, dnsmessage.AResource{}); err != nil {
+			return nil, err
+		}
+	case dnsmessage.TypeAAAA:
+		if err := builder.AAAAResource(resourceHeader, dnsmessage.AAAAResource{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// forward sends the query to the upstream server and returns its response.
+func (s *Server) forward(query []byte, network string) ([]byte, error) {
+	conn, err := s.dialer.Dial(network, s.options.Upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(s.options.Timeout)); err != nil {
+		return nil, err
+	}
+
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+
+		return readTCPMessage(conn)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, maxMessageSize)
+
+	// A response to another query (e.g. a late one) is ignored.
+	for {
+		count, err := conn.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+
+		if count >= 2 && binary.BigEndian.Uint16(buffer) == binary.BigEndian.Uint16(query) {
+			return buffer[:count], nil
+		}
+	}
+}
+
+// newResponseBuilder starts the response to the query of the given header.
+func newResponseBuilder(header dnsmessage.Header, rcode dnsmessage.RCode) dnsmessage.Builder {
+	builder := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	builder.EnableCompression()
+
+	return builder
+}
+
+// addQuestions copies the questions of the query into the response.
+func addQuestions(builder *dnsmessage.Builder, questions []dnsmessage.Question) error {
+	if err := builder.StartQuestions(); err != nil {
+		return err
+	}
+
+	for _, question := range questions {
+		if err := builder.Question(question); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// errorResponse builds a response without answer with the given code.
+func errorResponse(header dnsmessage.Header, questions []dnsmessage.Question, rcode dnsmessage.RCode) ([]byte, error) {
+	builder := newResponseBuilder(header, rcode)
+
+	if err := addQuestions(&builder, questions); err != nil {
+		return nil, err
+	}
+
+	return builder.Finish()
+}
+
+// readTCPMessage reads a length-prefixed DNS message.
+func readTCPMessage(reader io.Reader) ([]byte, error) {
+	var length [2]byte
+
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		return nil, err
+	}
+
+	message := make([]byte, binary.BigEndian.Uint16(length[:]))
+
+	if _, err := io.ReadFull(reader, message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// writeTCPMessage writes a length-prefixed DNS message.
+func writeTCPMessage(writer io.Writer, message []byte) error {
+	if len(message) > maxMessageSize {
+		return fmt.Errorf("message of %d bytes too large", len(message))
+	}
+
+	buffer := make([]byte, 2, 2+len(message))
+	binary.BigEndian.PutUint16(buffer, uint16(len(message)))
+
+	_, err := writer.Write(append(buffer, message...))
+
+	return err
+}
@@ -0,0 +1,412 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package dnsfilter
+
+import (
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// upstreamAddress is the answer of the stand-in upstream server to every A query.
+var upstreamAddress = [4]byte{192, 0, 2, 1}
+
+// answer builds the response of the stand-in upstream server to the given query.
+func answer(t *testing.T, query []byte) []byte {
+	t.Helper()
+
+	var parser dnsmessage.Parser
+
+	header, err := parser.Start(query)
+	if err != nil {
+		t.Errorf("upstream: invalid query: %v", err)
+		return nil
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		t.Errorf("upstream: invalid question: %v", err)
+		return nil
+	}
+
+	builder := newResponseBuilder(header, dnsmessage.RCodeSuccess)
+	_ = addQuestions(&builder, []dnsmessage.Question{question})
+	_ = builder.StartAnswers()
+	_ = builder.AResource(dnsmessage.ResourceHeader{Name: question.Name, Class: question.Class, TTL: 300}, dnsmessage.AResource{A: upstreamAddress})
+
+	response, err := builder.Finish()
+	if err != nil {
+		t.Errorf("upstream: building response: %v", err)
+	}
+
+	return response
+}
+
+// startUpstream starts a stand-in upstream server answering over UDP and TCP
+// on the same local address.
+func startUpstream(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.ListenPacket("udp", listener.Addr().String())
+	if err != nil {
+		listener.Close()
+		t.Skipf("cannot listen on the same UDP port: %v", err)
+	}
+
+	t.Cleanup(func() {
+		listener.Close()
+		conn.Close()
+	})
+
+	go func() {
+		buffer := make([]byte, maxMessageSize)
+
+		for {
+			count, address, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+
+			_, _ = conn.WriteTo(answer(t, buffer[:count]), address)
+		}
+	}()
+
+	go func() {
+		for {
+			client, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer client.Close()
+
+				query, err := readTCPMessage(client)
+				if err != nil {
+					return
+				}
+
+				_ = writeTCPMessage(client, answer(t, query))
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// newQuery builds a query for the given name and type.
+func newQuery(t *testing.T, id uint16, name string, queryType dnsmessage.Type) []byte {
+	t.Helper()
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	_ = builder.StartQuestions()
+	_ = builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(name), Type: queryType, Class: dnsmessage.ClassINET})
+
+	query, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return query
+}
+
+// parseResponse parses a response into its header and answers.
+func parseResponse(t *testing.T, response []byte) (dnsmessage.Header, []dnsmessage.Resource) {
+	t.Helper()
+
+	var message dnsmessage.Message
+
+	if err := message.Unpack(response); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	return message.Header, message.Answers
+}
+
+// newTestServer creates a server blocking ads.example.com and the subdomains of
+// tracker.example.net, except the whitelisted cdn.tracker.example.net.
+func newTestServer(upstream string, mode BlockMode) *Server {
+	blocklist := NewBlocklist()
+	blocklist.Add("ads.example.com", false)
+	blocklist.Add("tracker.example.net", true)
+
+	whitelisted := func(name string) bool { return name == "cdn.tracker.example.net" }
+
+	return New(blocklist, whitelisted, Options{Upstream: upstream, Mode: mode, Timeout: 2 * time.Second}, slog.New(slog.DiscardHandler))
+}
+
+func TestResolve(t *testing.T) {
+	upstream := startUpstream(t)
+
+	tests := []struct {
+		name      string
+		queryType dnsmessage.Type
+		mode      BlockMode
+		rcode     dnsmessage.RCode
+		answer    dnsmessage.ResourceBody
+	}{
+		{"ads.example.com.", dnsmessage.TypeA, BlockModeNXDomain, dnsmessage.RCodeNameError, nil},
+		{"ADS.example.com.", dnsmessage.TypeA, BlockModeNXDomain, dnsmessage.RCodeNameError, nil},
+		{"a.tracker.example.net.", dnsmessage.TypeA, BlockModeNXDomain, dnsmessage.RCodeNameError, nil},
+		{"ads.example.com.", dnsmessage.TypeA, BlockModeNull, dnsmessage.RCodeSuccess, &dnsmessage.AResource{}},
+		{"ads.example.com.", dnsmessage.TypeAAAA, BlockModeNull, dnsmessage.RCodeSuccess, &dnsmessage.AAAAResource{}},
+		{"ads.example.com.", dnsmessage.TypeMX, BlockModeNull, dnsmessage.RCodeSuccess, nil},
+		{"example.com.", dnsmessage.TypeA, BlockModeNXDomain, dnsmessage.RCodeSuccess, &dnsmessage.AResource{A: upstreamAddress}},
+		{"sub.ads.example.com.", dnsmessage.TypeA, BlockModeNXDomain, dnsmessage.RCodeSuccess, &dnsmessage.AResource{A: upstreamAddress}},
+		{"cdn.tracker.example.net.", dnsmessage.TypeA, BlockModeNXDomain, dnsmessage.RCodeSuccess, &dnsmessage.AResource{A: upstreamAddress}},
+	}
+
+	for _, network := range []string{"udp", "tcp"} {
+		for index, test := range tests {
+			id := uint16(index + 1)
+			server := newTestServer(upstream, test.mode)
+
+			response, err := server.Resolve(newQuery(t, id, test.name, test.queryType), network)
+			if err != nil {
+				t.Fatalf("Resolve(%s %s) over %s returned error: %v", test.name, test.queryType, network, err)
+			}
+
+			header, answers := parseResponse(t, response)
+
+			if header.ID != id || !header.Response || header.RCode != test.rcode {
+				t.Errorf("Resolve(%s %s, %s) over %s: header = %+v; want ID %d and %s", test.name, test.queryType, test.mode, network, header, id, test.rcode)
+			}
+
+			if test.answer == nil {
+				if len(answers) != 0 {
+					t.Errorf("Resolve(%s %s, %s) over %s: answers = %v; want none", test.name, test.queryType, test.mode, network, answers)
+				}
+
+				continue
+			}
+
+			if len(answers) != 1 || answers[0].Body.GoString() != test.answer.GoString() {
+				t.Errorf("Resolve(%s %s, %s) over %s: answers = %v; want %s", test.name, test.queryType, test.mode, network, answers, test.answer.GoString())
+			}
+		}
+	}
+}
+
+func TestResolveUpstreamFailure(t *testing.T) {
+	// Nothing answers on the port of a closed listener.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := listener.Addr().String()
+	listener.Close()
+
+	server := newTestServer(upstream, BlockModeNXDomain)
+	server.options.Timeout = 200 * time.Millisecond
+
+	response, err := server.Resolve(newQuery(t, 7, "example.com.", dnsmessage.TypeA), "tcp")
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+
+	if header, _ := parseResponse(t, response); header.RCode != dnsmessage.RCodeServerFailure || header.ID != 7 {
+		t.Errorf("Resolve() header = %+v; want SERVFAIL", header)
+	}
+
+	if _, err := server.Resolve([]byte{0, 1}, "udp"); err == nil {
+		t.Errorf("Resolve() of a truncated query returned no error")
+	}
+}
+
+func TestServe(t *testing.T) {
+	server := newTestServer(startUpstream(t), BlockModeNXDomain)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go server.ServeUDP(conn)
+	go server.ServeTCP(listener)
+
+	for _, network := range []string{"udp", "tcp"} {
+		address := conn.LocalAddr().String()
+
+		if network == "tcp" {
+			address = listener.Addr().String()
+		}
+
+		client, err := net.DialTimeout(network, address, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = client.SetDeadline(time.Now().Add(2 * time.Second))
+
+		for index, name := range []string{"ads.example.com.", "example.com."} {
+			query := newQuery(t, uint16(index+1), name, dnsmessage.TypeA)
+
+			var response []byte
+
+			if network == "tcp" {
+				if err = writeTCPMessage(client, query); err == nil {
+					response, err = readTCPMessage(client)
+				}
+			} else if _, err = client.Write(query); err == nil {
+				buffer := make([]byte, maxMessageSize)
+				count, readErr := client.Read(buffer)
+				response, err = buffer[:count], readErr
+			}
+
+			if err != nil {
+				t.Fatalf("querying %s over %s: %v", name, network, err)
+			}
+
+			header, answers := parseResponse(t, response)
+			blocked := header.RCode == dnsmessage.RCodeNameError
+
+			if blocked != (index == 0) || (!blocked && len(answers) != 1) {
+				t.Errorf("querying %s over %s: header = %+v, answers = %v", name, network, header, answers)
+			}
+		}
+
+		client.Close()
+	}
+}
+
+func TestServeUDPMaxQueries(t *testing.T) {
+	// The stand-in upstream server counts the forwarded queries without answering them.
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+
+	forwarded := make(chan struct{}, 8)
+
+	go func() {
+		buffer := make([]byte, maxMessageSize)
+
+		for {
+			if _, _, err := upstream.ReadFrom(buffer); err != nil {
+				return
+			}
+
+			forwarded <- struct{}{}
+		}
+	}()
+
+	server := newTestServer(upstream.LocalAddr().String(), BlockModeNXDomain)
+	server.options.MaxQueries = 2
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go server.ServeUDP(conn)
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for index := range 4 {
+		if _, err := client.Write(newQuery(t, uint16(index+1), "example.com.", dnsmessage.TypeA)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The blocked query is answered locally, but only once a slot is free.
+	if _, err := client.Write(newQuery(t, 5, "ads.example.com.", dnsmessage.TypeA)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	if count := len(forwarded); count != 2 {
+		t.Errorf("%d queries forwarded while the upstream server is not answering; want 2", count)
+	}
+
+	_ = client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+
+	if _, err := client.Read(make([]byte, maxMessageSize)); err == nil {
+		t.Errorf("a query was answered while all the slots were taken")
+	}
+}
+
+func TestServeTCPMaxQueries(t *testing.T) {
+	server := newTestServer(startUpstream(t), BlockModeNXDomain)
+	server.options.MaxQueries = 2
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go server.ServeTCP(listener)
+
+	var idle []net.Conn
+
+	for range 2 {
+		client, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		idle = append(idle, client)
+	}
+
+	// The connection waits in the backlog of the listener while the idle ones are open.
+	client, err := net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := writeTCPMessage(client, newQuery(t, 1, "ads.example.com.", dnsmessage.TypeA)); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = client.SetReadDeadline(time.Now().Add(300 * time.Millisecond))
+
+	if _, err := readTCPMessage(client); err == nil {
+		t.Fatalf("a query was answered while all the connections were taken")
+	}
+
+	idle[0].Close()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := readTCPMessage(client); err != nil {
+		t.Errorf("the query was not answered once a connection was closed: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package dnsfilter
+
+import (
+	"log/slog"
+	"net"
+	"time"
+)
+
+// BlockMode is how the blocked queries are answered.
+type BlockMode string
+
+const (
+	// BlockModeNXDomain answers the blocked queries with NXDOMAIN.
+	BlockModeNXDomain BlockMode = "nxdomain"
+	// BlockModeNull answers the blocked A and AAAA queries with 0.0.0.0 and
+	// ::, the other types with an empty answer.
+	BlockModeNull BlockMode = "null"
+)
+
+const (
+	// blockedTTL is the TTL of the answers to the blocked queries, in seconds.
+	blockedTTL = 60
+	// maxMessageSize is the maximum size of a DNS message.
+	maxMessageSize = 65535
+	// defaultTimeout is the default maximum time to wait for the upstream server.
+	defaultTimeout = 5 * time.Second
+	// tcpIdleTimeout is how long a TCP connection is kept open without query.
+	tcpIdleTimeout = 10 * time.Second
+	// defaultMaxQueries is the default maximum number of UDP queries (and of TCP connections) answered concurrently.
+	defaultMaxQueries = 256
+)
+
+// Blocklist is the set of the blocked names.
+type Blocklist struct {
+	// names are the names blocked alone.
+	names map[string]bool
+	// wildcards are the names blocked along with all their subdomains.
+	wildcards map[string]bool
+}
+
+// Options holds the options of the server.
+type Options struct {
+	// Upstream is the address ("host:port") of the server the allowed queries are forwarded to.
+	Upstream string
+	// Mode is how the blocked queries are answered.
+	Mode BlockMode
+	// Timeout is the maximum time to wait for the upstream server, 5 seconds when zero.
+	Timeout time.Duration
+	// MaxQueries is the maximum number of UDP queries, and of TCP connections, answered
+	// concurrently, 256 when zero. The next queries wait in the buffer of the connection,
+	// the next connections in the backlog of the listener.
+	MaxQueries int
+}
+
+// Server answers the DNS queries: the blocked names are answered directly, the
+// other queries are forwarded to the upstream server.
+type Server struct {
+	blocklist   *Blocklist
+	whitelisted func(name string) bool
+	options     Options
+	dialer      net.Dialer
+	logger      *slog.Logger
+}
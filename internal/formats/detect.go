@@ -0,0 +1,234 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// dnsmasqLineRegex matches the dnsmasq options embedding a list of domains.
+var dnsmasqLineRegex = regexp.MustCompile(`^(address|server|local)=/`)
+
+// DetectionBufferSize is the size of the buffer of a source stream, whose
+// first lines are sampled to detect its format.
+const DetectionBufferSize = 64 * 1024
+
+// rpzRecordTypes are the record types which identify a RPZ line.
+var rpzRecordTypes = []string{"CNAME", "SOA", "NS", "A", "AAAA", "TXT", "DNAME"}
+
+// Detector detects the format of a source file by sampling its first lines.
+type Detector struct {
+	sampleSize   int
+	samples      int
+	unrecognized int
+	votes        map[Format]int
+	examples     map[Format]string
+}
+
+// NewDetector creates a new format detector.
+//
+// Args:
+//
+//	sampleSize: The number of non-comment lines to sample.
+//
+// Returns:
+//
+//	*Detector: The new detector.
+func NewDetector(sampleSize int) *Detector {
+	if sampleSize <= 0 {
+		sampleSize = DefaultDetectionSampleSize
+	}
+
+	return &Detector{
+		sampleSize: sampleSize,
+		votes:      make(map[Format]int),
+		examples:   make(map[Format]string),
+	}
+}
+
+// DetectReader detects the format of a source stream by sampling the lines
+// held by its buffer, without consuming them.
+//
+// Args:
+//
+//	reader: The buffered stream to sample.
+//	sampleSize: The number of non-comment lines to sample.
+//
+// Returns:
+//
+//	Format: The detected format.
+//	string: Why the format was chosen.
+//	error: An error if the stream cannot be read, or if the sampled lines are ambiguous or mixed.
+func DetectReader(reader *bufio.Reader, sampleSize int) (Format, string, error) {
+	head, err := reader.Peek(reader.Size())
+
+	// A stream shorter than the buffer is sampled as a whole.
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return "", "", err
+	}
+
+	lines := strings.Split(string(head), "\n")
+
+	// The last line of a full buffer may be cut.
+	if err == nil || errors.Is(err, bufio.ErrBufferFull) {
+		lines = lines[:len(lines)-1]
+	}
+
+	detector := NewDetector(sampleSize)
+
+	for _, line := range lines {
+		if !detector.Feed(strings.TrimSuffix(line, "\r")) {
+			break
+		}
+	}
+
+	return detector.Result()
+}
+
+// Feed samples the given line.
+//
+// Args:
+//
+//	line: The line to sample.
+//
+// Returns:
+//
+//	bool: true if more lines are needed, false otherwise.
+func (d *Detector) Feed(line string) bool {
+	if d.samples >= d.sampleSize {
+		return false
+	}
+
+	trimmed := strings.TrimSpace(line)
+
+	// Comments and lines starting with a blank (e.g. multi-line RPZ records)
+	// tell nothing about the format.
+	if trimmed == "" || line[0] == ' ' || line[0] == '\t' || strings.HasPrefix(trimmed, "!") ||
+		strings.HasPrefix(trimmed, ";") || (strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "##")) {
+		return true
+	}
+
+	d.samples++
+
+	format, ok := classifyLine(trimmed)
+
+	if !ok {
+		d.unrecognized++
+	} else {
+		d.votes[format]++
+
+		if _, found := d.examples[format]; !found {
+			d.examples[format] = trimmed
+		}
+	}
+
+	return d.samples < d.sampleSize
+}
+
+// Result returns the detected format along with the reason of the decision.
+//
+// Returns:
+//
+//	Format: The detected format.
+//	string: Why the format was chosen.
+//	error: An error if the sampled lines are ambiguous or mixed.
+func (d *Detector) Result() (Format, string, error) {
+	if d.samples == 0 {
+		return FormatPlain, "no line to sample, defaulting to plain", nil
+	}
+
+	if len(d.votes) == 0 {
+		return "", "", fmt.Errorf("unable to detect the source format: none of the %d sampled lines is recognized, please specify the format explicitly", d.samples)
+	}
+
+	if len(d.votes) > 1 {
+		var details []string
+
+		for _, format := range SupportedFormats() {
+			if count, ok := d.votes[format]; ok {
+				details = append(details, fmt.Sprintf("%s: %d (e.g. %q)", format, count, d.examples[format]))
+			}
+		}
+
+		return "", "", fmt.Errorf("unable to detect the source format: the sampled lines are mixed (%s), please specify the format explicitly", strings.Join(details, ", "))
+	}
+
+	var format Format
+
+	for candidate := range d.votes {
+		format = candidate
+	}
+
+	// A format has to be recognized in most of the sampled lines.
+	if d.unrecognized >= d.votes[format] {
+		return "", "", fmt.Errorf("unable to detect the source format: only %d of the %d sampled lines look like %s lines (e.g. %q), %d are unrecognized, please specify the format explicitly", d.votes[format], d.samples, format, d.examples[format], d.unrecognized)
+	}
+
+	reason := fmt.Sprintf("%d of %d sampled lines look like %s lines (e.g. %q)", d.votes[format], d.samples, format, d.examples[format])
+
+	if d.unrecognized > 0 {
+		reason = fmt.Sprintf("%s, %d unrecognized", reason, d.unrecognized)
+	}
+
+	return format, reason, nil
+}
+
+// classifyLine guesses the format of a single, non-comment, line.
+func classifyLine(line string) (Format, bool) {
+	if strings.HasPrefix(line, "[Adblock") || strings.HasPrefix(line, "||") ||
+		strings.HasPrefix(line, "@@") || strings.Contains(line, "##") {
+		return FormatABP, true
+	}
+
+	if dnsmasqLineRegex.MatchString(line) {
+		return FormatDnsmasq, true
+	}
+
+	if strings.HasPrefix(line, "local-zone:") || strings.HasPrefix(line, "local-data:") || line == "server:" {
+		return FormatUnbound, true
+	}
+
+	if strings.HasPrefix(line, "$TTL") || strings.HasPrefix(line, "$ORIGIN") {
+		return FormatRPZ, true
+	}
+
+	fields := strings.Fields(stripComment(line, "#"))
+
+	if len(fields) >= 2 && net.ParseIP(fields[0]) != nil {
+		return FormatHosts, true
+	}
+
+	if rpzFields := strings.Fields(stripComment(line, ";")); len(rpzFields) >= 3 {
+		for _, field := range rpzFields[1:min(len(rpzFields), 5)] {
+			if slices.Contains(rpzRecordTypes, strings.ToUpper(field)) {
+				return FormatRPZ, true
+			}
+		}
+	}
+
+	if len(fields) == 1 && strings.Contains(fields[0], ".") {
+		return FormatPlain, true
+	}
+
+	return "", false
+}
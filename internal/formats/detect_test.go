@@ -0,0 +1,137 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testDetect(lines []string) (Format, string, error) {
+	detector := NewDetector(DefaultDetectionSampleSize)
+
+	for _, line := range lines {
+		if !detector.Feed(line) {
+			break
+		}
+	}
+
+	return detector.Result()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		expected Format
+	}{
+		{[]string{}, FormatPlain},
+		{[]string{"# comment", "example.com", "example.org"}, FormatPlain},
+		{[]string{"example.com", "example.org", "foo bar"}, FormatPlain},
+		{[]string{"# comment", "0.0.0.0 example.com", "127.0.0.1 localhost", ":: example.org"}, FormatHosts},
+		{[]string{"[Adblock Plus 2.0]", "! comment", "||example.com^", "||example.org^$third-party"}, FormatABP},
+		{[]string{"address=/example.com/0.0.0.0", "server=/example.org/"}, FormatDnsmasq},
+		{[]string{"server:", `local-zone: "example.com" always_nxdomain`}, FormatUnbound},
+		{[]string{
+			"$TTL 2h",
+			"@ IN SOA localhost. root.localhost. (",
+			"    1 3600 600 86400 60 )",
+			"  IN NS localhost.",
+			"example.com CNAME .",
+			"*.example.org CNAME .",
+		}, FormatRPZ},
+	}
+
+	for _, test := range tests {
+		result, reason, err := testDetect(test.lines)
+		if err != nil {
+			t.Errorf("Detect(%q) returned error: %v", test.lines, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("Detect(%q) = %q (%s); want %q", test.lines, result, reason, test.expected)
+		}
+	}
+}
+
+func TestDetectRefusesMixedOrAmbiguous(t *testing.T) {
+	tests := [][]string{
+		{"0.0.0.0 example.com", "example.org"},
+		{"||example.com^", "address=/example.org/0.0.0.0"},
+		{"foo bar baz", "hello world"},
+		{"||example.com^", "foo bar"},
+		{"example.com", "foo bar", "hello world", "lorem ipsum"},
+	}
+
+	for _, lines := range tests {
+		if result, _, err := testDetect(lines); err == nil {
+			t.Errorf("Detect(%q) = %q; want error", lines, result)
+		}
+	}
+}
+
+func TestDetectSampleSize(t *testing.T) {
+	detector := NewDetector(2)
+
+	lines := []string{"example.com", "example.org", "0.0.0.0 example.net"}
+	fed := 0
+
+	for _, line := range lines {
+		fed++
+
+		if !detector.Feed(line) {
+			break
+		}
+	}
+
+	if fed != 2 {
+		t.Errorf("Feed() consumed %d lines; want 2", fed)
+	}
+
+	if result, _, err := detector.Result(); err != nil || result != FormatPlain {
+		t.Errorf("Result() = %q, %v; want %q", result, err, FormatPlain)
+	}
+}
+
+func TestDetectReader(t *testing.T) {
+	tests := []struct {
+		content  string
+		size     int
+		expected Format
+	}{
+		{"# comment\r\n0.0.0.0 example.com\r\n0.0.0.0 example.org", 4096, FormatHosts},
+		{"||example.com^\n||example.org^\n", 4096, FormatABP},
+		// The cut line of a full buffer is not sampled.
+		{"example.com\nexample.org\n0.0.0.0 example.net\n", 28, FormatPlain},
+		{"", 4096, FormatPlain},
+	}
+
+	for _, test := range tests {
+		reader := bufio.NewReaderSize(strings.NewReader(test.content), test.size)
+
+		result, reason, err := DetectReader(reader, DefaultDetectionSampleSize)
+		if err != nil || result != test.expected {
+			t.Errorf("DetectReader(%q) = %q (%s), %v; want %q", test.content, result, reason, err, test.expected)
+		}
+
+		// The sampled lines are still to be read.
+		if content, _ := io.ReadAll(reader); string(content) != test.content {
+			t.Errorf("DetectReader(%q) consumed the stream, %q left", test.content, content)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SupportedFormats returns the list of supported source formats.
+func SupportedFormats() []Format {
+	return []Format{FormatPlain, FormatHosts, FormatABP, FormatDnsmasq, FormatUnbound, FormatRPZ}
+}
+
+// NewParser creates a new parser for the given format.
+//
+// Args:
+//
+//	format: The format of the source file.
+//
+// Returns:
+//
+//	Parser: The parser to use for the given format.
+//	error: An error if the format is not supported.
+func NewParser(format Format) (Parser, error) {
+	switch Format(strings.ToLower(string(format))) {
+	case FormatPlain:
+		return &plainParser{}, nil
+	case FormatHosts:
+		return &hostsParser{}, nil
+	case FormatABP:
+		return &abpParser{}, nil
+	case FormatDnsmasq:
+		return &dnsmasqParser{}, nil
+	case FormatUnbound:
+		return &unboundParser{}, nil
+	case FormatRPZ:
+		return &rpzParser{}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported source format: %s", format)
+}
+
+// stripComment removes everything starting from the given comment marker.
+func stripComment(line string, marker string) string {
+	if index := strings.Index(line, marker); index >= 0 {
+		return line[:index]
+	}
+
+	return line
+}
+
+type plainParser struct{}
+
+func (p *plainParser) Format() Format {
+	return FormatPlain
+}
+
+func (p *plainParser) Parse(line string) Record {
+	subject := strings.TrimSpace(stripComment(line, "#"))
+
+	if subject == "" {
+		return Record{Line: line}
+	}
+
+	return Record{Line: line, Subjects: []string{subject}}
+}
+
+func (p *plainParser) Rebuild(record Record, subjects []string) string {
+	return record.Line
+}
+
+type hostsParser struct{}
+
+func (p *hostsParser) Format() Format {
+	return FormatHosts
+}
+
+func (p *hostsParser) Parse(line string) Record {
+	fields := strings.Fields(stripComment(line, "#"))
+
+	if len(fields) < 2 {
+		return Record{Line: line}
+	}
+
+	return Record{Line: line, Subjects: fields[1:]}
+}
+
+func (p *hostsParser) Rebuild(record Record, subjects []string) string {
+	fields := strings.Fields(stripComment(record.Line, "#"))
+
+	if len(fields) < 2 || len(subjects) == 0 {
+		return record.Line
+	}
+
+	separator := " "
+
+	if strings.Contains(record.Line, "\t") {
+		separator = "\t"
+	}
+
+	result := fields[0] + separator + strings.Join(subjects, " ")
+
+	if index := strings.Index(record.Line, "#"); index >= 0 {
+		result += " " + record.Line[index:]
+	}
+
+	return result
+}
+
+type abpParser struct{}
+
+func (p *abpParser) Format() Format {
+	return FormatABP
+}
+
+func (p *abpParser) Parse(line string) Record {
+	trimmed := strings.TrimSpace(line)
+
+	// Only the domain anchored rules (||example.com^) embed a subject. Exception
+	// and cosmetic rules are preserved as they are.
+	rule, found := strings.CutPrefix(trimmed, "||")
+
+	if !found || strings.Contains(rule, "##") || strings.Contains(rule, "#@#") {
+		return Record{Line: line}
+	}
+
+	rule, _, _ = strings.Cut(rule, "$")
+	rule = strings.TrimSuffix(strings.TrimSuffix(rule, "|"), "^")
+
+	if rule == "" || strings.ContainsAny(rule, "/*^|") {
+		return Record{Line: line}
+	}
+
+	return Record{Line: line, Subjects: []string{rule}}
+}
+
+func (p *abpParser) Rebuild(record Record, subjects []string) string {
+	return record.Line
+}
+
+// dnsmasqKeys are the dnsmasq options which embed a list of domains.
+var dnsmasqKeys = []string{"address", "server", "local"}
+
+type dnsmasqParser struct{}
+
+func (p *dnsmasqParser) Format() Format {
+	return FormatDnsmasq
+}
+
+// split splits a dnsmasq option into its key, its domains and its value.
+func (p *dnsmasqParser) split(line string) (string, []string, string, bool) {
+	trimmed := strings.TrimSpace(line)
+
+	// dnsmasq only supports full line comments.
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", nil, "", false
+	}
+
+	key, value, found := strings.Cut(trimmed, "=")
+	key = strings.TrimSpace(key)
+
+	if !found || !strings.HasPrefix(value, "/") {
+		return "", nil, "", false
+	}
+
+	known := false
+
+	for _, dnsmasqKey := range dnsmasqKeys {
+		if key == dnsmasqKey {
+			known = true
+			break
+		}
+	}
+
+	if !known {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(value[1:], "/")
+
+	if len(parts) < 2 {
+		return "", nil, "", false
+	}
+
+	return key, parts[:len(parts)-1], parts[len(parts)-1], true
+}
+
+func (p *dnsmasqParser) Parse(line string) Record {
+	_, domains, _, ok := p.split(line)
+
+	if !ok {
+		return Record{Line: line}
+	}
+
+	var subjects []string
+
+	for _, domain := range domains {
+		// "#" matches any domain, there is nothing to check against it.
+		if domain == "" || domain == "#" {
+			continue
+		}
+
+		subjects = append(subjects, strings.TrimPrefix(domain, "."))
+	}
+
+	return Record{Line: line, Subjects: subjects}
+}
+
+func (p *dnsmasqParser) Rebuild(record Record, subjects []string) string {
+	key, _, value, ok := p.split(record.Line)
+
+	if !ok || len(subjects) == 0 {
+		return record.Line
+	}
+
+	return fmt.Sprintf("%s=/%s/%s", key, strings.Join(subjects, "/"), value)
+}
+
+type unboundParser struct{}
+
+func (p *unboundParser) Format() Format {
+	return FormatUnbound
+}
+
+func (p *unboundParser) Parse(line string) Record {
+	trimmed := strings.TrimSpace(stripComment(line, "#"))
+
+	var value string
+
+	if rest, found := strings.CutPrefix(trimmed, "local-zone:"); found {
+		fields := strings.Fields(rest)
+
+		if len(fields) == 0 {
+			return Record{Line: line}
+		}
+
+		value = strings.Trim(fields[0], `"`)
+	} else if rest, found := strings.CutPrefix(trimmed, "local-data:"); found {
+		fields := strings.Fields(strings.Trim(strings.TrimSpace(rest), `"`))
+
+		if len(fields) == 0 {
+			return Record{Line: line}
+		}
+
+		value = fields[0]
+	} else {
+		return Record{Line: line}
+	}
+
+	value = strings.TrimSuffix(value, ".")
+
+	if value == "" {
+		return Record{Line: line}
+	}
+
+	return Record{Line: line, Subjects: []string{value}}
+}
+
+func (p *unboundParser) Rebuild(record Record, subjects []string) string {
+	return record.Line
+}
+
+// rpzClasses are the DNS classes which may precede the record type.
+var rpzClasses = []string{"IN", "CH", "HS", "CS"}
+
+// rpzPreservedTypes are the record types which are always preserved.
+var rpzPreservedTypes = []string{"SOA", "NS"}
+
+// rpzPassthru is the CNAME target of the records exempting their owner from
+// the policy. Such records are preserved, their owner is not blocked.
+const rpzPassthru = "rpz-passthru."
+
+type rpzParser struct {
+	// origin is the last seen $ORIGIN, without its trailing dot.
+	origin string
+	// depth is the number of unclosed parentheses, multi-line records (e.g
+	// SOA) are written between parentheses.
+	depth int
+}
+
+func (p *rpzParser) Format() Format {
+	return FormatRPZ
+}
+
+func (p *rpzParser) Parse(line string) Record {
+	record := Record{Line: line}
+	content := stripComment(line, ";")
+
+	continuation := p.depth > 0
+	p.depth = max(p.depth+strings.Count(content, "(")-strings.Count(content, ")"), 0)
+
+	trimmed := strings.TrimSpace(content)
+
+	if continuation || trimmed == "" {
+		return record
+	}
+
+	fields := strings.Fields(trimmed)
+
+	if strings.HasPrefix(fields[0], "$") {
+		if strings.EqualFold(fields[0], "$ORIGIN") && len(fields) > 1 {
+			p.origin = strings.ToLower(strings.TrimSuffix(fields[1], "."))
+		}
+
+		return record
+	}
+
+	// Lines starting with a blank reuse the owner of the previous record.
+	if line[0] == ' ' || line[0] == '\t' || len(fields) < 2 {
+		return record
+	}
+
+	index := 1
+
+	if _, err := strconv.Atoi(fields[index]); err == nil && index < len(fields)-1 {
+		index++
+	}
+
+	for _, class := range rpzClasses {
+		if strings.EqualFold(fields[index], class) && index < len(fields)-1 {
+			index++
+			break
+		}
+	}
+
+	if _, err := strconv.Atoi(fields[index]); err == nil && index < len(fields)-1 {
+		index++
+	}
+
+	for _, preserved := range rpzPreservedTypes {
+		if strings.EqualFold(fields[index], preserved) {
+			return record
+		}
+	}
+
+	if strings.EqualFold(fields[index], "CNAME") && index < len(fields)-1 && strings.EqualFold(fields[index+1], rpzPassthru) {
+		return record
+	}
+
+	owner := fields[0]
+
+	if strings.HasSuffix(owner, ".") {
+		owner = strings.TrimSuffix(owner, ".")
+
+		if p.origin != "" {
+			owner = strings.TrimSuffix(owner, "."+p.origin)
+		}
+	}
+
+	if owner == "@" || owner == "*" || owner == "" {
+		return record
+	}
+
+	if strings.HasPrefix(owner, "*.") {
+		record.Wildcard = true
+		owner = strings.TrimPrefix(owner, "*.")
+	}
+
+	record.Subjects = []string{owner}
+
+	return record
+}
+
+func (p *rpzParser) Rebuild(record Record, subjects []string) string {
+	return record.Line
+}
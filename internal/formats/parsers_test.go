@@ -0,0 +1,135 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		format   Format
+		input    string
+		subjects []string
+		wildcard bool
+	}{
+		{FormatPlain, "example.com", []string{"example.com"}, false},
+		{FormatPlain, "example.com # comment", []string{"example.com"}, false},
+		{FormatPlain, "# comment", nil, false},
+		{FormatHosts, "0.0.0.0 example.com", []string{"example.com"}, false},
+		{FormatHosts, "0.0.0.0\texample.com example.org # comment", []string{"example.com", "example.org"}, false},
+		{FormatHosts, "# 0.0.0.0 example.com", nil, false},
+		{FormatABP, "||example.com^", []string{"example.com"}, false},
+		{FormatABP, "||example.com^$third-party", []string{"example.com"}, false},
+		{FormatABP, "||example.com/ads^", nil, false},
+		{FormatABP, "@@||example.com^", nil, false},
+		{FormatABP, "example.com##.ad", nil, false},
+		{FormatABP, "! comment", nil, false},
+		{FormatDnsmasq, "address=/example.com/0.0.0.0", []string{"example.com"}, false},
+		{FormatDnsmasq, "address=/example.com/example.org/", []string{"example.com", "example.org"}, false},
+		{FormatDnsmasq, "server=/.example.com/", []string{"example.com"}, false},
+		{FormatDnsmasq, "address=/#/0.0.0.0", nil, false},
+		{FormatDnsmasq, "cache-size=1000", nil, false},
+		{FormatDnsmasq, "# address=/example.com/0.0.0.0", nil, false},
+		{FormatUnbound, `local-zone: "example.com" always_nxdomain`, []string{"example.com"}, false},
+		{FormatUnbound, `local-zone: "example.com." refuse # comment`, []string{"example.com"}, false},
+		{FormatUnbound, `local-data: "example.com A 0.0.0.0"`, []string{"example.com"}, false},
+		{FormatUnbound, "server:", nil, false},
+		{FormatRPZ, "example.com CNAME .", []string{"example.com"}, false},
+		{FormatRPZ, "*.example.com CNAME .", []string{"example.com"}, true},
+		{FormatRPZ, "example.com 3600 IN CNAME . ; comment", []string{"example.com"}, false},
+		{FormatRPZ, "@ IN NS localhost.", nil, false},
+		{FormatRPZ, "good.example.com CNAME rpz-passthru.", nil, false},
+		{FormatRPZ, "*.good.example.com 3600 IN CNAME RPZ-PASSTHRU. ; exception", nil, false},
+		{FormatRPZ, "$TTL 2h", nil, false},
+		{FormatRPZ, "; example.com CNAME .", nil, false},
+	}
+
+	for _, test := range tests {
+		parser, err := NewParser(test.format)
+		if err != nil {
+			t.Fatalf("NewParser(%q) returned error: %v", test.format, err)
+		}
+
+		record := parser.Parse(test.input)
+
+		if record.Line != test.input {
+			t.Errorf("Parse(%q).Line = %q; want %q", test.input, record.Line, test.input)
+		}
+
+		if !slices.Equal(record.Subjects, test.subjects) {
+			t.Errorf("Parse(%q).Subjects = %q; want %q", test.input, record.Subjects, test.subjects)
+		}
+
+		if record.Wildcard != test.wildcard {
+			t.Errorf("Parse(%q).Wildcard = %v; want %v", test.input, record.Wildcard, test.wildcard)
+		}
+	}
+}
+
+func TestParseRPZZone(t *testing.T) {
+	parser, _ := NewParser(FormatRPZ)
+
+	lines := []string{
+		"$ORIGIN rpz.example.net.",
+		"@ IN SOA localhost. root.localhost. (",
+		"    2025010101 ; serial",
+		"    3600 600 86400 60 )",
+		"  IN NS localhost.",
+		"example.com.rpz.example.net. CNAME .",
+		"*.example.org CNAME .",
+	}
+
+	expected := [][]string{nil, nil, nil, nil, nil, {"example.com"}, {"example.org"}}
+
+	for index, line := range lines {
+		record := parser.Parse(line)
+
+		if !slices.Equal(record.Subjects, expected[index]) {
+			t.Errorf("Parse(%q).Subjects = %q; want %q", line, record.Subjects, expected[index])
+		}
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	tests := []struct {
+		format   Format
+		input    string
+		subjects []string
+		expected string
+	}{
+		{FormatHosts, "0.0.0.0 example.com example.org", []string{"example.org"}, "0.0.0.0 example.org"},
+		{FormatHosts, "0.0.0.0\texample.com example.org # comment", []string{"example.com"}, "0.0.0.0\texample.com # comment"},
+		{FormatDnsmasq, "address=/example.com/example.org/0.0.0.0", []string{"example.org"}, "address=/example.org/0.0.0.0"},
+		{FormatDnsmasq, "server=/example.com/example.org/", []string{"example.com"}, "server=/example.com/"},
+	}
+
+	for _, test := range tests {
+		parser, _ := NewParser(test.format)
+
+		result := parser.Rebuild(parser.Parse(test.input), test.subjects)
+		if result != test.expected {
+			t.Errorf("Rebuild(%q, %q) = %q; want %q", test.input, test.subjects, result, test.expected)
+		}
+	}
+}
+
+func TestNewParserUnsupported(t *testing.T) {
+	if _, err := NewParser("unknown"); err == nil {
+		t.Errorf("NewParser(%q) returned no error", "unknown")
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RPZHeader returns the header (TTL, SOA and NS records) of a generated RPZ zone.
+//
+// Args:
+//
+//	serial: The serial of the SOA record.
+//
+// Returns:
+//
+//	[]string: The lines of the header.
+func RPZHeader(serial uint32) []string {
+	return []string{
+		"$TTL 3600",
+		"@ IN SOA localhost. root.localhost. (",
+		fmt.Sprintf("    %d ; serial", serial),
+		"    3600 ; refresh",
+		"    600 ; retry",
+		"    86400 ; expire",
+		"    60 ) ; minimum",
+		"  IN NS localhost.",
+	}
+}
+
+const (
+	// LossSubdomainsDropped: the output format has no subtree, only the domain
+	// itself is written and its subdomains are no longer covered.
+	LossSubdomainsDropped = "subtree narrowed, the subdomains are no longer covered"
+	// LossExactWidened: the output format only has subtrees, the subdomains of
+	// the exact entry are covered too.
+	LossExactWidened = "exact entry widened, the subdomains are covered too"
+)
+
+// coversSubdomains checks if a subject of the given source format covers its subdomains.
+//
+// Args:
+//
+//	source: The format of the source the subject comes from.
+//	wildcard: Whether the subject comes from a wildcard record.
+//
+// Returns:
+//
+//	bool: true if the subdomains of the subject are covered, false otherwise.
+func coversSubdomains(source Format, wildcard bool) bool {
+	switch Format(strings.ToLower(string(source))) {
+	case FormatABP, FormatDnsmasq, FormatUnbound:
+		return true
+	}
+
+	return wildcard
+}
+
+// SupportedOutputFormats returns the list of supported output formats.
+func SupportedOutputFormats() []Format {
+	return []Format{FormatHosts, FormatDomains, FormatABP, FormatDnsmasq, FormatUnbound, FormatRPZ}
+}
+
+// NewRenderer creates a new renderer for the given output format.
+//
+// Args:
+//
+//	format: The output format.
+//	options: The options of the renderer.
+//
+// Returns:
+//
+//	Renderer: The renderer to use for the given format.
+//	error: An error if the format is not supported.
+func NewRenderer(format Format, options RendererOptions) (Renderer, error) {
+	if options.SinkIP == "" {
+		options.SinkIP = DefaultSinkIP
+	}
+
+	if options.HostsPerLine <= 0 {
+		options.HostsPerLine = 1
+	}
+
+	switch Format(strings.ToLower(string(format))) {
+	case FormatHosts:
+		return &hostsRenderer{options: options}, nil
+	case FormatDomains, FormatPlain:
+		return &domainsRenderer{}, nil
+	case FormatABP:
+		return &abpRenderer{}, nil
+	case FormatDnsmasq:
+		return &dnsmasqRenderer{options: options}, nil
+	case FormatUnbound:
+		return &unboundRenderer{}, nil
+	case FormatRPZ:
+		return &rpzRenderer{options: options}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format: %s", format)
+}
+
+type hostsRenderer struct {
+	options RendererOptions
+	pending []string
+}
+
+func (r *hostsRenderer) Format() Format {
+	return FormatHosts
+}
+
+func (r *hostsRenderer) Header() []string {
+	return nil
+}
+
+func (r *hostsRenderer) Render(subject string, wildcard bool) []string {
+	r.pending = append(r.pending, subject)
+
+	if len(r.pending) < r.options.HostsPerLine {
+		return nil
+	}
+
+	return r.Flush()
+}
+
+func (r *hostsRenderer) Lossy(source Format, wildcard bool) string {
+	if coversSubdomains(source, wildcard) {
+		return LossSubdomainsDropped
+	}
+
+	return ""
+}
+
+func (r *hostsRenderer) Flush() []string {
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	line := fmt.Sprintf("%s %s", r.options.SinkIP, strings.Join(r.pending, " "))
+	r.pending = r.pending[:0]
+
+	return []string{line}
+}
+
+type domainsRenderer struct{}
+
+func (r *domainsRenderer) Format() Format {
+	return FormatDomains
+}
+
+func (r *domainsRenderer) Header() []string {
+	return nil
+}
+
+func (r *domainsRenderer) Render(subject string, wildcard bool) []string {
+	return []string{subject}
+}
+
+func (r *domainsRenderer) Lossy(source Format, wildcard bool) string {
+	if coversSubdomains(source, wildcard) {
+		return LossSubdomainsDropped
+	}
+
+	return ""
+}
+
+func (r *domainsRenderer) Flush() []string {
+	return nil
+}
+
+type abpRenderer struct{}
+
+func (r *abpRenderer) Format() Format {
+	return FormatABP
+}
+
+func (r *abpRenderer) Header() []string {
+	return []string{"[Adblock Plus 2.0]"}
+}
+
+func (r *abpRenderer) Render(subject string, wildcard bool) []string {
+	return []string{fmt.Sprintf("||%s^", subject)}
+}
+
+func (r *abpRenderer) Lossy(source Format, wildcard bool) string {
+	if !coversSubdomains(source, wildcard) {
+		return LossExactWidened
+	}
+
+	return ""
+}
+
+func (r *abpRenderer) Flush() []string {
+	return nil
+}
+
+type dnsmasqRenderer struct {
+	options RendererOptions
+}
+
+func (r *dnsmasqRenderer) Format() Format {
+	return FormatDnsmasq
+}
+
+func (r *dnsmasqRenderer) Header() []string {
+	return nil
+}
+
+func (r *dnsmasqRenderer) Render(subject string, wildcard bool) []string {
+	return []string{fmt.Sprintf("address=/%s/%s", subject, r.options.SinkIP)}
+}
+
+func (r *dnsmasqRenderer) Lossy(source Format, wildcard bool) string {
+	if !coversSubdomains(source, wildcard) {
+		return LossExactWidened
+	}
+
+	return ""
+}
+
+func (r *dnsmasqRenderer) Flush() []string {
+	return nil
+}
+
+type unboundRenderer struct{}
+
+func (r *unboundRenderer) Format() Format {
+	return FormatUnbound
+}
+
+func (r *unboundRenderer) Header() []string {
+	return []string{"server:"}
+}
+
+func (r *unboundRenderer) Render(subject string, wildcard bool) []string {
+	return []string{fmt.Sprintf(`local-zone: "%s" always_nxdomain`, subject)}
+}
+
+func (r *unboundRenderer) Lossy(source Format, wildcard bool) string {
+	if !coversSubdomains(source, wildcard) {
+		return LossExactWidened
+	}
+
+	return ""
+}
+
+func (r *unboundRenderer) Flush() []string {
+	return nil
+}
+
+type rpzRenderer struct {
+	options RendererOptions
+}
+
+func (r *rpzRenderer) Format() Format {
+	return FormatRPZ
+}
+
+func (r *rpzRenderer) Header() []string {
+	return RPZHeader(r.options.Serial)
+}
+
+func (r *rpzRenderer) Render(subject string, wildcard bool) []string {
+	if wildcard {
+		return []string{fmt.Sprintf("*.%s CNAME .", subject)}
+	}
+
+	return []string{fmt.Sprintf("%s CNAME .", subject)}
+}
+
+func (r *rpzRenderer) Lossy(source Format, wildcard bool) string {
+	if !wildcard && coversSubdomains(source, wildcard) {
+		return LossSubdomainsDropped
+	}
+
+	return ""
+}
+
+func (r *rpzRenderer) Flush() []string {
+	return nil
+}
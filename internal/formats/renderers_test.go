@@ -0,0 +1,136 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+import (
+	"slices"
+	"testing"
+)
+
+func testRender(renderer Renderer, subjects []string, wildcard bool) []string {
+	result := renderer.Header()
+
+	for _, subject := range subjects {
+		result = append(result, renderer.Render(subject, wildcard)...)
+	}
+
+	return append(result, renderer.Flush()...)
+}
+
+func TestRender(t *testing.T) {
+	subjects := []string{"example.com", "example.org", "example.net"}
+
+	tests := []struct {
+		format   Format
+		options  RendererOptions
+		wildcard bool
+		expected []string
+	}{
+		{FormatHosts, RendererOptions{}, false, []string{"0.0.0.0 example.com", "0.0.0.0 example.org", "0.0.0.0 example.net"}},
+		{FormatHosts, RendererOptions{SinkIP: "::", HostsPerLine: 2}, false, []string{":: example.com example.org", ":: example.net"}},
+		{FormatDomains, RendererOptions{}, false, subjects},
+		{FormatABP, RendererOptions{}, false, []string{"[Adblock Plus 2.0]", "||example.com^", "||example.org^", "||example.net^"}},
+		{FormatDnsmasq, RendererOptions{}, false, []string{"address=/example.com/0.0.0.0", "address=/example.org/0.0.0.0", "address=/example.net/0.0.0.0"}},
+		{FormatUnbound, RendererOptions{}, false, []string{
+			"server:",
+			`local-zone: "example.com" always_nxdomain`,
+			`local-zone: "example.org" always_nxdomain`,
+			`local-zone: "example.net" always_nxdomain`,
+		}},
+	}
+
+	for _, test := range tests {
+		renderer, err := NewRenderer(test.format, test.options)
+		if err != nil {
+			t.Fatalf("NewRenderer(%q) returned error: %v", test.format, err)
+		}
+
+		result := testRender(renderer, subjects, test.wildcard)
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("Render(%q) = %q; want %q", test.format, result, test.expected)
+		}
+	}
+}
+
+func TestRenderRPZ(t *testing.T) {
+	renderer, _ := NewRenderer(FormatRPZ, RendererOptions{Serial: 1234})
+
+	header := renderer.Header()
+
+	if !slices.Contains(header, "    1234 ; serial") {
+		t.Errorf("Header() = %q; want the serial to be included", header)
+	}
+
+	tests := []struct {
+		subject  string
+		wildcard bool
+		expected string
+	}{
+		{"example.com", false, "example.com CNAME ."},
+		{"example.com", true, "*.example.com CNAME ."},
+	}
+
+	for _, test := range tests {
+		result := renderer.Render(test.subject, test.wildcard)
+		if !slices.Equal(result, []string{test.expected}) {
+			t.Errorf("Render(%q, %v) = %q; want %q", test.subject, test.wildcard, result, test.expected)
+		}
+	}
+}
+
+func TestRenderLossy(t *testing.T) {
+	tests := []struct {
+		format   Format
+		source   Format
+		wildcard bool
+		expected string
+	}{
+		{FormatHosts, FormatHosts, false, ""},
+		{FormatHosts, FormatRPZ, true, LossSubdomainsDropped},
+		{FormatHosts, FormatABP, false, LossSubdomainsDropped},
+		{FormatDomains, FormatPlain, false, ""},
+		{FormatDomains, FormatRPZ, true, LossSubdomainsDropped},
+		{FormatDomains, FormatDnsmasq, false, LossSubdomainsDropped},
+		{FormatABP, FormatHosts, false, LossExactWidened},
+		{FormatABP, FormatRPZ, false, LossExactWidened},
+		{FormatABP, FormatRPZ, true, ""},
+		{FormatABP, FormatUnbound, false, ""},
+		{FormatDnsmasq, FormatPlain, false, LossExactWidened},
+		{FormatDnsmasq, FormatABP, false, ""},
+		{FormatUnbound, FormatHosts, false, LossExactWidened},
+		{FormatUnbound, FormatDnsmasq, false, ""},
+		{FormatRPZ, FormatHosts, false, ""},
+		{FormatRPZ, FormatRPZ, true, ""},
+		{FormatRPZ, FormatABP, false, LossSubdomainsDropped},
+	}
+
+	for _, test := range tests {
+		renderer, err := NewRenderer(test.format, RendererOptions{})
+		if err != nil {
+			t.Fatalf("NewRenderer(%q) returned error: %v", test.format, err)
+		}
+
+		if result := renderer.Lossy(test.source, test.wildcard); result != test.expected {
+			t.Errorf("Lossy(%q, %q, %v) = %q; want %q", test.format, test.source, test.wildcard, result, test.expected)
+		}
+	}
+}
+
+func TestNewRendererUnsupported(t *testing.T) {
+	if _, err := NewRenderer("unknown", RendererOptions{}); err == nil {
+		t.Errorf("NewRenderer(%q) returned no error", "unknown")
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package formats
+
+// Format is the name of a supported source format.
+type Format string
+
+const (
+	// FormatPlain: one subject per line.
+	FormatPlain Format = "plain"
+	// FormatHosts: hosts file, an IP followed by one or more hostnames.
+	FormatHosts Format = "hosts"
+	// FormatABP: Adblock Plus filter list (||example.com^).
+	FormatABP Format = "abp"
+	// FormatDnsmasq: dnsmasq configuration (address=/.../ and server=/.../).
+	FormatDnsmasq Format = "dnsmasq"
+	// FormatUnbound: Unbound configuration (local-zone: and local-data:).
+	FormatUnbound Format = "unbound"
+	// FormatRPZ: BIND Response Policy Zone file.
+	FormatRPZ Format = "rpz"
+
+	// FormatDomains: one domain per line, only used as output format.
+	FormatDomains Format = "domains"
+
+	// FormatAuto: let Givilsta detect the format of the source file.
+	FormatAuto Format = "auto"
+)
+
+// DefaultSinkIP is the IP written in front of the hostnames of a hosts file.
+const DefaultSinkIP = "0.0.0.0"
+
+// DefaultDetectionSampleSize is the number of non-comment lines sampled to
+// detect the format of a source file.
+const DefaultDetectionSampleSize = 100
+
+// Record represents a single line of a source file.
+type Record struct {
+	// Line is the line as read from the source file.
+	Line string
+	// Subjects holds the subjects embedded in the line. It is empty when the
+	// line has to be preserved as it is (comments, SOA or NS records, ...).
+	Subjects []string
+	// Wildcard is set when the subjects are meant to cover all their
+	// subdomains (e.g. RPZ "*.example.com" owners).
+	Wildcard bool
+}
+
+// Parser extracts the subjects of the lines of a source file.
+type Parser interface {
+	// Format returns the format handled by the parser.
+	Format() Format
+	// Parse parses a single line.
+	Parse(line string) Record
+	// Rebuild rewrites the given record so that it only contains the given
+	// subjects.
+	Rebuild(record Record, subjects []string) string
+}
+
+// RendererOptions holds the options of the renderers.
+type RendererOptions struct {
+	// SinkIP is the IP the blocked subjects resolve to (hosts and dnsmasq).
+	SinkIP string
+	// HostsPerLine is the number of hostnames written per line (hosts).
+	HostsPerLine int
+	// Serial is the serial of the generated SOA record (rpz).
+	Serial uint32
+}
+
+// Renderer converts subjects into the lines of a target format.
+type Renderer interface {
+	// Format returns the format handled by the renderer.
+	Format() Format
+	// Header returns the lines to write before any subject.
+	Header() []string
+	// Render returns the lines representing the given subject. Wildcard is
+	// set when the subject is meant to cover all its subdomains.
+	Render(subject string, wildcard bool) []string
+	// Lossy returns how rendering a subject of the given source format
+	// changes what it covers, empty when the output format keeps its meaning.
+	Lossy(source Format, wildcard bool) string
+	// Flush returns the lines still buffered by the renderer.
+	Flush() []string
+}
@@ -0,0 +1,241 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	dsnetbzip2 "github.com/dsnet/compress/bzip2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression is the name of a supported compression algorithm.
+type Compression string
+
+const (
+	CompressionNone  Compression = ""
+	CompressionGzip  Compression = "gzip"
+	CompressionBzip2 Compression = "bzip2"
+	CompressionXz    Compression = "xz"
+	CompressionZstd  Compression = "zstd"
+)
+
+// compressionMagics maps the magic bytes starting a compressed stream to its compression.
+var compressionMagics = []struct {
+	magic       []byte
+	compression Compression
+}{
+	{[]byte{0x1f, 0x8b}, CompressionGzip},
+	{[]byte("BZh"), CompressionBzip2},
+	{[]byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}, CompressionXz},
+	{[]byte{0x28, 0xb5, 0x2f, 0xfd}, CompressionZstd},
+}
+
+// compressionExtensions maps the file extensions to their compression.
+var compressionExtensions = map[string]Compression{
+	".gz":   CompressionGzip,
+	".gzip": CompressionGzip,
+	".bz2":  CompressionBzip2,
+	".xz":   CompressionXz,
+	".zst":  CompressionZstd,
+	".zstd": CompressionZstd,
+}
+
+// CompressionFromPath guesses the compression of a file from its extension.
+//
+// Args:
+//
+//	filePath: The path of the file.
+//
+// Returns:
+//
+//	The compression of the file, CompressionNone if the extension is not known.
+func CompressionFromPath(filePath string) Compression {
+	return compressionExtensions[strings.ToLower(filepath.Ext(filePath))]
+}
+
+// TrimCompressionExtension removes the compression extension of the given file, if any.
+//
+// Args:
+//
+//	filePath: The path of the file.
+//
+// Returns:
+//
+//	The path of the file without its compression extension (e.g. "rules.toml" for "rules.toml.gz").
+func TrimCompressionExtension(filePath string) string {
+	extension := filepath.Ext(filePath)
+
+	if _, ok := compressionExtensions[strings.ToLower(extension)]; ok {
+		return strings.TrimSuffix(filePath, extension)
+	}
+
+	return filePath
+}
+
+// chainedReadCloser closes a reader and the reader it wraps.
+type chainedReadCloser struct {
+	io.ReadCloser
+	next io.Closer
+}
+
+func (c *chainedReadCloser) Close() error {
+	if err := c.ReadCloser.Close(); err != nil {
+		_ = c.next.Close()
+		return err
+	}
+
+	return c.next.Close()
+}
+
+// NewDecompressingReader wraps the given reader so that it transparently decompresses
+// its content. The compression is detected through the magic bytes of the stream.
+//
+// Args:
+//
+//	reader: The reader to wrap.
+//
+// Returns:
+//
+//	An io.ReadCloser yielding the decompressed content, and an error if the compressed stream cannot be read.
+func NewDecompressingReader(reader io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(reader)
+
+	// An error means that the stream is shorter than the longest magic, which is fine.
+	head, _ := buffered.Peek(6)
+
+	compression := CompressionNone
+
+	for _, candidate := range compressionMagics {
+		if bytes.HasPrefix(head, candidate.magic) {
+			compression = candidate.compression
+			break
+		}
+	}
+
+	return NewDecompressor(buffered, compression)
+}
+
+// NewDecompressor wraps the given reader so that it decompresses its content with the given compression.
+//
+// Args:
+//
+//	reader: The reader to wrap.
+//	compression: The compression of the content.
+//
+// Returns:
+//
+//	An io.ReadCloser yielding the decompressed content, and an error if the compressed stream cannot be read.
+func NewDecompressor(reader io.Reader, compression Compression) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return io.NopCloser(reader), nil
+	case CompressionGzip:
+		return gzip.NewReader(reader)
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(reader)), nil
+	case CompressionXz:
+		decompressor, err := xz.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading xz stream: %w", err)
+		}
+
+		return io.NopCloser(decompressor), nil
+	case CompressionZstd:
+		decompressor, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("reading zstd stream: %w", err)
+		}
+
+		return decompressor.IOReadCloser(), nil
+	}
+
+	return nil, fmt.Errorf("unsupported compression %q", compression)
+}
+
+// NewContentDecoder wraps the body of an HTTP response so that it decodes the given Content-Encoding.
+//
+// Args:
+//
+//	reader: The body to wrap.
+//	encoding: The value of the Content-Encoding header.
+//
+// Returns:
+//
+//	An io.ReadCloser yielding the decoded content, and an error if the encoding is not supported.
+func NewContentDecoder(reader io.Reader, encoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return io.NopCloser(reader), nil
+	case "gzip", "x-gzip":
+		return NewDecompressor(reader, CompressionGzip)
+	case "deflate":
+		return zlib.NewReader(reader)
+	case "bzip2", "x-bzip2":
+		return NewDecompressor(reader, CompressionBzip2)
+	case "xz", "x-xz":
+		return NewDecompressor(reader, CompressionXz)
+	case "zstd":
+		return NewDecompressor(reader, CompressionZstd)
+	}
+
+	return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+}
+
+// NewCompressor wraps the given writer so that it compresses what is written with the given compression.
+//
+// Args:
+//
+//	writer: The writer to wrap.
+//	compression: The compression to apply.
+//
+// Returns:
+//
+//	An io.WriteCloser compressing its input. Closing it does not close the wrapped writer.
+func NewCompressor(writer io.Writer, compression Compression) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nopWriteCloser{writer}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(writer), nil
+	case CompressionBzip2:
+		// The standard library only reads bzip2.
+		return dsnetbzip2.NewWriter(writer, nil)
+	case CompressionXz:
+		return xz.NewWriter(writer)
+	case CompressionZstd:
+		return zstd.NewWriter(writer)
+	}
+
+	return nil, fmt.Errorf("unsupported compression %q", compression)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package helpers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func testCompress(t *testing.T, content string, compression Compression) []byte {
+	var buffer bytes.Buffer
+
+	compressor, err := NewCompressor(&buffer, compression)
+	if err != nil {
+		t.Fatalf("NewCompressor(%q) returned error: %v", compression, err)
+	}
+
+	if _, err := compressor.Write([]byte(content)); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	if err := compressor.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestDecompressingReader(t *testing.T) {
+	content := "example.com\nexample.org\n"
+
+	tests := []Compression{CompressionNone, CompressionGzip, CompressionBzip2, CompressionXz, CompressionZstd}
+
+	for _, compression := range tests {
+		reader, err := NewDecompressingReader(bytes.NewReader(testCompress(t, content, compression)))
+		if err != nil {
+			t.Errorf("NewDecompressingReader(%q) returned error: %v", compression, err)
+			continue
+		}
+
+		result, err := io.ReadAll(reader)
+		if err != nil {
+			t.Errorf("ReadAll(%q) returned error: %v", compression, err)
+		}
+
+		if err := reader.Close(); err != nil {
+			t.Errorf("Close(%q) returned error: %v", compression, err)
+		}
+
+		if string(result) != content {
+			t.Errorf("NewDecompressingReader(%q) = %q; want %q", compression, result, content)
+		}
+	}
+}
+
+func TestUnsupportedCompression(t *testing.T) {
+	if _, err := NewCompressor(&bytes.Buffer{}, Compression("lz4")); err == nil {
+		t.Errorf("NewCompressor(%q) returned no error", "lz4")
+	}
+
+	if _, err := NewDecompressor(&bytes.Buffer{}, Compression("lz4")); err == nil {
+		t.Errorf("NewDecompressor(%q) returned no error", "lz4")
+	}
+}
+
+func TestContentDecoder(t *testing.T) {
+	var buffer bytes.Buffer
+
+	writer := gzip.NewWriter(&buffer)
+	_, _ = writer.Write([]byte("example.com\n"))
+	_ = writer.Close()
+
+	reader, err := NewContentDecoder(&buffer, "gzip")
+	if err != nil {
+		t.Fatalf("NewContentDecoder() returned error: %v", err)
+	}
+
+	result, _ := io.ReadAll(reader)
+	if string(result) != "example.com\n" {
+		t.Errorf("NewContentDecoder() = %q; want %q", result, "example.com\n")
+	}
+
+	if _, err := NewContentDecoder(&buffer, "br"); err == nil {
+		t.Errorf("NewContentDecoder(%q) returned no error", "br")
+	}
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Compression
+	}{
+		{"output.list", CompressionNone},
+		{"output.list.gz", CompressionGzip},
+		{"output.list.GZ", CompressionGzip},
+		{"output.list.bz2", CompressionBzip2},
+		{"output.list.xz", CompressionXz},
+		{"output.list.zst", CompressionZstd},
+	}
+
+	for _, test := range tests {
+		result := CompressionFromPath(test.input)
+		if result != test.expected {
+			t.Errorf("CompressionFromPath(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestTrimCompressionExtension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"rules.toml", "rules.toml"},
+		{"rules.toml.gz", "rules.toml"},
+		{"rules.yaml.XZ", "rules.yaml"},
+		{"rules.json.zst", "rules.json"},
+		{"rules.gz", "rules"},
+		{"rules", "rules"},
+	}
+
+	for _, test := range tests {
+		if result := TrimCompressionExtension(test.input); result != test.expected {
+			t.Errorf("TrimCompressionExtension(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
@@ -17,12 +17,38 @@ package helpers
 
 import (
 	"bufio"
+	"fmt"
 	"io"
-	"log"
 	"os"
 )
 
+// OpenFile opens a file for reading. Compressed files (gzip, bzip2, xz, zstd) are
+// detected through their magic bytes and transparently decompressed while reading.
+//
+// Args:
+//
+//	filePath: The path to the file to open.
+//
+// Returns:
+//
+//	An io.ReadCloser yielding the (decompressed) content of the file, and an error if the file cannot be opened.
+func OpenFile(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := NewDecompressingReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &chainedReadCloser{ReadCloser: reader, next: file}, nil
+}
+
 // IterFile reads a file line by line and applies the provided yield function to each line - thus allowing for processing of each line.
+// Compressed files are transparently decompressed.
 //
 // Args:
 //
@@ -31,29 +57,67 @@ import (
 //
 // Returns:
 //
-//	None. If an error occurs while opening or reading the file, it logs the error and exits the program.
-func IterFile(filePath string, yield func(string)) {
-	file, err := os.Open(filePath)
+//	An error if the file cannot be opened, read or closed, otherwise nil.
+func IterFile(filePath string, yield func(string)) error {
+	return IterFileUntil(filePath, func(line string) bool {
+		yield(line)
+		return true
+	})
+}
+
+// IterFileUntil reads a file line by line and applies the provided yield function to each line until it returns false.
+// Compressed files are transparently decompressed.
+//
+// Args:
+//
+//	filePath: The path to the file to be read.
+//	yield: A function that takes a string (the line read from the file) and returns whether the reading should continue.
+//
+// Returns:
+//
+//	An error if the file cannot be opened, read or closed, otherwise nil.
+func IterFileUntil(filePath string, yield func(string) bool) (err error) {
+	file, err := OpenFile(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer func() {
-		if err := file.Close(); err != nil {
-			log.Panic("error closing file:", err)
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", filePath, closeErr)
 		}
 	}()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		yield(scanner.Text())
+	if err := IterReaderUntil(file, yield); err != nil {
+		return fmt.Errorf("error reading %s: %w", filePath, err)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	return nil
+}
+
+// IterReaderUntil reads a stream line by line and applies the provided yield function to each line until it returns false.
+// The stream is read as it is: it has to be decompressed beforehand.
+//
+// Args:
+//
+//	reader: The stream to be read.
+//	yield: A function that takes a string (the line read from the stream) and returns whether the reading should continue.
+//
+// Returns:
+//
+//	An error if the stream cannot be read, otherwise nil.
+func IterReaderUntil(reader io.Reader, yield func(string) bool) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if !yield(scanner.Text()) {
+			return nil
+		}
 	}
+
+	return scanner.Err()
 }
 
 // WriteFileFromIter creates a file at the specified path and writes lines to it using the provided iterator function.
+// The file is compressed when its extension is one of the supported compressions (.gz, .bz2, .xz, .zst).
 //
 // Args:
 //
@@ -62,23 +126,88 @@ func IterFile(filePath string, yield func(string)) {
 //
 // Returns:
 //
-//	None. If an error occurs while creating the file, it logs the error and exits the program.
-func WriteFileFromIter(filePath string, iter func(func(string))) {
+//	An error if the file cannot be created, written or closed, otherwise nil.
+//	Once a write failed, the remaining lines are dropped.
+func WriteFileFromIter(filePath string, iter func(func(string))) (err error) {
 	file, err := os.Create(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	defer func() {
-		if err := file.Close(); err != nil {
-			log.Panicf("error closing file: %v", err)
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", filePath, closeErr)
 		}
 	}()
 
+	compressor, err := NewCompressor(file, CompressionFromPath(filePath))
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(compressor)
+
+	var writeErr error
+
 	iter(func(line string) {
-		if _, err := file.WriteString(line + "\n"); err != nil {
-			log.Panic("failed to write line to file:", err)
+		if writeErr != nil {
+			return
 		}
+
+		_, writeErr = writer.WriteString(line + "\n")
 	})
+
+	if writeErr != nil {
+		return fmt.Errorf("failed to write line to %s: %w", filePath, writeErr)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write line to %s: %w", filePath, err)
+	}
+
+	if err := compressor.Close(); err != nil {
+		return fmt.Errorf("error closing compressor of %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+// WriteFileFromReader creates a file at the specified path and writes the content of the given reader to it.
+// The content is decompressed when it is compressed.
+//
+// Args:
+//
+//	filePath: The path where the file will be created.
+//	reader: The reader to copy the content from.
+//
+// Returns:
+//
+//	An error if the write operation fails, otherwise nil.
+func WriteFileFromReader(filePath string, reader io.Reader) (err error) {
+	decompressed, err := NewDecompressingReader(reader)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := decompressed.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing reader: %w", closeErr)
+		}
+	}()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", filePath, closeErr)
+		}
+	}()
+
+	if _, err := io.Copy(file, decompressed); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // CopyFile copies the contents of a source file to a destination file.
@@ -90,14 +219,14 @@ func WriteFileFromIter(filePath string, iter func(func(string))) {
 // Returns:
 //
 //	An error if the copy operation fails, otherwise nil.
-func CopyFile(srcFile string, destFile string) error {
+func CopyFile(srcFile string, destFile string) (err error) {
 	src, err := os.Open(srcFile)
 	if err != nil {
 		return err
 	}
 	defer func() {
-		if err := src.Close(); err != nil {
-			log.Panicf("error closing source file: %v", err)
+		if closeErr := src.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", srcFile, closeErr)
 		}
 	}()
 
@@ -106,8 +235,8 @@ func CopyFile(srcFile string, destFile string) error {
 		return err
 	}
 	defer func() {
-		if err := dest.Close(); err != nil {
-			log.Panicf("error closing destination file: %v", err)
+		if closeErr := dest.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", destFile, closeErr)
 		}
 	}()
 
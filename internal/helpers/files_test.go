@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package helpers
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIterFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "rules.list.gz")
+	expected := []string{"example.com", "example.org"}
+
+	err := WriteFileFromIter(filePath, func(yield func(string)) {
+		for _, line := range expected {
+			yield(line)
+		}
+	})
+	if err != nil {
+		t.Fatalf("WriteFileFromIter() returned error: %v", err)
+	}
+
+	var lines []string
+
+	if err := IterFile(filePath, func(line string) { lines = append(lines, line) }); err != nil {
+		t.Fatalf("IterFile() returned error: %v", err)
+	}
+
+	if !slices.Equal(lines, expected) {
+		t.Errorf("IterFile() = %v, want %v", lines, expected)
+	}
+}
+
+func TestFilesErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing", "rules.list")
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"IterFile", func() error { return IterFile(missing, func(string) {}) }},
+		{"IterFileUntil", func() error { return IterFileUntil(missing, func(string) bool { return true }) }},
+		{"WriteFileFromIter", func() error { return WriteFileFromIter(missing, func(func(string)) {}) }},
+	}
+
+	for _, test := range tests {
+		if err := test.call(); err == nil {
+			t.Errorf("%s(%q) returned no error", test.name, missing)
+		}
+	}
+}
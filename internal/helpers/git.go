@@ -0,0 +1,80 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SplitGitRevision splits a file given at a git revision (e.g. "whitelist.list@v1").
+// A file which exists as is is never split, so that a file name containing an '@'
+// is still read.
+// Args:
+//   - filePath: The file, optionally suffixed by a git revision.
+//
+// Returns:
+//   - The file without its revision.
+//   - The revision.
+//   - Whether the file was given at a revision.
+func SplitGitRevision(filePath string) (string, string, bool) {
+	index := strings.LastIndex(filePath, "@")
+
+	if index <= 0 || index == len(filePath)-1 || strings.ContainsRune(filePath[index:], filepath.Separator) {
+		return filePath, "", false
+	}
+
+	if _, err := os.Stat(filePath); err == nil {
+		return filePath, "", false
+	}
+
+	return filePath[:index], filePath[index+1:], true
+}
+
+// FetchGitRevisionToFile writes the content of the given file at the given git
+// revision to a file, through "git show". The file must be in a git work tree.
+// Args:
+//   - filePath: The file to read.
+//   - revision: The git revision to read it at (e.g. "v1", "HEAD~1").
+//   - destination: The path to the file where the content will be written.
+//
+// Returns:
+//   - An error if git fails or if the write operation fails.
+func FetchGitRevisionToFile(filePath, revision, destination string) error {
+	command := exec.Command("git", "show", fmt.Sprintf("%s:./%s", revision, filepath.Base(filePath)))
+	command.Dir = filepath.Dir(filePath)
+
+	content, err := command.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("git show %s at %s: %s", filePath, revision, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return fmt.Errorf("git show %s at %s: %w", filePath, revision, err)
+	}
+
+	if err := os.WriteFile(destination, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
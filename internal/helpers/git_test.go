@@ -0,0 +1,105 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package helpers
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testGit runs git with the given arguments in the given directory.
+func testGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	command := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.org"}, args...)...)
+	command.Dir = dir
+
+	if output, err := command.CombinedOutput(); err != nil {
+		t.Fatalf("git %s returned error: %v: %s", strings.Join(args, " "), err, output)
+	}
+}
+
+func TestSplitGitRevision(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "white@list.list")
+
+	if err := os.WriteFile(existing, []byte("example.org\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filePath     string
+		wantFile     string
+		wantRevision string
+		wantOk       bool
+	}{
+		{"whitelist.list@v1", "whitelist.list", "v1", true},
+		{"lists/whitelist.list@HEAD~1", "lists/whitelist.list", "HEAD~1", true},
+		{"whitelist.list", "whitelist.list", "", false},
+		{"whitelist.list@", "whitelist.list@", "", false},
+		{"@v1", "@v1", "", false},
+		{"lists@v1/whitelist.list", "lists@v1/whitelist.list", "", false},
+		{existing, existing, "", false},
+	}
+
+	for _, test := range tests {
+		file, revision, ok := SplitGitRevision(test.filePath)
+
+		if file != test.wantFile || revision != test.wantRevision || ok != test.wantOk {
+			t.Errorf("SplitGitRevision(%q) = %q, %q, %v; want %q, %q, %v", test.filePath, file, revision, ok, test.wantFile, test.wantRevision, test.wantOk)
+		}
+	}
+}
+
+func TestFetchGitRevisionToFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "whitelist.list")
+
+	testGit(t, dir, "init", "--quiet")
+
+	if err := os.WriteFile(filePath, []byte("example.org\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testGit(t, dir, "add", "whitelist.list")
+	testGit(t, dir, "commit", "--quiet", "-m", "v1")
+	testGit(t, dir, "tag", "v1")
+
+	if err := os.WriteFile(filePath, []byte("example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	destination := filepath.Join(t.TempDir(), "whitelist.list")
+
+	if err := FetchGitRevisionToFile(filePath, "v1", destination); err != nil {
+		t.Fatalf("FetchGitRevisionToFile() returned error: %v", err)
+	}
+
+	if content, err := os.ReadFile(destination); err != nil || string(content) != "example.org\n" {
+		t.Errorf("FetchGitRevisionToFile() wrote %q (%v); want %q", content, err, "example.org\n")
+	}
+
+	if err := FetchGitRevisionToFile(filePath, "v2", destination); err == nil {
+		t.Errorf("FetchGitRevisionToFile() at an unknown revision returned no error")
+	}
+}
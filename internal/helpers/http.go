@@ -25,6 +25,8 @@ import (
 )
 
 // FetchURL fetches the content of the given URL and returns it as a string.
+// The content is decoded according to the Content-Encoding header and decompressed
+// when it is a compressed file.
 // Args:
 //   - rawUrl: The URL to fetch.
 //
@@ -46,7 +48,17 @@ func FetchURL(rawUrl string) (string, error) {
 		return "", fmt.Errorf("non-200 response: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	reader, err := newResponseReader(resp)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			log.Panicf("error closing response reader: %v", err)
+		}
+	}()
+
+	body, err := io.ReadAll(reader)
 	if err != nil {
 		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
@@ -54,47 +66,95 @@ func FetchURL(rawUrl string) (string, error) {
 	return string(body), nil
 }
 
+// OpenURL fetches the given URL and returns a stream of its content, read as
+// it is downloaded. The content is decoded according to the Content-Encoding
+// header and decompressed when it is a compressed file.
+// Args:
+//   - rawUrl: The URL to fetch.
+//
+// Returns:
+//   - A stream of the plain text content, to be closed by the caller.
+//   - An error if the URL cannot be fetched.
+func OpenURL(rawUrl string) (io.ReadCloser, error) {
+	resp, err := http.Get(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+
+	reader, err := newResponseReader(resp)
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+
+	return &chainedReadCloser{ReadCloser: reader, next: resp.Body}, nil
+}
+
 // FetchURLToFile fetches the content of the given URL and writes it to a file.
+// The content is decoded according to the Content-Encoding header and decompressed
+// when it is a compressed file, so that the written file is always plain text.
 // Args:
 //   - rawUrl: The URL to fetch.
 //   - filePath: The path to the file where the content will be written.
 //
 // Returns:
 //   - An error if the fetch or write operation fails.
-func FetchURLToFile(rawUrl, filePath string) error {
-	resp, err := http.Get(rawUrl)
+func FetchURLToFile(rawUrl, filePath string) (err error) {
+	reader, err := OpenURL(rawUrl)
 	if err != nil {
-		return fmt.Errorf("failed to fetch URL: %w", err)
+		return err
 	}
 	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Panicf("error closing response body: %v", err)
+		if closeErr := reader.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing response reader: %w", closeErr)
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("non-200 response: %d", resp.StatusCode)
-	}
-
 	file, err := os.Create(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-
 	defer func() {
-		if err := file.Close(); err != nil {
-			log.Panicf("error closing stream: %v", err)
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", filePath, closeErr)
 		}
 	}()
 
-	_, err = io.Copy(file, resp.Body)
-	if err != nil {
+	if _, err := io.Copy(file, reader); err != nil {
 		return fmt.Errorf("failed to write response body to file: %w", err)
 	}
 
 	return nil
 }
 
+// newResponseReader returns a reader yielding the decoded and decompressed body of the given response.
+func newResponseReader(resp *http.Response) (io.ReadCloser, error) {
+	// The HTTP client already decoded the body if it negotiated the encoding itself.
+	encoding := resp.Header.Get("Content-Encoding")
+
+	if resp.Uncompressed {
+		encoding = ""
+	}
+
+	decoded, err := NewContentDecoder(resp.Body, encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressed, err := NewDecompressingReader(decoded)
+	if err != nil {
+		_ = decoded.Close()
+		return nil, err
+	}
+
+	return &chainedReadCloser{ReadCloser: decompressed, next: decoded}, nil
+}
+
 // IsUrl checks if the given string is a valid URL.
 // Args:
 //   - str: The string to check.
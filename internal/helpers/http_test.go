@@ -0,0 +1,65 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenURL(t *testing.T) {
+	content := "example.com\nexample.org\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hosts.txt.zst":
+			_, _ = w.Write(testCompress(t, content, CompressionZstd))
+		case "/hosts.txt":
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(testCompress(t, content, CompressionGzip))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/hosts.txt.zst", "/hosts.txt"} {
+		reader, err := OpenURL(server.URL + path)
+		if err != nil {
+			t.Errorf("OpenURL(%q) returned error: %v", path, err)
+			continue
+		}
+
+		result, err := io.ReadAll(reader)
+		if err != nil {
+			t.Errorf("ReadAll(%q) returned error: %v", path, err)
+		}
+
+		if err := reader.Close(); err != nil {
+			t.Errorf("Close(%q) returned error: %v", path, err)
+		}
+
+		if string(result) != content {
+			t.Errorf("OpenURL(%q) = %q; want %q", path, result, content)
+		}
+	}
+
+	if _, err := OpenURL(server.URL + "/missing"); err == nil {
+		t.Errorf("OpenURL(%q) returned no error", "/missing")
+	}
+}
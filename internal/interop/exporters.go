@@ -0,0 +1,383 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package interop
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/funilrys/givilsta/internal/formats"
+	"github.com/funilrys/givilsta/internal/ruler"
+)
+
+// dnsmasqDomainsPerLine is the number of domains packed into a single dnsmasq option.
+const dnsmasqDomainsPerLine = 50
+
+// SupportedExportFormats returns the list of supported formats to export to.
+func SupportedExportFormats() []ExportFormat {
+	return []ExportFormat{ExportAdGuard, ExportPiholeRegex, ExportDnsmasq, ExportUnbound, ExportRPZPassthru}
+}
+
+// NewExporter creates a new exporter for the given format.
+//
+// Args:
+//
+//	format: The format to export to.
+//	options: The options of the exporter.
+//
+// Returns:
+//
+//	Exporter: The exporter to use for the given format.
+//	error: An error if the format is not supported.
+func NewExporter(format ExportFormat, options ExportOptions) (Exporter, error) {
+	if options.Extensions == nil {
+		options.Extensions = func() []string { return nil }
+	}
+
+	options.ComplementPrefixes = ruler.NormalizeComplementPrefixes(options.ComplementPrefixes)
+
+	base := exporter{options: options}
+
+	switch ExportFormat(strings.ToLower(string(format))) {
+	case ExportAdGuard:
+		return &adguardExporter{base}, nil
+	case ExportPiholeRegex:
+		return &piholeRegexExporter{base}, nil
+	case ExportDnsmasq:
+		return &dnsmasqExporter{base}, nil
+	case ExportUnbound:
+		return &unboundExporter{base}, nil
+	case ExportRPZPassthru:
+		return &rpzPassthruExporter{base}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported export format: %s", format)
+}
+
+// exporter holds the logic shared by all exporters.
+type exporter struct {
+	options ExportOptions
+}
+
+// isURL checks if the given rule value is an URL.
+func isURL(value string) bool {
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
+}
+
+// complementPrefixes returns the complement prefixes handled by the given rule.
+func (e *exporter) complementPrefixes(rule ruler.Rule) []string {
+	switch rule.Modifiers.Complement {
+	case ruler.ComplementOff:
+		return nil
+	case ruler.ComplementOn:
+		if len(e.options.ComplementPrefixes) == 0 {
+			return ruler.DefaultComplementPrefixes
+		}
+	}
+
+	return e.options.ComplementPrefixes
+}
+
+// depthRegex returns a regular expression matching the domains covered by an ALL rule limited in depth.
+func (e *exporter) depthRegex(rule ruler.Rule) string {
+	return fmt.Sprintf(`^([^.]+\.){0,%d}%s$`, rule.Modifiers.Depth, regexp.QuoteMeta(e.domain(rule)))
+}
+
+// depthApproximated reports that the depth limit of an ALL rule can't be expressed in the given tool.
+func depthApproximated(rule ruler.Rule, tool string) *Issue {
+	if rule.Modifiers.Depth == 0 {
+		return nil
+	}
+
+	return approximated(rule.String(), "the depth limit can't be expressed in %s, all the subdomains are whitelisted", tool)
+}
+
+// domain returns the domain covered by a PLAIN, ALL or ROOT rule.
+func (e *exporter) domain(rule ruler.Rule) string {
+	return strings.TrimPrefix(rule.Value, ".")
+}
+
+// plainDomains returns the domains whitelisted by a PLAIN rule, complement included.
+func (e *exporter) plainDomains(rule ruler.Rule) []string {
+	return append([]string{rule.Value}, ruler.Complements(rule.Value, e.complementPrefixes(rule))...)
+}
+
+// urlPrefixes returns the prefixes covered by an URL rule, complement included.
+func (e *exporter) urlPrefixes(rule ruler.Rule) []string {
+	return append([]string{rule.Value}, ruler.ComplementURLs(rule.Value, e.complementPrefixes(rule))...)
+}
+
+// rzdbRecord returns the record of a RZDB rule, as the ruler expands it.
+func (e *exporter) rzdbRecord(rule ruler.Rule) string {
+	return ruler.StripComplementPrefix(rule.Value, e.complementPrefixes(rule))
+}
+
+// rzdbDomains returns the domains a RZDB rule is expanded to.
+func (e *exporter) rzdbDomains(rule ruler.Rule) []string {
+	record := e.rzdbRecord(rule)
+
+	var domains []string
+
+	for _, extension := range e.options.Extensions() {
+		domains = append(domains, fmt.Sprintf("%s.%s", record, extension))
+
+		for _, prefix := range e.complementPrefixes(rule) {
+			domains = append(domains, fmt.Sprintf("%s%s.%s", prefix, record, extension))
+		}
+	}
+
+	return domains
+}
+
+// rzdbRegex returns a single regular expression matching all the domains a RZDB rule is expanded to.
+func (e *exporter) rzdbRegex(rule ruler.Rule) string {
+	extensions := slices.Clone(e.options.Extensions())
+	slices.Sort(extensions)
+	extensions = slices.Compact(extensions)
+
+	for index, extension := range extensions {
+		extensions[index] = regexp.QuoteMeta(extension)
+	}
+
+	prefix := "^"
+
+	if prefixes := e.complementPrefixes(rule); len(prefixes) > 0 {
+		var alternatives []string
+
+		for _, complementPrefix := range prefixes {
+			alternatives = append(alternatives, regexp.QuoteMeta(complementPrefix))
+		}
+
+		prefix = fmt.Sprintf("^(%s)?", strings.Join(alternatives, "|"))
+	}
+
+	return fmt.Sprintf(`%s%s\.(%s)$`, prefix, regexp.QuoteMeta(e.rzdbRecord(rule)), strings.Join(extensions, "|"))
+}
+
+func (e *exporter) Header() []string {
+	return nil
+}
+
+type adguardExporter struct {
+	exporter
+}
+
+func (e *adguardExporter) Format() ExportFormat {
+	return ExportAdGuard
+}
+
+func (e *adguardExporter) Export(rule ruler.Rule) ([]string, *Issue) {
+	var result []string
+
+	switch rule.Kind {
+	case ruler.RuleKindPlain:
+		if isURL(rule.Value) {
+			return []string{fmt.Sprintf("@@|%s|", rule.Value)}, nil
+		}
+
+		for _, domain := range e.plainDomains(rule) {
+			result = append(result, fmt.Sprintf("@@|%s^", domain))
+		}
+	case ruler.RuleKindAll, ruler.RuleKindRoot:
+		if rule.Modifiers.Depth > 0 {
+			return []string{fmt.Sprintf("@@/%s/", e.depthRegex(rule))}, nil
+		}
+
+		result = append(result, fmt.Sprintf("@@||%s^", e.domain(rule)))
+	case ruler.RuleKindReg:
+		result = append(result, fmt.Sprintf("@@/%s/", strings.ReplaceAll(rule.Value, "/", `\/`)))
+	case ruler.RuleKindRzdb:
+		result = append(result, fmt.Sprintf("@@/%s/", e.rzdbRegex(rule)))
+	case ruler.RuleKindURL:
+		for _, prefix := range e.urlPrefixes(rule) {
+			result = append(result, fmt.Sprintf("@@|%s", prefix))
+		}
+	}
+
+	return result, nil
+}
+
+type piholeRegexExporter struct {
+	exporter
+}
+
+func (e *piholeRegexExporter) Format() ExportFormat {
+	return ExportPiholeRegex
+}
+
+func (e *piholeRegexExporter) Export(rule ruler.Rule) ([]string, *Issue) {
+	switch rule.Kind {
+	case ruler.RuleKindPlain:
+		if isURL(rule.Value) {
+			return nil, skipped(rule.String(), "URL rules can't be expressed in Pi-hole")
+		}
+
+		var alternatives []string
+
+		for _, domain := range e.plainDomains(rule) {
+			alternatives = append(alternatives, regexp.QuoteMeta(domain))
+		}
+
+		if len(alternatives) == 1 {
+			return []string{fmt.Sprintf("^%s$", alternatives[0])}, nil
+		}
+
+		return []string{fmt.Sprintf("^(%s)$", strings.Join(alternatives, "|"))}, nil
+	case ruler.RuleKindAll, ruler.RuleKindRoot:
+		if rule.Modifiers.Depth > 0 {
+			return []string{e.depthRegex(rule)}, nil
+		}
+
+		return []string{fmt.Sprintf(`(^|\.)%s$`, regexp.QuoteMeta(e.domain(rule)))}, nil
+	case ruler.RuleKindReg:
+		return []string{rule.Value}, nil
+	case ruler.RuleKindRzdb:
+		return []string{e.rzdbRegex(rule)}, nil
+	case ruler.RuleKindURL:
+		return nil, skipped(rule.String(), "URL rules can't be expressed in Pi-hole")
+	}
+
+	return nil, nil
+}
+
+type dnsmasqExporter struct {
+	exporter
+}
+
+func (e *dnsmasqExporter) Format() ExportFormat {
+	return ExportDnsmasq
+}
+
+func (e *dnsmasqExporter) lines(domains []string) []string {
+	var result []string
+
+	for chunk := range slices.Chunk(domains, dnsmasqDomainsPerLine) {
+		result = append(result, fmt.Sprintf("server=/%s/#", strings.Join(chunk, "/")))
+	}
+
+	return result
+}
+
+func (e *dnsmasqExporter) Export(rule ruler.Rule) ([]string, *Issue) {
+	switch rule.Kind {
+	case ruler.RuleKindPlain:
+		if isURL(rule.Value) {
+			return nil, skipped(rule.String(), "URL rules can't be expressed in dnsmasq")
+		}
+
+		return e.lines([]string{rule.Value}), approximated(rule.String(), "dnsmasq also whitelists the subdomains")
+	case ruler.RuleKindAll, ruler.RuleKindRoot:
+		return e.lines([]string{e.domain(rule)}), depthApproximated(rule, "dnsmasq")
+	case ruler.RuleKindReg:
+		return nil, skipped(rule.String(), "regular expressions can't be expressed in dnsmasq")
+	case ruler.RuleKindRzdb:
+		return e.lines(e.rzdbDomains(rule)), nil
+	case ruler.RuleKindURL:
+		return nil, skipped(rule.String(), "URL rules can't be expressed in dnsmasq")
+	}
+
+	return nil, nil
+}
+
+type unboundExporter struct {
+	exporter
+}
+
+func (e *unboundExporter) Format() ExportFormat {
+	return ExportUnbound
+}
+
+func (e *unboundExporter) Header() []string {
+	return []string{"server:"}
+}
+
+func (e *unboundExporter) lines(domains []string) []string {
+	var result []string
+
+	for _, domain := range domains {
+		result = append(result, fmt.Sprintf(`local-zone: "%s" transparent`, domain))
+	}
+
+	return result
+}
+
+func (e *unboundExporter) Export(rule ruler.Rule) ([]string, *Issue) {
+	switch rule.Kind {
+	case ruler.RuleKindPlain:
+		if isURL(rule.Value) {
+			return nil, skipped(rule.String(), "URL rules can't be expressed in Unbound")
+		}
+
+		return e.lines(e.plainDomains(rule)), approximated(rule.String(), "Unbound also whitelists the subdomains")
+	case ruler.RuleKindAll, ruler.RuleKindRoot:
+		return e.lines([]string{e.domain(rule)}), depthApproximated(rule, "Unbound")
+	case ruler.RuleKindReg:
+		return nil, skipped(rule.String(), "regular expressions can't be expressed in Unbound")
+	case ruler.RuleKindRzdb:
+		return e.lines(e.rzdbDomains(rule)), nil
+	case ruler.RuleKindURL:
+		return nil, skipped(rule.String(), "URL rules can't be expressed in Unbound")
+	}
+
+	return nil, nil
+}
+
+type rpzPassthruExporter struct {
+	exporter
+}
+
+func (e *rpzPassthruExporter) Format() ExportFormat {
+	return ExportRPZPassthru
+}
+
+func (e *rpzPassthruExporter) Header() []string {
+	return formats.RPZHeader(e.options.Serial)
+}
+
+func (e *rpzPassthruExporter) lines(domains []string) []string {
+	var result []string
+
+	for _, domain := range domains {
+		result = append(result, fmt.Sprintf("%s CNAME rpz-passthru.", domain))
+	}
+
+	return result
+}
+
+func (e *rpzPassthruExporter) Export(rule ruler.Rule) ([]string, *Issue) {
+	switch rule.Kind {
+	case ruler.RuleKindPlain:
+		if isURL(rule.Value) {
+			return nil, skipped(rule.String(), "URL rules can't be expressed in RPZ")
+		}
+
+		return e.lines(e.plainDomains(rule)), nil
+	case ruler.RuleKindAll, ruler.RuleKindRoot:
+		domain := e.domain(rule)
+
+		return e.lines([]string{domain, fmt.Sprintf("*.%s", domain)}), depthApproximated(rule, "RPZ")
+	case ruler.RuleKindReg:
+		return nil, skipped(rule.String(), "regular expressions can't be expressed in RPZ")
+	case ruler.RuleKindRzdb:
+		return e.lines(e.rzdbDomains(rule)), nil
+	case ruler.RuleKindURL:
+		return nil, skipped(rule.String(), "URL rules can't be expressed in RPZ")
+	}
+
+	return nil, nil
+}
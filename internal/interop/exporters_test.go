@@ -0,0 +1,124 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package interop
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/ruler"
+)
+
+func testExtensions() []string {
+	return []string{"org", "com", "co.uk"}
+}
+
+func TestExport(t *testing.T) {
+	plain := ruler.Rule{Kind: ruler.RuleKindPlain, Value: "example.com"}
+	url := ruler.Rule{Kind: ruler.RuleKindPlain, Value: "https://example.com/foo"}
+	all := ruler.Rule{Kind: ruler.RuleKindAll, Value: ".example.org"}
+	reg := ruler.Rule{Kind: ruler.RuleKindReg, Value: "^ads?/example$"}
+	rzdb := ruler.Rule{Kind: ruler.RuleKindRzdb, Value: "example"}
+	prefix := ruler.Rule{Kind: ruler.RuleKindURL, Value: "https://docs.example.com/shared"}
+	shallow := ruler.Rule{Kind: ruler.RuleKindAll, Value: ".example.org", Modifiers: ruler.Modifiers{Depth: 1}}
+	exact := ruler.Rule{Kind: ruler.RuleKindPlain, Value: "example.com", Modifiers: ruler.Modifiers{Complement: ruler.ComplementOff}}
+	forced := ruler.Rule{Kind: ruler.RuleKindPlain, Value: "example.com", Modifiers: ruler.Modifiers{Complement: ruler.ComplementOn}}
+	root := ruler.Rule{Kind: ruler.RuleKindRoot, Value: "example.co.uk"}
+	www := []string{"www."}
+	mobile := []string{"www", "M."}
+
+	tests := []struct {
+		format             ExportFormat
+		complementPrefixes []string
+		rule               ruler.Rule
+		expected           []string
+		issue              bool
+	}{
+		{ExportAdGuard, nil, plain, []string{"@@|example.com^"}, false},
+		{ExportAdGuard, www, plain, []string{"@@|example.com^", "@@|www.example.com^"}, false},
+		{ExportAdGuard, nil, url, []string{"@@|https://example.com/foo|"}, false},
+		{ExportAdGuard, nil, all, []string{"@@||example.org^"}, false},
+		{ExportAdGuard, nil, reg, []string{`@@/^ads?\/example$/`}, false},
+		{ExportAdGuard, nil, rzdb, []string{`@@/^example\.(co\.uk|com|org)$/`}, false},
+		{ExportAdGuard, www, prefix, []string{"@@|https://docs.example.com/shared", "@@|https://www.docs.example.com/shared"}, false},
+		{ExportAdGuard, nil, shallow, []string{`@@/^([^.]+\.){0,1}example\.org$/`}, false},
+		{ExportAdGuard, www, exact, []string{"@@|example.com^"}, false},
+		{ExportAdGuard, nil, forced, []string{"@@|example.com^", "@@|www.example.com^"}, false},
+		{ExportAdGuard, nil, root, []string{"@@||example.co.uk^"}, false},
+		{ExportPiholeRegex, nil, plain, []string{`^example\.com$`}, false},
+		{ExportPiholeRegex, nil, shallow, []string{`^([^.]+\.){0,1}example\.org$`}, false},
+		{ExportPiholeRegex, www, plain, []string{`^(example\.com|www\.example\.com)$`}, false},
+		{ExportPiholeRegex, mobile, plain, []string{`^(example\.com|www\.example\.com|m\.example\.com)$`}, false},
+		{ExportPiholeRegex, mobile, rzdb, []string{`^(www\.|m\.)?example\.(co\.uk|com|org)$`}, false},
+		{ExportPiholeRegex, nil, url, nil, true},
+		{ExportPiholeRegex, nil, all, []string{`(^|\.)example\.org$`}, false},
+		{ExportPiholeRegex, nil, reg, []string{"^ads?/example$"}, false},
+		{ExportPiholeRegex, www, rzdb, []string{`^(www\.)?example\.(co\.uk|com|org)$`}, false},
+		{ExportDnsmasq, nil, plain, []string{"server=/example.com/#"}, true},
+		{ExportDnsmasq, nil, all, []string{"server=/example.org/#"}, false},
+		{ExportDnsmasq, nil, reg, nil, true},
+		{ExportDnsmasq, nil, shallow, []string{"server=/example.org/#"}, true},
+		{ExportDnsmasq, nil, rzdb, []string{"server=/example.org/example.com/example.co.uk/#"}, false},
+		{ExportUnbound, nil, all, []string{`local-zone: "example.org" transparent`}, false},
+		{ExportUnbound, nil, reg, nil, true},
+		{ExportUnbound, nil, root, []string{`local-zone: "example.co.uk" transparent`}, false},
+		{ExportUnbound, nil, prefix, nil, true},
+		{ExportRPZPassthru, www, plain, []string{"example.com CNAME rpz-passthru.", "www.example.com CNAME rpz-passthru."}, false},
+		{ExportRPZPassthru, mobile, exact, []string{"example.com CNAME rpz-passthru."}, false},
+		{ExportRPZPassthru, nil, shallow, []string{"example.org CNAME rpz-passthru.", "*.example.org CNAME rpz-passthru."}, true},
+		{ExportRPZPassthru, nil, all, []string{"example.org CNAME rpz-passthru.", "*.example.org CNAME rpz-passthru."}, false},
+		{ExportRPZPassthru, nil, rzdb, []string{"example.org CNAME rpz-passthru.", "example.com CNAME rpz-passthru.", "example.co.uk CNAME rpz-passthru."}, false},
+	}
+
+	for _, test := range tests {
+		exporter, err := NewExporter(test.format, ExportOptions{ComplementPrefixes: test.complementPrefixes, Extensions: testExtensions})
+		if err != nil {
+			t.Fatalf("NewExporter(%q) returned error: %v", test.format, err)
+		}
+
+		result, issue := exporter.Export(test.rule)
+
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("Export(%q, %q) = %q; want %q", test.format, test.rule, result, test.expected)
+		}
+
+		if (issue != nil) != test.issue {
+			t.Errorf("Export(%q, %q) issue = %+v; want issue: %v", test.format, test.rule, issue, test.issue)
+		}
+	}
+}
+
+func TestExportDnsmasqPacking(t *testing.T) {
+	var extensions []string
+
+	for range dnsmasqDomainsPerLine + 1 {
+		extensions = append(extensions, "com")
+	}
+
+	exporter, _ := NewExporter(ExportDnsmasq, ExportOptions{Extensions: func() []string { return extensions }})
+
+	result, _ := exporter.Export(ruler.Rule{Kind: ruler.RuleKindRzdb, Value: "example"})
+
+	if len(result) != 2 {
+		t.Errorf("Export() returned %d lines; want 2", len(result))
+	}
+}
+
+func TestNewExporterUnsupported(t *testing.T) {
+	if _, err := NewExporter("unknown", ExportOptions{}); err == nil {
+		t.Errorf("NewExporter(%q) returned no error", "unknown")
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package interop
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/funilrys/givilsta/internal/helpers"
+)
+
+// SupportedImportFormats returns the list of supported formats to import from.
+func SupportedImportFormats() []ImportFormat {
+	return []ImportFormat{ImportAdGuard, ImportPiholeRegex, ImportPiholeExact, ImportHosts}
+}
+
+// NewImporter creates a new importer for the given format.
+//
+// Args:
+//
+//	format: The format to import from.
+//
+// Returns:
+//
+//	Importer: The importer to use for the given format.
+//	error: An error if the format is not supported.
+func NewImporter(format ImportFormat) (Importer, error) {
+	switch ImportFormat(strings.ToLower(string(format))) {
+	case ImportAdGuard:
+		return &adguardImporter{}, nil
+	case ImportPiholeRegex:
+		return &piholeRegexImporter{}, nil
+	case ImportPiholeExact:
+		return &piholeExactImporter{}, nil
+	case ImportHosts:
+		return &hostsImporter{}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported import format: %s", format)
+}
+
+// ImportFile translates the lines of the given allowlist file.
+//
+// Args:
+//
+//	importer: The importer to translate the lines with.
+//	filePath: The path to the file to translate.
+//	yield: The function receiving the translated rules.
+//	report: The function receiving the issues, with their line number.
+//
+// Returns:
+//
+//	error: An error if the file cannot be read.
+func ImportFile(importer Importer, filePath string, yield func(rule string), report func(issue *Issue)) (err error) {
+	file, err := helpers.OpenFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("error closing %s: %w", filePath, closeErr)
+		}
+	}()
+
+	return ImportReader(importer, file, yield, report)
+}
+
+// ImportReader translates the lines of the given allowlist stream.
+//
+// Args:
+//
+//	importer: The importer to translate the lines with.
+//	reader: The (decompressed) stream to translate.
+//	yield: The function receiving the translated rules.
+//	report: The function receiving the issues, with their line number.
+//
+// Returns:
+//
+//	error: An error if the stream cannot be read.
+func ImportReader(importer Importer, reader io.Reader, yield func(rule string), report func(issue *Issue)) error {
+	lineNumber := 0
+
+	return helpers.IterReaderUntil(reader, func(line string) bool {
+		lineNumber++
+
+		rules, issue := importer.Import(line)
+
+		for _, rule := range rules {
+			yield(rule)
+		}
+
+		if issue != nil {
+			issue.Line = lineNumber
+			report(issue)
+		}
+
+		return true
+	})
+}
+
+// regexRules translates the given (valid) regular expression of the given
+// input into a REG rule. A regular expression anchored at neither end is
+// reported as approximated.
+func regexRules(input string, expression string) ([]string, *Issue) {
+	rules := []string{fmt.Sprintf("REG %s", expression)}
+
+	if !strings.HasPrefix(expression, "^") && !strings.HasSuffix(expression, "$") {
+		return rules, approximated(input, "non-anchored regular expression, it also matches any URL containing it")
+	}
+
+	return rules, nil
+}
+
+// isComment checks if the given (trimmed) line is empty or a comment.
+func isComment(line string, markers ...string) bool {
+	if line == "" {
+		return true
+	}
+
+	for _, marker := range markers {
+		if strings.HasPrefix(line, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipped creates an issue for a construct which was not translated at all.
+func skipped(input string, format string, args ...any) *Issue {
+	return &Issue{Input: input, Message: fmt.Sprintf(format, args...), Skipped: true}
+}
+
+// approximated creates an issue for a construct which was translated with a different meaning.
+func approximated(input string, format string, args ...any) *Issue {
+	return &Issue{Input: input, Message: fmt.Sprintf(format, args...)}
+}
+
+// adguardHarmlessModifiers are the modifiers which do not change what an
+// exception rule whitelists.
+var adguardHarmlessModifiers = []string{"important", "all", "document", "doc", "match-case"}
+
+// adguardCosmeticMarkers identify the cosmetic (element hiding, scriptlet, ...) rules.
+var adguardCosmeticMarkers = []string{"##", "#@#", "#?#", "#$#", "#%#"}
+
+type adguardImporter struct{}
+
+func (i *adguardImporter) Format() ImportFormat {
+	return ImportAdGuard
+}
+
+func (i *adguardImporter) Import(line string) ([]string, *Issue) {
+	trimmed := strings.TrimSpace(line)
+
+	if isComment(trimmed, "!", "[") || (strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "##")) {
+		return nil, nil
+	}
+
+	for _, marker := range adguardCosmeticMarkers {
+		if strings.Contains(trimmed, marker) {
+			return nil, skipped(trimmed, "cosmetic rules can't be translated")
+		}
+	}
+
+	rule, found := strings.CutPrefix(trimmed, "@@")
+
+	if !found {
+		return nil, skipped(trimmed, "not an exception (@@) rule")
+	}
+
+	var pattern, modifiers string
+
+	if strings.HasPrefix(rule, "/") && strings.LastIndex(rule, "/") > 0 {
+		end := strings.LastIndex(rule, "/")
+		pattern, modifiers = rule[:end+1], strings.TrimPrefix(rule[end+1:], "$")
+	} else {
+		pattern, modifiers, _ = strings.Cut(rule, "$")
+	}
+
+	if modifiers != "" {
+		for _, modifier := range strings.Split(modifiers, ",") {
+			name, _, _ := strings.Cut(strings.TrimPrefix(modifier, "~"), "=")
+
+			if !slices.Contains(adguardHarmlessModifiers, name) {
+				return nil, skipped(trimmed, "unsupported modifier $%s", name)
+			}
+		}
+	}
+
+	if strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") && len(pattern) > 2 {
+		expression := pattern[1 : len(pattern)-1]
+
+		if _, err := regexp.Compile(expression); err != nil {
+			return nil, skipped(trimmed, "invalid regular expression: %v", err)
+		}
+
+		return regexRules(trimmed, expression)
+	}
+
+	domain, found := strings.CutPrefix(pattern, "||")
+
+	if !found {
+		if strings.HasPrefix(pattern, "|") {
+			return nil, skipped(trimmed, "address anchored (|) rules can't be translated")
+		}
+
+		return nil, skipped(trimmed, "non-anchored pattern can't be translated")
+	}
+
+	terminated := strings.HasSuffix(domain, "^") || strings.HasSuffix(domain, "^|")
+	domain = strings.TrimSuffix(strings.TrimSuffix(domain, "|"), "^")
+
+	if domain == "" || strings.ContainsAny(domain, "/^|") {
+		return nil, skipped(trimmed, "path or address based rules can't be translated")
+	}
+
+	if strings.Contains(domain, "*") {
+		expression := strings.ReplaceAll(regexp.QuoteMeta(domain), `\*`, `.*`)
+
+		if strings.HasPrefix(domain, "*.") {
+			// The leading wildcard requires at least one subdomain.
+			expression = fmt.Sprintf("^.+%s", strings.TrimPrefix(expression, ".*"))
+		} else {
+			expression = fmt.Sprintf(`(^|\.)%s`, expression)
+		}
+
+		if terminated {
+			expression += "$"
+		}
+
+		return []string{fmt.Sprintf("REG %s", expression)}, nil
+	}
+
+	if !terminated {
+		return []string{fmt.Sprintf("ALL %s", domain)}, approximated(trimmed, "not terminated by ^, translated as if it were")
+	}
+
+	return []string{fmt.Sprintf("ALL %s", domain)}, nil
+}
+
+type piholeRegexImporter struct{}
+
+func (i *piholeRegexImporter) Format() ImportFormat {
+	return ImportPiholeRegex
+}
+
+func (i *piholeRegexImporter) Import(line string) ([]string, *Issue) {
+	trimmed := strings.TrimSpace(line)
+
+	if isComment(trimmed, "#") {
+		return nil, nil
+	}
+
+	if expression, extension, found := strings.Cut(trimmed, ";"); found {
+		return nil, skipped(trimmed, "unsupported Pi-hole extension ;%s for %s", extension, expression)
+	}
+
+	if _, err := regexp.Compile(trimmed); err != nil {
+		return nil, skipped(trimmed, "invalid regular expression: %v", err)
+	}
+
+	return regexRules(trimmed, trimmed)
+}
+
+type piholeExactImporter struct{}
+
+func (i *piholeExactImporter) Format() ImportFormat {
+	return ImportPiholeExact
+}
+
+func (i *piholeExactImporter) Import(line string) ([]string, *Issue) {
+	trimmed := strings.TrimSpace(line)
+
+	if isComment(trimmed, "#") {
+		return nil, nil
+	}
+
+	fields := strings.Fields(stripComment(trimmed))
+
+	if len(fields) != 1 {
+		return nil, skipped(trimmed, "expected a single domain")
+	}
+
+	return fields, nil
+}
+
+type hostsImporter struct{}
+
+func (i *hostsImporter) Format() ImportFormat {
+	return ImportHosts
+}
+
+func (i *hostsImporter) Import(line string) ([]string, *Issue) {
+	trimmed := strings.TrimSpace(line)
+
+	if isComment(trimmed, "#") {
+		return nil, nil
+	}
+
+	fields := strings.Fields(stripComment(trimmed))
+
+	if len(fields) == 1 && net.ParseIP(fields[0]) != nil {
+		return nil, skipped(trimmed, "expected hostnames after the IP")
+	}
+
+	if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+		fields = fields[1:]
+	} else if len(fields) > 1 {
+		return nil, skipped(trimmed, "expected an IP followed by hostnames")
+	}
+
+	return fields, nil
+}
+
+// stripComment removes the inline comment of the given line.
+func stripComment(line string) string {
+	if index := strings.Index(line, "#"); index >= 0 {
+		return line[:index]
+	}
+
+	return line
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package interop
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		format   ImportFormat
+		input    string
+		expected []string
+		issue    bool
+		skipped  bool
+	}{
+		{ImportAdGuard, "! comment", nil, false, false},
+		{ImportAdGuard, "[Adblock Plus 2.0]", nil, false, false},
+		{ImportAdGuard, "@@||example.com^", []string{"ALL example.com"}, false, false},
+		{ImportAdGuard, "@@||example.com^$important", []string{"ALL example.com"}, false, false},
+		{ImportAdGuard, "@@||example.com", []string{"ALL example.com"}, true, false},
+		{ImportAdGuard, "@@||*.example.com^", []string{`REG ^.+\.example\.com$`}, false, false},
+		{ImportAdGuard, "@@||ex*ample.com^", []string{`REG (^|\.)ex.*ample\.com$`}, false, false},
+		{ImportAdGuard, `@@/^ads?\.example\.com$/`, []string{`REG ^ads?\.example\.com$`}, false, false},
+		{ImportAdGuard, `@@/ads?\.example\.com/`, []string{`REG ads?\.example\.com`}, true, false},
+		{ImportAdGuard, `@@/tracker\./$important`, []string{`REG tracker\.`}, true, false},
+		{ImportAdGuard, "@@||example.com^$domain=example.org", nil, true, true},
+		{ImportAdGuard, "@@||example.com/path^", nil, true, true},
+		{ImportAdGuard, "@@example.com", nil, true, true},
+		{ImportAdGuard, "@@|https://example.com/|", nil, true, true},
+		{ImportAdGuard, "||example.com^", nil, true, true},
+		{ImportAdGuard, "example.com#@#.ad", nil, true, true},
+		{ImportPiholeRegex, "# comment", nil, false, false},
+		{ImportPiholeRegex, `^ads?\.example\.com$`, []string{`REG ^ads?\.example\.com$`}, false, false},
+		{ImportPiholeRegex, `example\.com`, []string{`REG example\.com`}, true, false},
+		{ImportPiholeRegex, `^example\.com$;querytype=A`, nil, true, true},
+		{ImportPiholeRegex, `^(ex)\1$`, nil, true, true},
+		{ImportPiholeExact, "example.com", []string{"example.com"}, false, false},
+		{ImportPiholeExact, "example.com # comment", []string{"example.com"}, false, false},
+		{ImportPiholeExact, "example.com example.org", nil, true, true},
+		{ImportHosts, "0.0.0.0 example.com example.org", []string{"example.com", "example.org"}, false, false},
+		{ImportHosts, "example.com", []string{"example.com"}, false, false},
+		{ImportHosts, "0.0.0.0", nil, true, true},
+		{ImportHosts, ":: # comment", nil, true, true},
+	}
+
+	for _, test := range tests {
+		importer, err := NewImporter(test.format)
+		if err != nil {
+			t.Fatalf("NewImporter(%q) returned error: %v", test.format, err)
+		}
+
+		result, issue := importer.Import(test.input)
+
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("Import(%q, %q) = %q; want %q", test.format, test.input, result, test.expected)
+		}
+
+		if (issue != nil) != test.issue {
+			t.Errorf("Import(%q, %q) issue = %+v; want issue: %v", test.format, test.input, issue, test.issue)
+		} else if issue != nil && issue.Skipped != test.skipped {
+			t.Errorf("Import(%q, %q) issue.Skipped = %v; want %v", test.format, test.input, issue.Skipped, test.skipped)
+		}
+	}
+}
+
+func TestImportFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "hosts")
+
+	if err := os.WriteFile(filePath, []byte("# comment\n0.0.0.0 example.com\n0.0.0.0\nexample.org example.net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	importer, _ := NewImporter(ImportHosts)
+
+	var rules []string
+	var lines []int
+
+	err := ImportFile(importer, filePath, func(rule string) {
+		rules = append(rules, rule)
+	}, func(issue *Issue) {
+		lines = append(lines, issue.Line)
+	})
+
+	if err != nil {
+		t.Fatalf("ImportFile() returned error: %v", err)
+	}
+
+	if expected := []string{"example.com"}; !slices.Equal(rules, expected) {
+		t.Errorf("ImportFile() rules = %q; want %q", rules, expected)
+	}
+
+	if expected := []int{3, 4}; !slices.Equal(lines, expected) {
+		t.Errorf("ImportFile() issue lines = %v; want %v", lines, expected)
+	}
+
+	if err := ImportFile(importer, filepath.Join(t.TempDir(), "missing"), func(string) {}, func(*Issue) {}); err == nil {
+		t.Errorf("ImportFile() of a missing file returned no error")
+	}
+}
+
+func TestNewImporterUnsupported(t *testing.T) {
+	if _, err := NewImporter("unknown"); err == nil {
+		t.Errorf("NewImporter(%q) returned no error", "unknown")
+	}
+}
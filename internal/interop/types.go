@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package interop
+
+import "github.com/funilrys/givilsta/internal/ruler"
+
+// ImportFormat is the name of a supported allowlist format to import from.
+type ImportFormat string
+
+const (
+	// ImportAdGuard: AdGuard / uBlock Origin exception rules (@@||example.com^).
+	ImportAdGuard ImportFormat = "adguard"
+	// ImportPiholeRegex: Pi-hole regex allowlist.
+	ImportPiholeRegex ImportFormat = "pihole-regex"
+	// ImportPiholeExact: Pi-hole exact allowlist, one domain per line.
+	ImportPiholeExact ImportFormat = "pihole-exact"
+	// ImportHosts: hosts file allowlist (0.0.0.0 example.com).
+	ImportHosts ImportFormat = "hosts"
+)
+
+// Issue describes a construct which could not be translated faithfully.
+type Issue struct {
+	// Line is the line number of the construct in its file, set by ImportFile.
+	Line int
+	// Input is the untranslated construct.
+	Input string
+	// Message explains what could not be translated.
+	Message string
+	// Skipped is set when the construct was not translated at all.
+	Skipped bool
+}
+
+// Importer translates the lines of a foreign allowlist into Givilsta rules.
+type Importer interface {
+	// Format returns the format handled by the importer.
+	Format() ImportFormat
+	// Import translates a single line. It returns the translated rules and, if
+	// the line could not be translated faithfully, why.
+	Import(line string) ([]string, *Issue)
+}
+
+// ExportFormat is the name of a supported allowlist format to export to.
+type ExportFormat string
+
+const (
+	// ExportAdGuard: AdGuard exception rules (@@||example.com^).
+	ExportAdGuard ExportFormat = "adguard"
+	// ExportPiholeRegex: Pi-hole regex allowlist.
+	ExportPiholeRegex ExportFormat = "pihole-regex"
+	// ExportDnsmasq: dnsmasq server=/example.com/# options.
+	ExportDnsmasq ExportFormat = "dnsmasq"
+	// ExportUnbound: Unbound transparent local zones.
+	ExportUnbound ExportFormat = "unbound"
+	// ExportRPZPassthru: RPZ zone of rpz-passthru records.
+	ExportRPZPassthru ExportFormat = "rpz-passthru"
+)
+
+// ExportOptions holds the options of the exporters.
+type ExportOptions struct {
+	// ComplementPrefixes are the complement prefixes handled by the ruler (e.g. "www.").
+	ComplementPrefixes []string
+	// Extensions returns the extensions the RZDB rules are expanded with.
+	Extensions func() []string
+	// Serial is the serial of the generated SOA record (rpz-passthru).
+	Serial uint32
+}
+
+// Exporter translates Givilsta rules into a foreign allowlist format.
+type Exporter interface {
+	// Format returns the format handled by the exporter.
+	Format() ExportFormat
+	// Header returns the lines to write before any rule.
+	Header() []string
+	// Export translates a single rule. It returns the translated lines and, if
+	// the rule could not be translated faithfully, why.
+	Export(rule ruler.Rule) ([]string, *Issue)
+}
@@ -0,0 +1,370 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rulefile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/funilrys/givilsta/internal/helpers"
+	"github.com/funilrys/givilsta/schema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// typeAliases maps the accepted rule types to their canonical name.
+var typeAliases = map[string]string{
+	"PLAIN": TypePlain,
+	"ALL":   TypeAll,
+	"REG":   TypeReg,
+	"RZDB":  TypeRzdb,
+	"RZD":   TypeRzdb,
+	"URL":   TypeURL,
+	"ROOT":  TypeRoot,
+}
+
+// formatExtensions maps the file extensions to the format of the structured rule files.
+var formatExtensions = map[string]string{
+	".json": FormatJSON,
+	".toml": FormatTOML,
+	".yaml": FormatYAML,
+	".yml":  FormatYAML,
+}
+
+// compileSchema compiles the embedded schema of the structured rule files, once.
+var compileSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema.Whitelist))
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+
+	if err := compiler.AddResource(schema.WhitelistURL, document); err != nil {
+		return nil, err
+	}
+
+	return compiler.Compile(schema.WhitelistURL)
+})
+
+// ValidationError describes why a structured rule file does not match the schema.
+type ValidationError struct {
+	// Path is the JSON path of the invalid value.
+	Path string
+	// Message explains what is wrong with the value.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// FormatOf returns the format of a structured rule file. The format is given
+// by the extension of the file (".json", ".toml", ".yaml" or ".yml"), once its
+// compression extension (e.g. ".gz") is removed. A file without such extension
+// is a JSON rule file when its (decompressed) content starts with a JSON object.
+//
+// Args:
+//
+//	name: The name of the file, as given by the end-user.
+//	filePath: The path to the local file.
+//
+// Returns:
+//
+//	string: The format of the file, empty when it is a line based rule file.
+func FormatOf(name string, filePath string) string {
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(helpers.TrimCompressionExtension(name)))]; ok {
+		return format
+	}
+
+	format := ""
+
+	// A file which cannot be read is not structured: the error is reported
+	// when the file is read as a plain rule file.
+	_ = helpers.IterFileUntil(filePath, func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			return true
+		}
+
+		if strings.HasPrefix(trimmed, "{") {
+			format = FormatJSON
+		}
+
+		return false
+	})
+
+	return format
+}
+
+// Load reads and validates a structured rule file.
+//
+// Args:
+//
+//	filePath: The path to the file to load.
+//	format: The format of the file, FormatJSON, FormatTOML or FormatYAML.
+//
+// Returns:
+//
+//	*Document: The loaded document.
+//	error: An error if the file cannot be read or does not match the schema.
+func Load(filePath string, format string) (*Document, error) {
+	file, err := helpers.OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Panic("error closing file:", err)
+		}
+	}()
+
+	return Parse(file, format)
+}
+
+// Parse reads and validates a structured rule document against the schema.
+//
+// Args:
+//
+//	reader: The reader to read the document from.
+//	format: The format of the document, FormatJSON, FormatTOML or FormatYAML.
+//
+// Returns:
+//
+//	*Document: The parsed document.
+//	error: An error if the document is malformed or does not match the schema.
+func Parse(reader io.Reader, format string) (*Document, error) {
+	raw, err := decode(reader, format)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := compileSchema()
+	if err != nil {
+		return nil, fmt.Errorf("compiling the rule file schema: %w", err)
+	}
+
+	if err := compiled.Validate(raw); err != nil {
+		var validationError *jsonschema.ValidationError
+
+		if errors.As(err, &validationError) {
+			return nil, schemaError(validationError)
+		}
+
+		return nil, &ValidationError{Path: "$", Message: err.Error()}
+	}
+
+	return parseDocument(raw)
+}
+
+// decode decodes a document into the values the JSON decoder gives with
+// UseNumber, so that every format is validated against the same schema.
+func decode(reader io.Reader, format string) (any, error) {
+	var raw any
+
+	switch format {
+	case FormatJSON:
+		decoded, err := jsonschema.UnmarshalJSON(reader)
+		if err != nil {
+			return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}
+		}
+
+		return decoded, nil
+	case FormatTOML:
+		var table map[string]any
+
+		if _, err := toml.NewDecoder(reader).Decode(&table); err != nil {
+			var parseErr toml.ParseError
+
+			if errors.As(err, &parseErr) {
+				return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid TOML: line %d: %s", parseErr.Position.Line, parseErr.Message)}
+			}
+
+			return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid TOML: %v", err)}
+		}
+
+		raw = table
+	case FormatYAML:
+		if err := yaml.NewDecoder(reader).Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+			return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid YAML: %v", err)}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rule file format %q", format)
+	}
+
+	content, err := json.Marshal(withDates(raw))
+	if err != nil {
+		return nil, &ValidationError{Path: "$", Message: fmt.Sprintf("invalid %s: %v", strings.ToUpper(format), err)}
+	}
+
+	return jsonschema.UnmarshalJSON(bytes.NewReader(content))
+}
+
+// withDates replaces the dates decoded from TOML and YAML by their string
+// form. A date without time (e.g. 2026-12-31) is written as such, so that it
+// expires at the end of the day like in a JSON document.
+func withDates(raw any) any {
+	switch value := raw.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(value))
+
+		for key, item := range value {
+			result[key] = withDates(item)
+		}
+
+		return result
+	case []map[string]any:
+		result := make([]any, 0, len(value))
+
+		for _, item := range value {
+			result = append(result, withDates(item))
+		}
+
+		return result
+	case []any:
+		result := make([]any, 0, len(value))
+
+		for _, item := range value {
+			result = append(result, withDates(item))
+		}
+
+		return result
+	case time.Time:
+		if value.Equal(value.Truncate(24*time.Hour)) && (value.Location() == time.UTC || value.Location().String() == "date-local") {
+			return value.Format(time.DateOnly)
+		}
+
+		return value.Format(time.RFC3339Nano)
+	}
+
+	return raw
+}
+
+// schemaError converts a schema validation error into a ValidationError
+// pointing at the JSON path of the first offending value.
+func schemaError(err *jsonschema.ValidationError) *ValidationError {
+	for len(err.Causes) > 0 {
+		err = err.Causes[0]
+	}
+
+	path := jsonPath(err.InstanceLocation)
+	printer := message.NewPrinter(language.English)
+
+	switch errorKind := err.ErrorKind.(type) {
+	case *kind.AdditionalProperties:
+		return &ValidationError{Path: fmt.Sprintf("%s.%s", path, errorKind.Properties[0]), Message: "unknown key"}
+	case *kind.Required:
+		return &ValidationError{Path: fmt.Sprintf("%s.%s", path, errorKind.Missing[0]), Message: "is required"}
+	}
+
+	return &ValidationError{Path: path, Message: err.ErrorKind.LocalizedString(printer)}
+}
+
+// jsonPath converts the location of a value (e.g. ["rules", "3", "type"]) into its JSON path (e.g. "$.rules[3].type").
+func jsonPath(location []string) string {
+	var path strings.Builder
+
+	path.WriteString("$")
+
+	for _, token := range location {
+		// The schema has no object with numeric keys: a numeric token is an array index.
+		if token != "" && strings.Trim(token, "0123456789") == "" {
+			fmt.Fprintf(&path, "[%s]", token)
+			continue
+		}
+
+		fmt.Fprintf(&path, ".%s", token)
+	}
+
+	return path.String()
+}
+
+// IsExpired checks if the rule is expired.
+//
+// Args:
+//
+//	now: The current time.
+//
+// Returns:
+//
+//	bool: true if the rule is expired, false otherwise.
+func (r Rule) IsExpired(now time.Time) bool {
+	if r.Expires == "" {
+		return false
+	}
+
+	expires, err := parseExpires(r.Expires)
+
+	return err == nil && now.After(expires)
+}
+
+// parseExpires parses an expiration date. A plain date expires at the end of the day.
+func parseExpires(value string) (time.Time, error) {
+	if expires, err := time.Parse(time.RFC3339, value); err == nil {
+		return expires, nil
+	}
+
+	expires, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return expires.Add(24 * time.Hour), nil
+}
+
+// parseDocument converts a document, validated against the schema, into a Document.
+func parseDocument(raw any) (*Document, error) {
+	content, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var document Document
+
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, &ValidationError{Path: "$", Message: err.Error()}
+	}
+
+	for index := range document.Rules {
+		rule := &document.Rules[index]
+
+		rule.Path = fmt.Sprintf("$.rules[%d]", index)
+
+		if rule.Type != "" {
+			rule.Type = typeAliases[strings.ToUpper(rule.Type)]
+		}
+
+		if strings.TrimSpace(rule.Value) == "" {
+			return nil, &ValidationError{Path: rule.Path + ".value", Message: "must be a non-empty string"}
+		}
+	}
+
+	return &document, nil
+}
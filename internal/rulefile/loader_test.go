@@ -0,0 +1,190 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rulefile
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/funilrys/givilsta/internal/helpers"
+)
+
+func TestParse(t *testing.T) {
+	documents := map[string]string{
+		FormatJSON: `{
+		"$schema": "https://example.org/whitelist.schema.json",
+		"version": 1,
+		"rules": [
+			{"type": "ALL", "value": "example.com", "reason": "CDN", "tags": ["cdn"], "owner": "team"},
+			{"value": "example.org"},
+			{"type": "rzd", "value": "example", "expires": "2025-01-31", "scope": ["hosts"]}
+		]
+	}`,
+		FormatTOML: `
+"$schema" = "https://example.org/whitelist.schema.json"
+version = 1
+
+[[rules]]
+type = "ALL"
+value = "example.com"
+reason = "CDN"
+tags = ["cdn"]
+owner = "team"
+
+[[rules]]
+value = "example.org"
+
+[[rules]]
+type = "rzd"
+value = "example"
+expires = 2025-01-31
+scope = ["hosts"]
+`,
+		FormatYAML: `
+$schema: https://example.org/whitelist.schema.json
+version: 1
+rules:
+  - type: ALL
+    value: example.com
+    reason: CDN
+    tags: [cdn]
+    owner: team
+  - value: example.org
+  - type: rzd
+    value: example
+    expires: 2025-01-31
+    scope: [hosts]
+`,
+	}
+
+	expected := []Rule{
+		{Type: TypeAll, Value: "example.com", Reason: "CDN", Tags: []string{"cdn"}, Owner: "team", Path: "$.rules[0]"},
+		{Type: "", Value: "example.org", Path: "$.rules[1]"},
+		{Type: TypeRzdb, Value: "example", Expires: "2025-01-31", Scope: []string{"hosts"}, Path: "$.rules[2]"},
+	}
+
+	for format, input := range documents {
+		document, err := Parse(strings.NewReader(input), format)
+
+		if err != nil {
+			t.Fatalf("Parse(%s) returned error: %v", format, err)
+		}
+
+		if !reflect.DeepEqual(document.Rules, expected) {
+			t.Errorf("Parse(%s).Rules = %+v; want %+v", format, document.Rules, expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+		path   string
+	}{
+		{FormatJSON, `[]`, "$"},
+		{FormatJSON, `{"version": 1, "rules": [], "foo": true}`, "$.foo"},
+		{FormatJSON, `{"rules": []}`, "$.version"},
+		{FormatJSON, `{"version": 2, "rules": []}`, "$.version"},
+		{FormatJSON, `{"version": 1}`, "$.rules"},
+		{FormatJSON, `{"version": 1, "rules": ["example.com"]}`, "$.rules[0]"},
+		{FormatJSON, `{"version": 1, "rules": [{"type": "ALL"}]}`, "$.rules[0].value"},
+		{FormatJSON, `{"version": 1, "rules": [{"value": "a"}, {"type": "FOO", "value": "b"}]}`, "$.rules[1].type"},
+		{FormatJSON, `{"version": 1, "rules": [{"value": "a", "tags": ["a", 1]}]}`, "$.rules[0].tags[1]"},
+		{FormatJSON, `{"version": 1, "rules": [{"value": "a", "expires": "tomorrow"}]}`, "$.rules[0].expires"},
+		{FormatJSON, `{"version": 1, "rules": [{"value": "a", "comment": "b"}]}`, "$.rules[0].comment"},
+		{FormatJSON, `{"version": 1, "rules": [{"value": " "}]}`, "$.rules[0].value"},
+		{FormatJSON, `{"version": 1, "rules": [`, "$"},
+		{FormatTOML, "version = 1\n[[rules]]\nvalue = 1", "$.rules[0].value"},
+		{FormatTOML, "version = 1\nrules = [", "$"},
+		{FormatYAML, "version: 1\nrules:\n  - value: a\n    expires: tomorrow", "$.rules[0].expires"},
+		{FormatYAML, "version: 1\nrules: [a", "$"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(strings.NewReader(test.input), test.format)
+
+		var validationError *ValidationError
+
+		if !errors.As(err, &validationError) {
+			t.Errorf("Parse(%q) returned %v; want a validation error", test.input, err)
+			continue
+		}
+
+		if validationError.Path != test.path {
+			t.Errorf("Parse(%q) error path = %q; want %q", test.input, validationError.Path, test.path)
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"rules.toml", "version = 1", FormatTOML},
+		{"rules.toml.gz", "version = 1", FormatTOML},
+		{"rules.yaml.xz", "version: 1", FormatYAML},
+		{"rules.yml.zst", "version: 1", FormatYAML},
+		{"rules.JSON.bz2", "{}", FormatJSON},
+		{"rules.list.gz", `{"version": 1}`, FormatJSON},
+		{"rules.list.gz", "example.com", ""},
+		{"rules.list", "example.com", ""},
+	}
+
+	for _, test := range tests {
+		filePath := filepath.Join(dir, test.name)
+
+		err := helpers.WriteFileFromIter(filePath, func(yield func(string)) { yield(test.content) })
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result := FormatOf(test.name, filePath); result != test.expected {
+			t.Errorf("FormatOf(%q) with %q = %q; want %q", test.name, test.content, result, test.expected)
+		}
+	}
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expires  string
+		expected bool
+	}{
+		{"", false},
+		{"2025-06-14", true},
+		{"2025-06-15", false},
+		{"2025-06-16", false},
+		{"2025-06-15T11:00:00Z", true},
+		{"2025-06-15T13:00:00Z", false},
+	}
+
+	for _, test := range tests {
+		result := Rule{Expires: test.expires}.IsExpired(now)
+		if result != test.expected {
+			t.Errorf("IsExpired(%q) = %v; want %v", test.expires, result, test.expected)
+		}
+	}
+}
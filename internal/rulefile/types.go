@@ -0,0 +1,63 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rulefile
+
+// SchemaVersion is the version of the structured rule file format.
+const SchemaVersion = 1
+
+// Formats of the structured rule files.
+const (
+	FormatJSON = "json"
+	FormatTOML = "toml"
+	FormatYAML = "yaml"
+)
+
+// Rule types accepted in structured rule files.
+const (
+	TypePlain = "PLAIN"
+	TypeAll   = "ALL"
+	TypeReg   = "REG"
+	TypeRzdb  = "RZDB"
+	TypeURL   = "URL"
+	TypeRoot  = "ROOT"
+)
+
+// Document represents a structured rule file.
+type Document struct {
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule represents a single rule of a structured rule file.
+type Rule struct {
+	// Type is the type of the rule, empty when the type is not given.
+	Type string `json:"type,omitempty"`
+	// Value is the entry of the rule.
+	Value string `json:"value"`
+	// Reason explains why the rule exists.
+	Reason string `json:"reason,omitempty"`
+	// Tags helps to group rules.
+	Tags []string `json:"tags,omitempty"`
+	// Owner is the person or team responsible for the rule.
+	Owner string `json:"owner,omitempty"`
+	// Expires is the date (YYYY-MM-DD or RFC 3339) after which the rule is ignored.
+	Expires string `json:"expires,omitempty"`
+	// Scope lists where the rule is meant to be applied.
+	Scope []string `json:"scope,omitempty"`
+
+	// Path is the JSON path of the rule in its document.
+	Path string `json:"-"`
+}
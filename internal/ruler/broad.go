@@ -0,0 +1,208 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// BroadRuleError describes a rule which was refused because it whitelists too much.
+type BroadRuleError struct {
+	// Rule is the refused rule.
+	Rule string
+	// Reason explains why the rule is considered as over-broad.
+	Reason string
+}
+
+func (e *BroadRuleError) Error() string {
+	return fmt.Sprintf("over-broad rule %q: %s", e.Rule, e.Reason)
+}
+
+// InvalidRuleError describes a rule which was refused because it cannot be loaded.
+type InvalidRuleError struct {
+	// Rule is the refused rule.
+	Rule string
+	// Reason explains why the rule cannot be loaded.
+	Reason string
+}
+
+func (e *InvalidRuleError) Error() string {
+	return fmt.Sprintf("invalid rule %q: %s", e.Rule, e.Reason)
+}
+
+// ErrFrozenRuler is returned when a rule is added to or removed from a frozen ruler.
+var ErrFrozenRuler = errors.New("the ruler is frozen, its rules can no longer be changed")
+
+// broadRegexProbes are the subjects a regular expression has to match to be
+// considered as a match-all regular expression.
+var broadRegexProbes = []string{
+	"example.com",
+	"a.b.example.co.uk",
+	"xn--bcher-kva.de",
+	"z9-z.net",
+	"localhost",
+}
+
+// IsPublicSuffix checks if the given domain is a public suffix (e.g. "com",
+// "co.uk") according to the Public Suffix List. Unknown TLDs are considered
+// as public suffixes.
+//
+// Args:
+//
+//	domain: The (canonical) domain to check.
+//
+// Returns:
+//
+//	bool: true if the domain is a public suffix, false otherwise.
+func IsPublicSuffix(domain string) bool {
+	domain = strings.Trim(domain, ".")
+
+	if domain == "" {
+		return true
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+
+	return suffix == domain
+}
+
+// SetAllowBroadRules allows (or refuses) the over-broad rules.
+//
+// Args:
+//
+//	allow: Whether the over-broad rules are allowed or not.
+func (fun *InternalRuler) SetAllowBroadRules(allow bool) {
+	fun.allow_broad_rules = allow
+}
+
+// CheckRule checks if the given rule would be refused because it cannot be
+// loaded or because it is over-broad. Over-broad rules which are allowed or
+// forced are not reported, neither are the skipped ones (comments or invalid
+// domains).
+//
+// Args:
+//
+//	rule: The rule to check.
+//
+// Returns:
+//
+//	error: An *InvalidRuleError if the rule cannot be loaded (e.g. invalid
+//	modifiers or regular expression), a *BroadRuleError if the rule is over-broad, an
+//	error if the known extensions cannot be fetched, nil otherwise.
+func (fun *InternalRuler) CheckRule(rule string) error {
+	normalizedRule, modifiers, err := fun.parseRule(rule)
+
+	if err != nil || normalizedRule == "" {
+		return err
+	}
+
+	if err := fun.checkLoadableRule(rule, normalizedRule); err != nil {
+		return err
+	}
+
+	if err := fun.checkBroadRule(rule, normalizedRule, modifiers); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkLoadableRule checks that the given normalized rule can be indexed:
+// the regular expression of a REG rule has to compile, and the known
+// extensions have to be available to expand a RZDB rule.
+func (fun *InternalRuler) checkLoadableRule(rule string, normalizedRule string) error {
+	if fun.HasFlag(fun.FlagsReg, normalizedRule) {
+		if _, err := regexp.Compile(fun.cleanupFlags(fun.FlagsReg, normalizedRule)); err != nil {
+			return &InvalidRuleError{Rule: strings.TrimSpace(rule), Reason: err.Error()}
+		}
+	}
+
+	if fun.HasFlag(fun.FlagsRzdb, normalizedRule) {
+		if _, err := fun.getKnownExtensions(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkBroadRule checks the given normalized rule against the over-broad rules.
+func (fun *InternalRuler) checkBroadRule(rule string, normalizedRule string, modifiers Modifiers) *BroadRuleError {
+	if fun.allow_broad_rules || modifiers.Force {
+		return nil
+	}
+
+	if reason, broad := fun.broadReason(normalizedRule, modifiers); broad {
+		return &BroadRuleError{Rule: strings.TrimSpace(rule), Reason: reason}
+	}
+
+	return nil
+}
+
+// broadReason explains why the given normalized rule is over-broad.
+//
+// Returns:
+//
+//	string: The reason why the rule is over-broad.
+//	bool: true if the rule is over-broad, false otherwise.
+func (fun *InternalRuler) broadReason(normalizedRule string, modifiers Modifiers) (string, bool) {
+	var flag, kind string
+
+	if match := ruleFlagRegex.FindStringSubmatch(normalizedRule); match != nil {
+		flag, kind = match[0], ruleKindOfFlag(match[1])
+	}
+
+	record := strings.TrimPrefix(normalizedRule, flag)
+
+	switch kind {
+	case RuleKindAll:
+		if IsPublicSuffix(record) {
+			return fmt.Sprintf("%q is a public suffix, the rule whitelists every domain under it", strings.TrimPrefix(record, ".")), true
+		}
+	case RuleKindRzdb:
+		record = StripComplementPrefix(record, fun.complementPrefixesOf(modifiers))
+
+		// The extensions were loaded when the rule was checked as loadable.
+		extensions, _ := fun.getKnownExtensions()
+
+		for _, extension := range extensions {
+			if domain := fmt.Sprintf("%s.%s", record, extension); IsPublicSuffix(domain) {
+				return fmt.Sprintf("the rule covers the public suffix %q", domain), true
+			}
+		}
+	case RuleKindReg:
+		compiled, err := regexp.Compile(regexOf(record, modifiers))
+
+		if err != nil {
+			return "", false
+		}
+
+		for _, probe := range broadRegexProbes {
+			if !compiled.MatchString(probe) {
+				return "", false
+			}
+		}
+
+		return "the regular expression matches any subject", true
+	}
+
+	return "", false
+}
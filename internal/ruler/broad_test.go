@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckRule(t *testing.T) {
+	ruler := testGetNewRulerWithComplementsHandling()
+
+	tests := []struct {
+		rule  string
+		broad bool
+	}{
+		{"example.com", false},
+		{"com", false},
+		{"ALL example.com", false},
+		{"ALL .example.co.uk", false},
+		{"ALL .com", true},
+		{"ALL com", true},
+		{"ALL co.uk", true},
+		{"ALL .blogspot.com", true},
+		{"ALL[depth=1] .com", true},
+		{"ALL[force] .com", false},
+		{"REG .*", true},
+		{"REG ^.+$", true},
+		{"REG[icase] .", true},
+		{"REG ^example", false},
+		{"REG \\.com$", false},
+		{"REG[force] .*", false},
+		{"ROOT example.co.uk", false},
+		{"RZDB example", false},
+		{"RZDB com", true},
+		{"RZDB www.com", true},
+		{"RZDB[complement=off] www.com", false},
+	}
+
+	for _, test := range tests {
+		err := ruler.CheckRule(test.rule)
+
+		if (err != nil) != test.broad {
+			t.Errorf("CheckRule(%q) = %v; want broad: %v", test.rule, err, test.broad)
+		}
+	}
+
+	if err := ruler.CheckRule("ALL .com"); err != nil && !strings.Contains(err.Error(), `"ALL .com"`) {
+		t.Errorf("CheckRule(%q) = %q; want the rule in the error", "ALL .com", err)
+	}
+
+	ruler.SetAllowBroadRules(true)
+
+	if err := ruler.CheckRule("ALL .com"); err != nil {
+		t.Errorf("CheckRule(%q) = %v; want nil when the over-broad rules are allowed", "ALL .com", err)
+	}
+}
+
+func TestCheckInvalidRule(t *testing.T) {
+	ruler := testGetNewRuler()
+
+	for _, rule := range []string{"REG ([a-z", "REG[icase] a)", "ALL[depth=0] .com", "example.com[depth=1]"} {
+		err := ruler.CheckRule(rule)
+
+		var invalid *InvalidRuleError
+
+		if !errors.As(err, &invalid) {
+			t.Errorf("CheckRule(%q) = %v; want an *InvalidRuleError", rule, err)
+		}
+
+		if ruler.AddRule(rule) {
+			t.Errorf("AddRule(%q) = true; want the invalid rule refused", rule)
+		}
+	}
+
+	if rules := ruler.Rules(); len(rules) != 0 {
+		t.Errorf("Rules() = %v; want no rule loaded", rules)
+	}
+}
+
+func TestIsPublicSuffix(t *testing.T) {
+	tests := []struct {
+		domain   string
+		expected bool
+	}{
+		{"com", true},
+		{".com", true},
+		{"co.uk", true},
+		{"blogspot.com", true},
+		{"unknowntld", true},
+		{"", true},
+		{"example.com", false},
+		{"example.co.uk", false},
+	}
+
+	for _, test := range tests {
+		if result := IsPublicSuffix(test.domain); result != test.expected {
+			t.Errorf("IsPublicSuffix(%q) = %v; want %v", test.domain, result, test.expected)
+		}
+	}
+}
@@ -21,17 +21,28 @@ import (
 	"regexp"
 	"slices"
 	"strings"
-
-	"github.com/funilrys/givilsta/internal/data"
 )
 
 // Our internal constructor
 func NewInternalRuler(handle_complement bool, logger *slog.Logger) *InternalRuler {
+	if handle_complement {
+		return NewInternalRulerWithComplementPrefixes(DefaultComplementPrefixes, logger)
+	}
+
+	return NewInternalRulerWithComplementPrefixes(nil, logger)
+}
+
+// NewInternalRulerWithComplementPrefixes creates a ruler which handles the
+// complements of the given prefixes (e.g. "www.", "m.").
+// The complements are not handled when no prefix is given.
+func NewInternalRulerWithComplementPrefixes(complement_prefixes []string, logger *slog.Logger) *InternalRuler {
 	var FlagsAll = []string{"ALL ", "ALL:", "ALL#", "ALL,", "ALL@"}
 	var FlagsReg = []string{"REG ", "REG:", "REG#", "REG,", "REG@"}
 	var FlagsRzdb = []string{"RZD ", "RZD:", "RZD#", "RZD,", "RZD@", "RZDB ", "RZDB:", "RZDB#", "RZDB,", "RZDB@"}
+	var FlagsURL = []string{"URL ", "URL:", "URL#", "URL,", "URL@"}
+	var FlagsRoot = []string{"ROOT ", "ROOT:", "ROOT#", "ROOT,", "ROOT@"}
 
-	var AllowedFlags = append(append([]string{}, FlagsAll...), append(FlagsReg, FlagsRzdb...)...)
+	var AllowedFlags = append(append(append(append([]string{}, FlagsAll...), append(FlagsReg, FlagsRzdb...)...), FlagsURL...), FlagsRoot...)
 
 	// ALL: the "ends-with" rule.
 	var FlagAll = "ALL#"
@@ -39,30 +50,42 @@ func NewInternalRuler(handle_complement bool, logger *slog.Logger) *InternalRule
 	var FlagReg = "REG#"
 	// RZDB: the RZDB rule.
 	var FlagRzdb = "RZDB#"
+	// URL: the URL-prefix rule.
+	var FlagURL = "URL#"
+	// ROOT: the registrable domain rule.
+	var FlagRoot = "ROOT#"
 
 	return &InternalRuler{
-		strict:            make(map[string][]string),
-		ends:              make(map[string][]string),
-		present:           make(map[string][]string),
-		regex:             "",
-		compiled_regexp:   nil,
-		extensions:        []string{},
-		handle_complement: handle_complement,
-		logger:            logger,
+		strict:              make(map[string][]string),
+		ends:                make(map[string][]string),
+		depthEnds:           make(map[string][]depthRule),
+		prefixes:            make(map[string][]string),
+		owners:              make(map[string][]Rule),
+		origins:             make(map[Rule]string),
+		regex:               "",
+		compiled_regexp:     nil,
+		extensions:          []string{},
+		complement_prefixes: NormalizeComplementPrefixes(complement_prefixes),
+		logger:              logger,
 
 		// Shared flags for different rule types
 		FlagsAll:     FlagsAll,
 		FlagsReg:     FlagsReg,
 		FlagsRzdb:    FlagsRzdb,
+		FlagsURL:     FlagsURL,
+		FlagsRoot:    FlagsRoot,
 		AllowedFlags: AllowedFlags,
 		// Default flag for each rule type
 		FlagAll:  FlagAll,
 		FlagReg:  FlagReg,
 		FlagRzdb: FlagRzdb,
+		FlagURL:  FlagURL,
+		FlagRoot: FlagRoot,
 	}
 }
 
-// AddRule adds a rule to the whitelist checker.
+// AddRule adds a rule to the whitelist checker. The refused rules (see
+// AddRuleWithOrigin) are logged and skipped.
 //
 // Args:
 //
@@ -72,7 +95,57 @@ func NewInternalRuler(handle_complement bool, logger *slog.Logger) *InternalRule
 //
 //	bool: true if the rule was added successfully, false otherwise.
 func (fun *InternalRuler) AddRule(rule string) bool {
-	normalizedRule := NormalizeRule(rule)
+	added, err := fun.addRule(rule)
+
+	if err != nil {
+		fun.logger.Warn("Refusing rule", slog.String("rule", rule), slog.String("error", err.Error()))
+		return false
+	}
+
+	return added
+}
+
+// AddRuleWithOrigin adds a rule to the whitelist checker and records where it
+// comes from (e.g. "whitelist.list:3"). When the same rule is added several
+// times, the first origin is kept. The rule is checked once, as CheckRule
+// does, before being added.
+//
+// Args:
+//
+//	rule: The rule to add.
+//	origin: The origin of the rule.
+//
+// Returns:
+//
+//	bool: true if the rule was added successfully, false otherwise.
+//	error: An *InvalidRuleError if the rule cannot be loaded, a
+//	*BroadRuleError if the rule is over-broad, ErrFrozenRuler if the ruler
+//	is frozen, an error if the known extensions cannot be fetched, nil
+//	otherwise (even for skipped rules).
+func (fun *InternalRuler) AddRuleWithOrigin(rule string, origin string) (bool, error) {
+	added, err := fun.addRule(rule)
+
+	if !added || err != nil {
+		return false, err
+	}
+
+	last := fun.rules[len(fun.rules)-1]
+
+	if _, ok := fun.origins[last]; !ok {
+		fun.origins[last] = origin
+	}
+
+	return true, nil
+}
+
+// addRule checks and adds a rule to the whitelist checker.
+//
+// Returns:
+//
+//	bool: true if the rule was added successfully, false otherwise.
+//	error: The reason why the rule was refused, nil if it was added or skipped.
+func (fun *InternalRuler) addRule(rule string) (bool, error) {
+	normalizedRule, modifiers, err := fun.parseRule(rule)
 
 	logger := fun.logger.With(
 		slog.String("rule", rule),
@@ -80,12 +153,59 @@ func (fun *InternalRuler) AddRule(rule string) bool {
 	)
 	logger.Debug("Adding rule")
 
+	if fun.frozen {
+		return false, ErrFrozenRuler
+	}
+
+	if err != nil {
+		return false, err
+	}
+
 	if normalizedRule == "" {
-		logger.Debug("Rule is empty or a comment, skipping")
-		return false
+		logger.Debug("Rule is empty, a comment or invalid, skipping")
+		return false, nil
 	}
 
-	return fun.parseAllFlaggedRule(normalizedRule) || fun.parseRegexFlaggedRule(normalizedRule) || fun.parseRZDBFlagedRule(normalizedRule) || fun.parsePlainRule(normalizedRule)
+	if err := fun.checkLoadableRule(rule, normalizedRule); err != nil {
+		return false, err
+	}
+
+	if err := fun.checkBroadRule(rule, normalizedRule, modifiers); err != nil {
+		return false, err
+	}
+
+	// The force marker only matters while adding, the rule is indexed without it.
+	modifiers.Force = false
+
+	if fun.HasFlag(fun.FlagsURL, normalizedRule) {
+		// An invalid URL rule must not fall back to a plain rule.
+		return fun.parseURLFlaggedRule(normalizedRule, modifiers), nil
+	}
+
+	if fun.HasFlag(fun.FlagsRoot, normalizedRule) {
+		// A ROOT rule without registrable domain must not fall back to a plain rule.
+		return fun.parseRootFlaggedRule(normalizedRule, modifiers), nil
+	}
+
+	if fun.HasFlag(fun.FlagsReg, normalizedRule) {
+		// A REG rule which cannot be compiled must not fall back to a plain rule.
+		return fun.parseRegexFlaggedRule(normalizedRule, modifiers), nil
+	}
+
+	return fun.parseAllFlaggedRule(normalizedRule, modifiers) || fun.parseRZDBFlagedRule(normalizedRule, modifiers) || fun.parsePlainRule(normalizedRule, modifiers), nil
+}
+
+// Origin returns where the given loaded rule comes from.
+//
+// Args:
+//
+//	rule: The loaded rule.
+//
+// Returns:
+//
+//	string: The origin of the rule, empty when unknown.
+func (fun *InternalRuler) Origin(rule Rule) string {
+	return fun.origins[rule]
 }
 
 // RemoveRule removes a rule from the whitelist checker.
@@ -97,8 +217,9 @@ func (fun *InternalRuler) AddRule(rule string) bool {
 // Returns:
 //
 //	bool: true if the rule was removed successfully, false otherwise.
-func (fun *InternalRuler) RemoveRule(rule string) bool {
-	normalizedRule := NormalizeRule(rule)
+//	error: ErrFrozenRuler if the ruler is frozen, nil otherwise.
+func (fun *InternalRuler) RemoveRule(rule string) (bool, error) {
+	normalizedRule, modifiers, ok := fun.normalizeRule(rule)
 
 	logger := fun.logger.With(
 		slog.String("rule", rule),
@@ -106,16 +227,115 @@ func (fun *InternalRuler) RemoveRule(rule string) bool {
 	)
 	logger.Debug("Removing rule")
 
-	if normalizedRule == "" {
-		logger.Debug("Rule is empty or a comment, skipping")
-		return false
+	if fun.frozen {
+		return false, ErrFrozenRuler
+	}
+
+	if !ok {
+		logger.Debug("Rule is empty, a comment or invalid, skipping")
+		return false, nil
+	}
+
+	// The force marker only matters while adding, the rule is indexed without it.
+	modifiers.Force = false
+
+	if fun.HasFlag(fun.FlagsURL, normalizedRule) {
+		// An invalid URL rule must not fall back to a plain rule.
+		return fun.unparseURLFlaggedRule(normalizedRule, modifiers), nil
+	}
+
+	if fun.HasFlag(fun.FlagsRoot, normalizedRule) {
+		// A ROOT rule without registrable domain must not fall back to a plain rule.
+		return fun.unparseRootFlaggedRule(normalizedRule, modifiers), nil
+	}
+
+	return fun.unparseAllFlaggedRule(normalizedRule, modifiers) || fun.unparseRegexFlaggedRule(normalizedRule, modifiers) || fun.unparseRZDBFlagedRule(normalizedRule, modifiers) || fun.unparsePlainRule(normalizedRule, modifiers), nil
+}
+
+// Freeze makes the ruler immutable: the rules can no longer be added or
+// removed (ErrFrozenRuler). As the checks never change the ruler, a frozen ruler can be shared
+// by concurrent goroutines.
+func (fun *InternalRuler) Freeze() {
+	fun.frozen = true
+}
+
+// IsFrozen checks if the ruler is frozen.
+//
+// Returns:
+//
+//	bool: true if the ruler is frozen, false otherwise.
+func (fun *InternalRuler) IsFrozen() bool {
+	return fun.frozen
+}
+
+// normalizeRule extracts the modifiers of the given rule and normalizes the rest of it.
+//
+// Returns:
+//
+//	string: The normalized rule, without its modifiers.
+//	Modifiers: The modifiers of the rule.
+//	bool: false if the rule is empty, a comment or invalid.
+func (fun *InternalRuler) normalizeRule(rule string) (string, Modifiers, bool) {
+	normalizedRule, modifiers, err := fun.parseRule(rule)
+
+	if err != nil {
+		fun.logger.Warn("Invalid rule modifiers, skipping", slog.String("rule", rule), slog.String("error", err.Error()))
+		return "", Modifiers{}, false
+	}
+
+	return normalizedRule, modifiers, normalizedRule != ""
+}
+
+// parseRule splits the given rule into its normalized form and its modifiers.
+//
+// Returns:
+//
+//	string: The normalized rule, empty for an empty rule or a comment.
+//	Modifiers: The modifiers of the rule.
+//	error: An *InvalidRuleError if the modifiers are invalid.
+func (fun *InternalRuler) parseRule(rule string) (string, Modifiers, error) {
+	withoutModifiers, modifiers, err := ExtractModifiers(rule)
+
+	if err != nil {
+		return "", Modifiers{}, &InvalidRuleError{Rule: strings.TrimSpace(rule), Reason: err.Error()}
+	}
+
+	return NormalizeRule(withoutModifiers), modifiers, nil
+}
+
+// complementPrefixesOf returns the complement prefixes handled by a rule with the given modifiers.
+func (fun *InternalRuler) complementPrefixesOf(modifiers Modifiers) []string {
+	switch modifiers.Complement {
+	case ComplementOff:
+		return nil
+	case ComplementOn:
+		if len(fun.complement_prefixes) == 0 {
+			return DefaultComplementPrefixes
+		}
 	}
 
-	return fun.unparseAllFlaggedRule(normalizedRule) || fun.unparseRegexFlaggedRule(normalizedRule) || fun.unparseRZDBFlagedRule(normalizedRule) || fun.unparsePlainRule(normalizedRule)
+	return fun.complement_prefixes
 }
 
 func (fun *InternalRuler) IsWhitelisted(subject string) bool {
-	normalizedSubject := NormalizeSubject(subject, fun.handle_complement)
+	_, whitelisted := fun.WhitelistingRule(subject)
+
+	return whitelisted
+}
+
+// WhitelistingRule returns the rule which whitelists the given subject.
+//
+// Args:
+//
+//	subject: The subject to check.
+//
+// Returns:
+//
+//	Rule: The rule which whitelists the subject, the first loaded one when several rules match.
+//	bool: true if the subject is whitelisted, false otherwise.
+func (fun *InternalRuler) WhitelistingRule(subject string) (Rule, bool) {
+	// The rules are indexed with their complements, so the subject is looked up as is.
+	normalizedSubject := normalizeSubject(subject, nil)
 
 	logger := fun.logger.With(
 		slog.String("subject", subject),
@@ -126,7 +346,7 @@ func (fun *InternalRuler) IsWhitelisted(subject string) bool {
 	if normalizedSubject == "" {
 		logger.Debug("Normalized subject is empty, skipping")
 
-		return false
+		return Rule{}, false
 	}
 
 	var subjects []string
@@ -136,7 +356,7 @@ func (fun *InternalRuler) IsWhitelisted(subject string) bool {
 	if err != nil {
 		// If we cannot extract the net location, we consider the subject as a path.
 		logger.Debug("Failed to extract net location.", slog.String("error", err.Error()))
-		return false
+		return Rule{}, false
 	}
 
 	subjects = append(subjects, netloc)
@@ -144,6 +364,13 @@ func (fun *InternalRuler) IsWhitelisted(subject string) bool {
 	if strings.HasPrefix(normalizedSubject, "http://") || strings.HasPrefix(normalizedSubject, "https://") {
 		// We do this in order to handle the case that someone put an URL in the whitelist.
 		subjects = append(subjects, normalizedSubject)
+
+		if owner, ok := fun.isURLPrefixWhitelisted(normalizedSubject); ok {
+			logger.Debug("Subject found in URL rules")
+			return owner, true
+		}
+
+		logger.Debug("Subject not found in URL rules. Continuing search")
 	}
 
 	for _, sub := range subjects {
@@ -151,34 +378,36 @@ func (fun *InternalRuler) IsWhitelisted(subject string) bool {
 
 		if rules, ok := fun.strict[commonKey]; ok && slices.Contains(rules, sub) {
 			logger.Debug("Subject found in strict rules", slog.String("extractedSubject", sub))
-			return true
+			return fun.ownerOf(indexStrict, sub), true
 		}
 
 		logger.Debug("Subject not found in strict rules. Continuing search", slog.String("extractedSubject", sub))
 
-		if rules, ok := fun.present[commonKey]; ok && slices.Contains(rules, sub) {
-			logger.Debug("Subject found in present rules", slog.String("extractedSubject", sub))
-			return true
-		}
-
-		logger.Debug("Subject not found in present rules. Continuing search", slog.String("extractedSubject", sub))
-
 		endKey := fun.endsSearchKeyFromRule(sub)
 
 		if rules, ok := fun.ends[endKey]; ok {
 			for _, rule := range rules {
 				if strings.HasSuffix(sub, rule) {
 					logger.Debug("Subject found in ends rules", slog.String("extractedSubject", sub), slog.String("rule", rule))
-					return true
+					return fun.ownerOf(indexEnds, rule), true
 				}
 			}
 		}
 
 		logger.Debug("Subject not found in ends rules. Continuing search", slog.String("extractedSubject", sub))
 
-		if fun.compiled_regexp != nil && fun.compiled_regexp.MatchString(sub) {
+		for _, rule := range fun.depthEnds[endKey] {
+			if rule.matches(sub) {
+				logger.Debug("Subject found in depth rules", slog.String("extractedSubject", sub), slog.String("rule", rule.suffix), slog.Int("depth", rule.depth))
+				return rule.owner, true
+			}
+		}
+
+		logger.Debug("Subject not found in depth rules. Continuing search", slog.String("extractedSubject", sub))
+
+		if fun.compiled_regexp != nil && (fun.compiled_regexp.MatchString(sub) || fun.compiled_regexp.MatchString(StripComplementPrefix(sub, fun.complement_prefixes))) {
 			logger.Debug("Subject found in regex rules", slog.String("extractedSubject", sub))
-			return true
+			return fun.regexOwnerOf(sub), true
 		}
 
 		logger.Debug("Subject not found in regex rules.", slog.String("extractedSubject", sub))
@@ -186,9 +415,172 @@ func (fun *InternalRuler) IsWhitelisted(subject string) bool {
 
 	logger.Debug("Subject not matched any rule")
 
+	return Rule{}, false
+}
+
+// IsWildcardWhitelisted checks if a subject and all its subdomains are
+// whitelisted. Only the "ends-with" rules can cover a whole subtree, so they
+// are the only one considered.
+//
+// Args:
+//
+//	subject: The subject to check.
+//
+// Returns:
+//
+//	bool: true if the subject and all its subdomains are whitelisted, false otherwise.
+func (fun *InternalRuler) IsWildcardWhitelisted(subject string) bool {
+	normalizedSubject := NormalizeSubject(subject, false)
+
+	logger := fun.logger.With(
+		slog.String("subject", subject),
+		slog.String("normalizedSubject", normalizedSubject),
+	)
+	logger.Debug("Checking wildcard subject")
+
+	if normalizedSubject == "" {
+		logger.Debug("Normalized subject is empty, skipping")
+
+		return false
+	}
+
+	sub := fmt.Sprintf(".%s", normalizedSubject)
+
+	if rules, ok := fun.ends[fun.endsSearchKeyFromRule(sub)]; ok {
+		for _, rule := range rules {
+			if strings.HasSuffix(sub, rule) {
+				logger.Debug("Wildcard subject found in ends rules", slog.String("rule", rule))
+				return true
+			}
+		}
+	}
+
+	logger.Debug("Wildcard subject not matched any rule")
+
 	return false
 }
 
+// Rules returns the rules currently loaded into the ruler, in the order they
+// were added. Removed (bypassed) rules are not part of the result.
+//
+// Returns:
+//
+//	[]Rule: The loaded rules.
+func (fun *InternalRuler) Rules() []Rule {
+	return append([]Rule{}, fun.rules...)
+}
+
+// String returns the rule as it would be written in a rule file.
+func (r Rule) String() string {
+	if r.Kind == RuleKindPlain {
+		if r.Modifiers.IsZero() {
+			return r.Value
+		}
+
+		return fmt.Sprintf("%s[%s]", r.Value, r.Modifiers)
+	}
+
+	if r.Modifiers.IsZero() {
+		return fmt.Sprintf("%s %s", r.Kind, r.Value)
+	}
+
+	return fmt.Sprintf("%s[%s] %s", r.Kind, r.Modifiers, r.Value)
+}
+
+// KnownExtensions returns the extensions the RZDB rules are expanded with.
+//
+// Returns:
+//
+//	[]string: The known extensions, nil if they cannot be fetched.
+func (fun *InternalRuler) KnownExtensions() []string {
+	extensions, err := fun.getKnownExtensions()
+
+	if err != nil {
+		fun.logger.Error("Unable to load the known extensions", slog.String("error", err.Error()))
+	}
+
+	return extensions
+}
+
+// own records that the given entry of the given index was pushed by the given rule.
+func (fun *InternalRuler) own(index string, entry string, owner Rule) {
+	key := index + entry
+
+	fun.owners[key] = append(fun.owners[key], owner)
+}
+
+// disown forgets that the given entry of the given index was pushed by the given rule.
+func (fun *InternalRuler) disown(index string, entry string, owner Rule) {
+	key := index + entry
+
+	if position := slices.Index(fun.owners[key], owner); position >= 0 {
+		fun.owners[key] = slices.Delete(fun.owners[key], position, position+1)
+	} else if len(fun.owners[key]) > 0 {
+		// The entry was pushed by another rule (e.g. a complement), it is gone anyway.
+		fun.owners[key] = fun.owners[key][1:]
+	}
+
+	if len(fun.owners[key]) == 0 {
+		delete(fun.owners, key)
+	}
+}
+
+// ownerOf returns the rule which pushed the given entry of the given index first.
+func (fun *InternalRuler) ownerOf(index string, entry string) Rule {
+	if owners := fun.owners[index+entry]; len(owners) > 0 {
+		return owners[0]
+	}
+
+	return Rule{}
+}
+
+func (fun *InternalRuler) rememberRule(rule Rule) {
+	fun.rules = append(fun.rules, rule)
+}
+
+func (fun *InternalRuler) forgetRule(rule Rule) {
+	if index := slices.Index(fun.rules, rule); index >= 0 {
+		fun.rules = slices.Delete(fun.rules, index, index+1)
+	}
+
+	if !slices.Contains(fun.rules, rule) {
+		delete(fun.origins, rule)
+	}
+}
+
+// isURLPrefixWhitelisted checks if the given URL is under one of the URL-prefix rules
+// and returns the rule of the prefix.
+func (fun *InternalRuler) isURLPrefixWhitelisted(subject string) (Rule, bool) {
+	canonical, err := canonicalURL(subject)
+
+	if err != nil {
+		fun.logger.Debug("Failed to canonicalize URL.", slog.String("subject", subject), slog.String("error", err.Error()))
+		return Rule{}, false
+	}
+
+	for _, prefix := range fun.prefixes[canonical.Host] {
+		if MatchURLPrefix(canonical.String(), prefix) {
+			fun.logger.Debug("URL found under prefix", slog.String("subject", subject), slog.String("prefix", prefix))
+			return fun.ownerOf(indexPrefixes, prefix), true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// regexOwnerOf returns the REG rule matching the given subject.
+func (fun *InternalRuler) regexOwnerOf(subject string) Rule {
+	stripped := StripComplementPrefix(subject, fun.complement_prefixes)
+
+	for _, rule := range fun.regexRules {
+		if rule.compiled.MatchString(subject) || rule.compiled.MatchString(stripped) {
+			return rule.owner
+		}
+	}
+
+	return Rule{}
+}
+
 func (fun *InternalRuler) commonSearchKeyFromRule(rule string) string {
 	if len(rule) < 4 {
 		return rule
@@ -205,30 +597,23 @@ func (fun *InternalRuler) endsSearchKeyFromRule(rule string) string {
 	return rule[len(rule)-3:]
 }
 
-func (fun *InternalRuler) getKnownExtensions() []string {
-	if len(fun.extensions) == 0 {
-		fun.extensions = append(fun.extensions, data.NewIANAExtensions().Extensions...)
-		fun.extensions = append(fun.extensions, data.NewPSLExtensions().Suffixes...)
-	}
-
-	return fun.extensions
-}
-
-func (fun *InternalRuler) pushStrictRule(rule string) {
+func (fun *InternalRuler) pushStrictRule(rule string, owner Rule) {
 	searchKey := fun.commonSearchKeyFromRule(rule)
 
 	fun.strict[searchKey] = append(fun.strict[searchKey], rule)
+	fun.own(indexStrict, rule, owner)
 
 	fun.logger.Debug("Pushed strict rule", slog.String("rule", rule), slog.String("searchKey", searchKey))
 }
 
-func (fun *InternalRuler) pullStrictRule(rule string) {
+func (fun *InternalRuler) pullStrictRule(rule string, owner Rule) {
 	searchKey := fun.commonSearchKeyFromRule(rule)
 
 	if _, ok := fun.strict[searchKey]; ok {
 		for i, r := range fun.strict[searchKey] {
 			if r == rule {
 				fun.strict[searchKey] = append(fun.strict[searchKey][:i], fun.strict[searchKey][i+1:]...)
+				fun.disown(indexStrict, rule, owner)
 
 				fun.logger.Debug("Pulled strict rule", slog.String("rule", rule), slog.String("searchKey", searchKey))
 				break
@@ -237,21 +622,23 @@ func (fun *InternalRuler) pullStrictRule(rule string) {
 	}
 }
 
-func (fun *InternalRuler) pushEndsRule(rule string) {
+func (fun *InternalRuler) pushEndsRule(rule string, owner Rule) {
 	searchKey := fun.endsSearchKeyFromRule(rule)
 
 	fun.ends[searchKey] = append(fun.ends[searchKey], rule)
+	fun.own(indexEnds, rule, owner)
 
 	fun.logger.Debug("Pushed ends rule", slog.String("rule", rule), slog.String("searchKey", searchKey))
 }
 
-func (fun *InternalRuler) pullEndsRule(rule string) {
+func (fun *InternalRuler) pullEndsRule(rule string, owner Rule) {
 	searchKey := fun.endsSearchKeyFromRule(rule)
 
 	if _, ok := fun.ends[searchKey]; ok {
 		for i, r := range fun.ends[searchKey] {
 			if r == rule {
 				fun.ends[searchKey] = append(fun.ends[searchKey][:i], fun.ends[searchKey][i+1:]...)
+				fun.disown(indexEnds, rule, owner)
 
 				fun.logger.Debug("Pulled ends rule", slog.String("rule", rule), slog.String("searchKey", searchKey))
 				break
@@ -260,42 +647,160 @@ func (fun *InternalRuler) pullEndsRule(rule string) {
 	}
 }
 
-func (fun *InternalRuler) pushRegexRule(rule string) {
-	if fun.regex == "" {
-		fun.regex = rule
-	} else {
-		fun.regex = fmt.Sprintf("%s|%s", fun.regex, rule)
+// pushSuffixRule pushes the suffix of an ALL rule, limited in depth when requested.
+func (fun *InternalRuler) pushSuffixRule(rule string, modifiers Modifiers, owner Rule) {
+	if modifiers.Depth == 0 {
+		fun.pushEndsRule(rule, owner)
+		return
 	}
 
-	if fun.compiled_regexp == nil {
-		fun.compiled_regexp = regexp.MustCompile(fun.regex)
-	} else {
-		fun.compiled_regexp = regexp.MustCompile(fmt.Sprintf("%s|%s", fun.compiled_regexp.String(), rule))
+	searchKey := fun.endsSearchKeyFromRule(rule)
+
+	fun.depthEnds[searchKey] = append(fun.depthEnds[searchKey], depthRule{
+		suffix:   rule,
+		depth:    modifiers.Depth,
+		prefixes: fun.complementPrefixesOf(modifiers),
+		owner:    owner,
+	})
+
+	fun.logger.Debug("Pushed depth rule", slog.String("rule", rule), slog.Int("depth", modifiers.Depth), slog.String("searchKey", searchKey))
+}
+
+// pullSuffixRule pulls the suffix of an ALL rule, limited in depth when requested.
+func (fun *InternalRuler) pullSuffixRule(rule string, modifiers Modifiers, owner Rule) {
+	if modifiers.Depth == 0 {
+		fun.pullEndsRule(rule, owner)
+		return
 	}
 
+	searchKey := fun.endsSearchKeyFromRule(rule)
+
+	index := slices.IndexFunc(fun.depthEnds[searchKey], func(r depthRule) bool {
+		return r.suffix == rule && r.depth == modifiers.Depth && r.owner == owner
+	})
+
+	if index >= 0 {
+		fun.depthEnds[searchKey] = slices.Delete(fun.depthEnds[searchKey], index, index+1)
+
+		fun.logger.Debug("Pulled depth rule", slog.String("rule", rule), slog.Int("depth", modifiers.Depth), slog.String("searchKey", searchKey))
+	}
+}
+
+// matches checks if the given subject ends with the suffix of the rule, with
+// at most the allowed number of labels in front of it. A complement prefix
+// does not count as a label.
+func (r depthRule) matches(subject string) bool {
+	subject = StripComplementPrefix(subject, r.prefixes)
+
+	labels, found := strings.CutSuffix(subject, r.suffix)
+
+	if !found || labels == "" {
+		return false
+	}
+
+	return strings.Count(labels, ".")+1 <= r.depth
+}
+
+// regexOf returns the regular expression to push for the given REG record.
+func regexOf(record string, modifiers Modifiers) string {
+	if modifiers.CaseInsensitive {
+		return fmt.Sprintf("(?i:%s)", record)
+	}
+
+	return record
+}
+
+func (fun *InternalRuler) pushRegexRule(rule string, owner Rule) error {
+	compiled, err := regexp.Compile(rule)
+	if err != nil {
+		return err
+	}
+
+	regex := rule
+
+	if fun.regex != "" {
+		regex = fmt.Sprintf("%s|%s", fun.regex, rule)
+	}
+
+	compiledRegex, err := regexp.Compile(regex)
+	if err != nil {
+		return err
+	}
+
+	fun.regexRules = append(fun.regexRules, regexRule{pattern: rule, compiled: compiled, owner: owner})
+	fun.regex = regex
+	fun.compiled_regexp = compiledRegex
+
 	fun.logger.Debug("Pushed regex rule", slog.String("rule", rule), slog.String("regexp", fun.regex))
+
+	return nil
 }
 
-func (fun *InternalRuler) pullRegexRule(rule string) {
-	if fun.regex == "" {
+func (fun *InternalRuler) pullRegexRule(rule string, owner Rule) {
+	index := slices.IndexFunc(fun.regexRules, func(r regexRule) bool { return r.pattern == rule && r.owner == owner })
+
+	if index < 0 {
 		return
 	}
 
-	if fun.compiled_regexp == nil {
-		return
+	fun.regexRules = slices.Delete(fun.regexRules, index, index+1)
+
+	// The combined regular expression is rebuilt from the remaining ones, as
+	// cutting the pattern out of it could leave an empty (match-all) alternative.
+	var patterns []string
+
+	for _, r := range fun.regexRules {
+		patterns = append(patterns, r.pattern)
 	}
 
-	fun.regex = strings.ReplaceAll(fun.regex, rule, "")
+	fun.regex = strings.Join(patterns, "|")
+	fun.compiled_regexp = nil
 
-	if fun.regex == "" {
-		fun.compiled_regexp = nil
-	} else {
-		fun.compiled_regexp = regexp.MustCompile(fun.regex)
+	if fun.regex != "" {
+		// The remaining patterns were compiled together when they were pushed.
+		compiled, err := regexp.Compile(fun.regex)
+
+		if err != nil {
+			fun.logger.Error("Unable to rebuild the regex rules", slog.String("regexp", fun.regex), slog.String("error", err.Error()))
+		}
+
+		fun.compiled_regexp = compiled
 	}
 
 	fun.logger.Debug("Pulled regex rule", slog.String("rule", rule), slog.String("regexp", fun.regex))
 }
 
+func (fun *InternalRuler) pushPrefixRule(rule string, owner Rule) {
+	searchKey := fun.prefixSearchKeyFromRule(rule)
+
+	fun.prefixes[searchKey] = append(fun.prefixes[searchKey], rule)
+	fun.own(indexPrefixes, rule, owner)
+
+	fun.logger.Debug("Pushed prefix rule", slog.String("rule", rule), slog.String("searchKey", searchKey))
+}
+
+func (fun *InternalRuler) pullPrefixRule(rule string, owner Rule) {
+	searchKey := fun.prefixSearchKeyFromRule(rule)
+
+	if index := slices.Index(fun.prefixes[searchKey], rule); index >= 0 {
+		fun.prefixes[searchKey] = slices.Delete(fun.prefixes[searchKey], index, index+1)
+		fun.disown(indexPrefixes, rule, owner)
+
+		fun.logger.Debug("Pulled prefix rule", slog.String("rule", rule), slog.String("searchKey", searchKey))
+	}
+}
+
+// prefixSearchKeyFromRule returns the host (and port) of the given canonical URL.
+func (fun *InternalRuler) prefixSearchKeyFromRule(rule string) string {
+	canonical, err := canonicalURL(rule)
+
+	if err != nil {
+		return rule
+	}
+
+	return canonical.Host
+}
+
 func (fun *InternalRuler) HasFlag(flags []string, rule string) bool {
 	for _, flag := range flags {
 		if strings.HasPrefix(strings.TrimSpace(strings.ToLower(rule)), strings.ToLower(flag)) {
@@ -315,7 +820,7 @@ func (fun *InternalRuler) cleanupFlags(flags []string, rule string) string {
 	return rule
 }
 
-func (fun *InternalRuler) parseAllFlaggedRule(rule string) bool {
+func (fun *InternalRuler) parseAllFlaggedRule(rule string, modifiers Modifiers) bool {
 	if !fun.HasFlag(fun.FlagsAll, rule) {
 		fun.logger.Debug("Rule does not match the ALL flags, skipping", slog.String("rule", rule))
 
@@ -325,33 +830,29 @@ func (fun *InternalRuler) parseAllFlaggedRule(rule string) bool {
 
 	record := fun.cleanupFlags(fun.FlagsAll, rule)
 
+	owner := Rule{Kind: RuleKindAll, Value: record, Modifiers: modifiers}
+
 	if strings.HasPrefix(record, `.`) {
 		if strings.Count(record, ".") > 1 {
 			new_record := strings.TrimPrefix(record, ".")
 
-			if fun.handle_complement {
-				var complement_record string
-
-				if !strings.HasPrefix(new_record, "www.") {
-					complement_record = fmt.Sprintf("www.%s", new_record)
-				} else {
-					complement_record = strings.TrimPrefix(new_record, "www.")
-				}
-
-				fun.pushStrictRule(complement_record)
+			for _, complement_record := range Complements(new_record, fun.complementPrefixesOf(modifiers)) {
+				fun.pushStrictRule(complement_record, owner)
 			}
-			fun.pushStrictRule(new_record)
+			fun.pushStrictRule(new_record, owner)
 		}
-		fun.pushEndsRule(record)
+		fun.pushSuffixRule(record, modifiers, owner)
 	} else {
-		fun.pushEndsRule(fmt.Sprintf(".%s", record))
-		fun.pushStrictRule(record)
+		fun.pushSuffixRule(fmt.Sprintf(".%s", record), modifiers, owner)
+		fun.pushStrictRule(record, owner)
 	}
 
+	fun.rememberRule(owner)
+
 	return true
 }
 
-func (fun *InternalRuler) unparseAllFlaggedRule(rule string) bool {
+func (fun *InternalRuler) unparseAllFlaggedRule(rule string, modifiers Modifiers) bool {
 	if !fun.HasFlag(fun.FlagsAll, rule) {
 		fun.logger.Debug("Rule does not match the ALL flags, skipping", slog.String("rule", rule))
 
@@ -361,58 +862,68 @@ func (fun *InternalRuler) unparseAllFlaggedRule(rule string) bool {
 
 	record := fun.cleanupFlags(fun.FlagsAll, rule)
 
+	owner := Rule{Kind: RuleKindAll, Value: record, Modifiers: modifiers}
+
 	if strings.HasPrefix(record, `.`) {
 		if strings.Count(record, ".") > 1 {
 			new_record := strings.TrimPrefix(record, ".")
 
-			if fun.handle_complement {
-				var complement_record string
-
-				if !strings.HasPrefix(new_record, "www.") {
-					complement_record = fmt.Sprintf("www.%s", new_record)
-				} else {
-					complement_record = strings.TrimPrefix(new_record, "www.")
-				}
-
-				fun.pullStrictRule(complement_record)
+			for _, complement_record := range Complements(new_record, fun.complementPrefixesOf(modifiers)) {
+				fun.pullStrictRule(complement_record, owner)
 			}
-			fun.pullStrictRule(new_record)
+			fun.pullStrictRule(new_record, owner)
 		}
-		fun.pullEndsRule(record)
+		fun.pullSuffixRule(record, modifiers, owner)
 	} else {
 		// We except the record to starts with a dot.
-		fun.pullEndsRule(fmt.Sprintf(".%s", record))
-		fun.pullStrictRule(record)
+		fun.pullSuffixRule(fmt.Sprintf(".%s", record), modifiers, owner)
+		fun.pullStrictRule(record, owner)
 	}
 
+	fun.forgetRule(owner)
+
 	return true
 }
 
-func (fun *InternalRuler) parseRegexFlaggedRule(rule string) bool {
+func (fun *InternalRuler) parseRegexFlaggedRule(rule string, modifiers Modifiers) bool {
 	if !fun.HasFlag(fun.FlagsReg, rule) {
 		fun.logger.Debug("Rule does not match the REG flags, skipping", slog.String("rule", rule))
 		// Nothing to do.
 		return false
 	}
 
-	fun.pushRegexRule(fun.cleanupFlags(fun.FlagsReg, rule))
+	record := fun.cleanupFlags(fun.FlagsReg, rule)
+
+	owner := Rule{Kind: RuleKindReg, Value: record, Modifiers: modifiers}
+
+	if err := fun.pushRegexRule(regexOf(record, modifiers), owner); err != nil {
+		fun.logger.Warn("Invalid REG rule, skipping", slog.String("rule", rule), slog.String("error", err.Error()))
+		return false
+	}
+
+	fun.rememberRule(owner)
 
 	return true
 }
 
-func (fun *InternalRuler) unparseRegexFlaggedRule(rule string) bool {
+func (fun *InternalRuler) unparseRegexFlaggedRule(rule string, modifiers Modifiers) bool {
 	if !fun.HasFlag(fun.FlagsReg, rule) {
 		fun.logger.Debug("Rule does not match the REG flags, skipping", slog.String("rule", rule))
 		// Nothing to do.
 		return false
 	}
 
-	fun.pullRegexRule(fun.cleanupFlags(fun.FlagsReg, rule))
+	record := fun.cleanupFlags(fun.FlagsReg, rule)
+
+	owner := Rule{Kind: RuleKindReg, Value: record, Modifiers: modifiers}
+
+	fun.pullRegexRule(regexOf(record, modifiers), owner)
+	fun.forgetRule(owner)
 
 	return true
 }
 
-func (fun *InternalRuler) parseRZDBFlagedRule(rule string) bool {
+func (fun *InternalRuler) parseRZDBFlagedRule(rule string, modifiers Modifiers) bool {
 	if !fun.HasFlag(fun.FlagsRzdb, rule) {
 		fun.logger.Debug("Rule does not match the RZDB flags, skipping", slog.String("rule", rule))
 		// Nothing to do.
@@ -421,22 +932,31 @@ func (fun *InternalRuler) parseRZDBFlagedRule(rule string) bool {
 
 	record := fun.cleanupFlags(fun.FlagsRzdb, rule)
 
-	if fun.handle_complement && strings.HasPrefix(record, "www.") {
-		record = strings.TrimPrefix(record, "www.")
+	prefixes := fun.complementPrefixesOf(modifiers)
+	record = StripComplementPrefix(record, prefixes)
+
+	owner := Rule{Kind: RuleKindRzdb, Value: record, Modifiers: modifiers}
+
+	extensions, err := fun.getKnownExtensions()
+	if err != nil {
+		fun.logger.Error("Unable to expand RZDB rule", slog.String("rule", rule), slog.String("error", err.Error()))
+		return false
 	}
 
-	for _, extension := range fun.getKnownExtensions() {
-		fun.pushStrictRule(fmt.Sprintf("%s.%s", record, extension))
+	for _, extension := range extensions {
+		fun.pushStrictRule(fmt.Sprintf("%s.%s", record, extension), owner)
 
-		if fun.handle_complement {
-			fun.pushStrictRule(fmt.Sprintf("www.%s.%s", record, extension))
+		for _, prefix := range prefixes {
+			fun.pushStrictRule(fmt.Sprintf("%s%s.%s", prefix, record, extension), owner)
 		}
 	}
 
+	fun.rememberRule(owner)
+
 	return true
 }
 
-func (fun *InternalRuler) unparseRZDBFlagedRule(rule string) bool {
+func (fun *InternalRuler) unparseRZDBFlagedRule(rule string, modifiers Modifiers) bool {
 	if !fun.HasFlag(fun.FlagsRzdb, rule) {
 		fun.logger.Debug("Rule does not match the RZDB flags, skipping", slog.String("rule", rule))
 		// Nothing to do.
@@ -445,23 +965,140 @@ func (fun *InternalRuler) unparseRZDBFlagedRule(rule string) bool {
 
 	record := fun.cleanupFlags(fun.FlagsRzdb, rule)
 
-	if fun.handle_complement && strings.HasPrefix(record, "www.") {
-		record = strings.TrimPrefix(record, "www.")
+	prefixes := fun.complementPrefixesOf(modifiers)
+	record = StripComplementPrefix(record, prefixes)
+
+	owner := Rule{Kind: RuleKindRzdb, Value: record, Modifiers: modifiers}
+
+	extensions, err := fun.getKnownExtensions()
+	if err != nil {
+		fun.logger.Error("Unable to expand RZDB rule", slog.String("rule", rule), slog.String("error", err.Error()))
+		return false
 	}
 
-	for _, extension := range fun.getKnownExtensions() {
-		fun.pullStrictRule(fmt.Sprintf("%s.%s", record, extension))
+	for _, extension := range extensions {
+		fun.pullStrictRule(fmt.Sprintf("%s.%s", record, extension), owner)
 
-		if fun.handle_complement {
-			fun.pullStrictRule(fmt.Sprintf("www.%s.%s", record, extension))
+		for _, prefix := range prefixes {
+			fun.pullStrictRule(fmt.Sprintf("%s%s.%s", prefix, record, extension), owner)
 		}
 	}
 
+	fun.forgetRule(owner)
+
 	return true
 }
 
-func (fun *InternalRuler) parsePlainRule(rule string) bool {
-	if fun.handle_complement {
+// urlPrefixes returns the canonical prefixes covered by an URL rule, complement included.
+func (fun *InternalRuler) urlPrefixes(rule string, modifiers Modifiers) (string, []string, bool) {
+	record := fun.cleanupFlags(fun.FlagsURL, rule)
+
+	canonical, err := CanonicalizeURL(record)
+
+	if err != nil {
+		fun.logger.Debug("Invalid URL rule, skipping", slog.String("rule", rule), slog.String("error", err.Error()))
+		return "", nil, false
+	}
+
+	prefixes := append([]string{canonical}, ComplementURLs(canonical, fun.complementPrefixesOf(modifiers))...)
+
+	return canonical, prefixes, true
+}
+
+func (fun *InternalRuler) parseURLFlaggedRule(rule string, modifiers Modifiers) bool {
+	canonical, prefixes, ok := fun.urlPrefixes(rule, modifiers)
+
+	if !ok {
+		return false
+	}
+
+	owner := Rule{Kind: RuleKindURL, Value: canonical, Modifiers: modifiers}
+
+	for _, prefix := range prefixes {
+		fun.pushPrefixRule(prefix, owner)
+	}
+
+	fun.rememberRule(owner)
+
+	return true
+}
+
+func (fun *InternalRuler) unparseURLFlaggedRule(rule string, modifiers Modifiers) bool {
+	canonical, prefixes, ok := fun.urlPrefixes(rule, modifiers)
+
+	if !ok {
+		return false
+	}
+
+	owner := Rule{Kind: RuleKindURL, Value: canonical, Modifiers: modifiers}
+
+	for _, prefix := range prefixes {
+		fun.pullPrefixRule(prefix, owner)
+	}
+
+	fun.forgetRule(owner)
+
+	return true
+}
+
+// rootRecord returns the registrable domain covered by a ROOT rule.
+func (fun *InternalRuler) rootRecord(rule string) (string, bool) {
+	record := fun.cleanupFlags(fun.FlagsRoot, rule)
+
+	registrable, err := RegistrableDomain(record)
+
+	if err != nil {
+		fun.logger.Warn("Invalid ROOT rule, skipping", slog.String("rule", rule), slog.String("error", err.Error()))
+		return "", false
+	}
+
+	return registrable, true
+}
+
+func (fun *InternalRuler) parseRootFlaggedRule(rule string, modifiers Modifiers) bool {
+	record, ok := fun.rootRecord(rule)
+
+	if !ok {
+		return false
+	}
+
+	owner := Rule{Kind: RuleKindRoot, Value: record, Modifiers: modifiers}
+
+	for _, complement_record := range Complements(record, fun.complementPrefixesOf(modifiers)) {
+		fun.pushStrictRule(complement_record, owner)
+	}
+	fun.pushStrictRule(record, owner)
+	fun.pushSuffixRule(fmt.Sprintf(".%s", record), modifiers, owner)
+
+	fun.rememberRule(owner)
+
+	return true
+}
+
+func (fun *InternalRuler) unparseRootFlaggedRule(rule string, modifiers Modifiers) bool {
+	record, ok := fun.rootRecord(rule)
+
+	if !ok {
+		return false
+	}
+
+	owner := Rule{Kind: RuleKindRoot, Value: record, Modifiers: modifiers}
+
+	for _, complement_record := range Complements(record, fun.complementPrefixesOf(modifiers)) {
+		fun.pullStrictRule(complement_record, owner)
+	}
+	fun.pullStrictRule(record, owner)
+	fun.pullSuffixRule(fmt.Sprintf(".%s", record), modifiers, owner)
+
+	fun.forgetRule(owner)
+
+	return true
+}
+
+func (fun *InternalRuler) parsePlainRule(rule string, modifiers Modifiers) bool {
+	owner := Rule{Kind: RuleKindPlain, Value: rule, Modifiers: modifiers}
+
+	if prefixes := fun.complementPrefixesOf(modifiers); len(prefixes) > 0 {
 		if strings.HasPrefix(rule, "http://") || strings.HasPrefix(rule, "https://") {
 			netloc, err := ExtractNetLocationFromURL(rule)
 
@@ -470,27 +1107,26 @@ func (fun *InternalRuler) parsePlainRule(rule string) bool {
 				return false
 			}
 
-			if strings.HasPrefix(netloc, "www.") {
-				fun.pushStrictRule(strings.ReplaceAll(rule, netloc, strings.TrimPrefix(netloc, "www.")))
-			} else {
-				fun.pushStrictRule(strings.ReplaceAll(rule, netloc, fmt.Sprintf("www.%s", netloc)))
+			for _, complement := range Complements(netloc, prefixes) {
+				fun.pushStrictRule(strings.ReplaceAll(rule, netloc, complement), owner)
 			}
 		} else {
-			if strings.HasPrefix(rule, "www.") {
-				fun.pushStrictRule(strings.TrimPrefix(rule, "www."))
-			} else {
-				fun.pushStrictRule(fmt.Sprintf("www.%s", rule))
+			for _, complement := range Complements(rule, prefixes) {
+				fun.pushStrictRule(complement, owner)
 			}
 		}
 	}
 
-	fun.pushStrictRule(rule)
+	fun.pushStrictRule(rule, owner)
+	fun.rememberRule(owner)
 
 	return true
 }
 
-func (fun *InternalRuler) unparsePlainRule(rule string) bool {
-	if fun.handle_complement {
+func (fun *InternalRuler) unparsePlainRule(rule string, modifiers Modifiers) bool {
+	owner := Rule{Kind: RuleKindPlain, Value: rule, Modifiers: modifiers}
+
+	if prefixes := fun.complementPrefixesOf(modifiers); len(prefixes) > 0 {
 		if strings.HasPrefix(rule, "http://") || strings.HasPrefix(rule, "https://") {
 			netloc, err := ExtractNetLocationFromURL(rule)
 
@@ -499,21 +1135,18 @@ func (fun *InternalRuler) unparsePlainRule(rule string) bool {
 				return false
 			}
 
-			if strings.HasPrefix(netloc, "www.") {
-				fun.pullStrictRule(strings.ReplaceAll(rule, netloc, strings.TrimPrefix(netloc, "www.")))
-			} else {
-				fun.pullStrictRule(strings.ReplaceAll(rule, netloc, fmt.Sprintf("www.%s", netloc)))
+			for _, complement := range Complements(netloc, prefixes) {
+				fun.pullStrictRule(strings.ReplaceAll(rule, netloc, complement), owner)
 			}
 		} else {
-			if strings.HasPrefix(rule, "www.") {
-				fun.pullStrictRule(strings.TrimPrefix(rule, "www."))
-			} else {
-				fun.pullStrictRule(fmt.Sprintf("www.%s", rule))
+			for _, complement := range Complements(rule, prefixes) {
+				fun.pullStrictRule(complement, owner)
 			}
 		}
 	}
 
-	fun.pullStrictRule(rule)
+	fun.pullStrictRule(rule, owner)
+	fun.forgetRule(owner)
 
 	return true
 }
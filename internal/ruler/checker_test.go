@@ -16,16 +16,27 @@ limitations under the License.
 package ruler
 
 import (
+	"errors"
 	"log/slog"
 	"testing"
 )
 
+// testExtensions are the known extensions of the test rulers, so that the
+// RZDB rules are expanded without fetching the IANA and PSL lists.
+var testExtensions = []string{"de", "com", "org", "net", "saarland"}
+
 func testGetNewRuler() *InternalRuler {
-	return NewInternalRuler(false, slog.Default())
+	ruler := NewInternalRuler(false, slog.Default())
+	ruler.SetKnownExtensions(testExtensions)
+
+	return ruler
 }
 
 func testGetNewRulerWithComplementsHandling() *InternalRuler {
-	return NewInternalRuler(true, slog.Default())
+	ruler := NewInternalRuler(true, slog.Default())
+	ruler.SetKnownExtensions(testExtensions)
+
+	return ruler
 }
 
 func TestRuleHandling(t *testing.T) {
@@ -39,6 +50,9 @@ func TestRuleHandling(t *testing.T) {
 		{"# comment", false},
 		{"foo.example.com", true},
 		{"all,example.com", true},
+		{"ALL .com", false},
+		{"ALL[force] .com", true},
+		{"REG .*", false},
 	}
 
 	for _, test := range addRuleTests {
@@ -56,12 +70,13 @@ func TestRuleHandling(t *testing.T) {
 		{"# comment", false},
 		{"foo.example.com", true},
 		{"all,example.com", true},
+		{"ALL .com", true},
 	}
 
 	for _, test := range removeRuleTests {
-		result := ruler.RemoveRule(test.input)
-		if result != test.expected {
-			t.Errorf("RemoveRule(%q) = %v; want %v", test.input, result, test.expected)
+		result, err := ruler.RemoveRule(test.input)
+		if result != test.expected || err != nil {
+			t.Errorf("RemoveRule(%q) = %v, %v; want %v, nil", test.input, result, err, test.expected)
 		}
 	}
 }
@@ -71,9 +86,9 @@ func TestIsWhitelisted(t *testing.T) {
 
 	ruler.AddRule("foo.example.com")
 	ruler.AddRule("https://saarbrücken.saarland/foo/bar")
-	ruler.AddRule("ALL .org")
+	ruler.AddRule("ALL[force] .org")
 	ruler.AddRule("ALL .foo.saarlouis.de")
-	ruler.AddRule("ALL foo")
+	ruler.AddRule("ALL[force] foo")
 	ruler.AddRule("REG vöklingen.*")
 	ruler.AddRule("RZDB güter")
 
@@ -127,9 +142,9 @@ func TestIsWhitelistedWithComplements(t *testing.T) {
 
 	ruler.AddRule("foo.example.com")
 	ruler.AddRule("https://saarbrücken.saarland/foo/bar")
-	ruler.AddRule("ALL .org")
+	ruler.AddRule("ALL[force] .org")
 	ruler.AddRule("ALL .foo.saarlouis.de")
-	ruler.AddRule("ALL foo")
+	ruler.AddRule("ALL[force] foo")
 	ruler.AddRule("RZDB www.güter")
 
 	tests := []struct {
@@ -189,3 +204,416 @@ func TestHasFlag(t *testing.T) {
 		t.Errorf("HasFlag() = false; want true")
 	}
 }
+
+func TestIsWildcardWhitelisted(t *testing.T) {
+	ruler := testGetNewRuler()
+
+	ruler.AddRule("foo.example.com")
+	ruler.AddRule("ALL example.org")
+	ruler.AddRule("ALL .example.net")
+
+	tests := []struct {
+		subject  string
+		expected bool
+	}{
+		{"", false},
+		{"foo.example.com", false},
+		{"example.org", true},
+		{"bar.example.org", true},
+		{"example.net", true},
+		{"bar.example.net", true},
+		{"fooexample.org", false},
+	}
+
+	for _, test := range tests {
+		result := ruler.IsWildcardWhitelisted(test.subject)
+		if result != test.expected {
+			t.Errorf("IsWildcardWhitelisted(%q) = %v; want %v", test.subject, result, test.expected)
+		}
+	}
+}
+
+func TestRuleOrigin(t *testing.T) {
+	ruler := testGetNewRuler()
+
+	ruler.AddRuleWithOrigin("foo.example.com", "whitelist.list:1")
+	ruler.AddRuleWithOrigin("ALL .example.org", "whitelist.list:2")
+	ruler.AddRuleWithOrigin("ALL .example.org", "other.list:7")
+	ruler.AddRule("REG ^example")
+	ruler.AddRuleWithOrigin("ALL example.net", "whitelist.list:3")
+	ruler.RemoveRule("ALL example.net")
+
+	if added, err := ruler.AddRuleWithOrigin("", "whitelist.list:4"); added || err != nil {
+		t.Errorf("AddRuleWithOrigin(%q) = %v, %v; want false, nil", "", added, err)
+	}
+
+	if _, err := ruler.AddRuleWithOrigin("ALL .com", "whitelist.list:5"); err == nil {
+		t.Errorf("AddRuleWithOrigin(%q) = nil error; want the over-broad rule refused", "ALL .com")
+	}
+
+	tests := []struct {
+		rule     Rule
+		expected string
+	}{
+		{Rule{Kind: RuleKindPlain, Value: "foo.example.com"}, "whitelist.list:1"},
+		{Rule{Kind: RuleKindAll, Value: ".example.org"}, "whitelist.list:2"},
+		{Rule{Kind: RuleKindReg, Value: "^example"}, ""},
+		{Rule{Kind: RuleKindAll, Value: "example.net"}, ""},
+	}
+
+	for _, test := range tests {
+		result := ruler.Origin(test.rule)
+		if result != test.expected {
+			t.Errorf("Origin(%v) = %q; want %q", test.rule, result, test.expected)
+		}
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	ruler := testGetNewRuler()
+
+	ruler.AddRule("ALL .example.org")
+	ruler.Freeze()
+
+	if !ruler.IsFrozen() {
+		t.Fatalf("IsFrozen() = false; want true")
+	}
+
+	if ruler.AddRule("example.com") {
+		t.Errorf("AddRule(%q) on a frozen ruler = true; want false", "example.com")
+	}
+
+	if added, err := ruler.AddRuleWithOrigin("example.com", "whitelist.list:1"); added || !errors.Is(err, ErrFrozenRuler) {
+		t.Errorf("AddRuleWithOrigin(%q) on a frozen ruler = %v, %v; want false, ErrFrozenRuler", "example.com", added, err)
+	}
+
+	if removed, err := ruler.RemoveRule("ALL .example.org"); removed || !errors.Is(err, ErrFrozenRuler) {
+		t.Errorf("RemoveRule(%q) on a frozen ruler = %v, %v; want false, ErrFrozenRuler", "ALL .example.org", removed, err)
+	}
+
+	done := make(chan bool)
+
+	for range 8 {
+		go func() {
+			done <- ruler.IsWhitelisted("foo.example.org") && !ruler.IsWhitelisted("example.com")
+		}()
+	}
+
+	for range 8 {
+		if !<-done {
+			t.Errorf("IsWhitelisted() on a frozen ruler returned unexpected results")
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	ruler := testGetNewRuler()
+
+	ruler.AddRule("foo.example.com")
+	ruler.AddRule("ALL .example.org")
+	ruler.AddRule("REG ^example")
+	ruler.AddRule("ALL example.net")
+	ruler.RemoveRule("ALL example.net")
+	ruler.RemoveRule("bar.example.com")
+
+	expected := []Rule{
+		{Kind: RuleKindPlain, Value: "foo.example.com"},
+		{Kind: RuleKindAll, Value: ".example.org"},
+		{Kind: RuleKindReg, Value: "^example"},
+	}
+
+	result := ruler.Rules()
+
+	if len(result) != len(expected) {
+		t.Fatalf("Rules() = %v; want %v", result, expected)
+	}
+
+	for index := range expected {
+		if result[index] != expected[index] {
+			t.Errorf("Rules()[%d] = %v; want %v", index, result[index], expected[index])
+		}
+	}
+
+	if result[1].String() != "ALL .example.org" || result[0].String() != "foo.example.com" {
+		t.Errorf("String() = %q, %q; want %q, %q", result[1].String(), result[0].String(), "ALL .example.org", "foo.example.com")
+	}
+}
+
+func TestIsURLWhitelisted(t *testing.T) {
+	ruler := testGetNewRulerWithComplementsHandling()
+
+	ruler.AddRule("URL https://docs.example.com/shared/")
+	ruler.AddRule("URL:https://example.org/view?id=42")
+	ruler.AddRule("URL https://www.example.net:443/")
+	ruler.AddRule("URL https://example.net/blocked")
+	ruler.RemoveRule("URL https://example.net/blocked/")
+
+	tests := []struct {
+		subject  string
+		expected bool
+	}{
+		{"docs.example.com", false},
+		{"https://docs.example.com/", false},
+		{"https://docs.example.com/shared", true},
+		{"https://docs.example.com/shared/report.pdf", true},
+		{"https://DOCS.example.com:443/shared//a%7eb", true},
+		{"https://www.docs.example.com/shared/a", true},
+		{"http://docs.example.com/shared/a", false},
+		{"https://docs.example.com/sharedx", false},
+		{"https://example.org/view?id=42&lang=en", true},
+		{"https://example.org/view?id=43", false},
+		{"https://example.net/anything", true},
+		{"https://www.example.net/blocked/a", true},
+	}
+
+	for _, test := range tests {
+		result := ruler.IsWhitelisted(test.subject)
+		if result != test.expected {
+			t.Errorf("IsWhitelisted(%q) = %v; want %v", test.subject, result, test.expected)
+		}
+	}
+
+	if ruler.AddRule("URL ftp://example.com/") {
+		t.Errorf("AddRule(%q) = true; want false", "URL ftp://example.com/")
+	}
+}
+
+func TestIsWhitelistedCanonical(t *testing.T) {
+	ruler := testGetNewRuler()
+
+	ruler.AddRule("Example.COM.")
+	ruler.AddRule("ALL Saarbrücken.Saarland # comment")
+
+	tests := []struct {
+		subject  string
+		expected bool
+	}{
+		{"example.com", true},
+		{"Example.COM", true},
+		{"example.com.", true},
+		{"example.com:443", true},
+		{"EXAMPLE.com.:8080", true},
+		{"https://Example.COM/path", true},
+		{"foo.example.com", false},
+		{"xn--saarbrcken-feb.saarland", true},
+		{"WWW.SAARBRÜCKEN.saarland.", true},
+		{"example..com", false},
+	}
+
+	for _, test := range tests {
+		result := ruler.IsWhitelisted(test.subject)
+		if result != test.expected {
+			t.Errorf("IsWhitelisted(%q) = %v; want %v", test.subject, result, test.expected)
+		}
+	}
+
+	if ruler.AddRule("exa mple.com") {
+		t.Errorf("AddRule(%q) = true; want false", "exa mple.com")
+	}
+}
+
+func TestIsWhitelistedWithComplementPrefixes(t *testing.T) {
+	ruler := NewInternalRulerWithComplementPrefixes([]string{"www", "m.", "mobile.", "amp.", "www2.", "ww."}, slog.Default())
+
+	ruler.AddRule("example.com")
+	ruler.AddRule("m.example.org")
+	ruler.AddRule("ALL .foo.example.net")
+	ruler.AddRule("URL https://docs.example.com/shared")
+	ruler.AddRule("example.info")
+	ruler.RemoveRule("amp.example.info")
+
+	tests := []struct {
+		subject  string
+		expected bool
+	}{
+		{"example.com", true},
+		{"www.example.com", true},
+		{"m.example.com", true},
+		{"mobile.example.com", true},
+		{"amp.example.com", true},
+		{"www2.example.com", true},
+		{"ww.example.com", true},
+		{"wwww.example.com", false},
+		{"m.www.example.com", false},
+		{"example.org", true},
+		{"www.example.org", true},
+		{"amp.foo.example.net", true},
+		{"foo.example.net", true},
+		{"bar.foo.example.net", true},
+		{"https://mobile.docs.example.com/shared/a", true},
+		{"example.info", false},
+		{"www.example.info", false},
+	}
+
+	for _, test := range tests {
+		result := ruler.IsWhitelisted(test.subject)
+		if result != test.expected {
+			t.Errorf("IsWhitelisted(%q) = %v; want %v", test.subject, result, test.expected)
+		}
+	}
+}
+
+func TestIsWhitelistedWithModifiers(t *testing.T) {
+	ruler := testGetNewRulerWithComplementsHandling()
+
+	ruler.AddRule("ALL[depth=1] example.com")
+	ruler.AddRule("ALL .example.org[depth=2]")
+	ruler.AddRule("example.net[complement=off]")
+	ruler.AddRule("REG[icase] ^ADS\\.")
+	ruler.AddRule("ALL[depth=1] example.info")
+	ruler.RemoveRule("ALL[depth=1] example.info")
+
+	tests := []struct {
+		subject  string
+		expected bool
+	}{
+		{"example.com", true},
+		{"a.example.com", true},
+		{"www.a.example.com", true},
+		{"a.b.example.com", false},
+		{"example.org", true},
+		{"a.b.example.org", true},
+		{"a.b.c.example.org", false},
+		{"example.net", true},
+		{"www.example.net", false},
+		{"ads.example.biz", true},
+		{"example.info", false},
+		{"a.example.info", false},
+	}
+
+	for _, test := range tests {
+		result := ruler.IsWhitelisted(test.subject)
+		if result != test.expected {
+			t.Errorf("IsWhitelisted(%q) = %v; want %v", test.subject, result, test.expected)
+		}
+	}
+
+	if ruler.IsWildcardWhitelisted("example.com") {
+		t.Errorf("IsWildcardWhitelisted(%q) = true; want false", "example.com")
+	}
+
+	if ruler.AddRule("ALL[icase] example.com") {
+		t.Errorf("AddRule(%q) = true; want false", "ALL[icase] example.com")
+	}
+
+	expected := []Rule{
+		{Kind: RuleKindAll, Value: "example.com", Modifiers: Modifiers{Depth: 1}},
+		{Kind: RuleKindAll, Value: ".example.org", Modifiers: Modifiers{Depth: 2}},
+		{Kind: RuleKindPlain, Value: "example.net", Modifiers: Modifiers{Complement: ComplementOff}},
+		{Kind: RuleKindReg, Value: "^ADS\\.", Modifiers: Modifiers{CaseInsensitive: true}},
+	}
+
+	result := ruler.Rules()
+
+	if len(result) != len(expected) {
+		t.Fatalf("Rules() = %v; want %v", result, expected)
+	}
+
+	for index := range expected {
+		if result[index] != expected[index] {
+			t.Errorf("Rules()[%d] = %v; want %v", index, result[index], expected[index])
+		}
+	}
+}
+
+func TestIsWhitelistedRoot(t *testing.T) {
+	ruler := testGetNewRulerWithComplementsHandling()
+
+	ruler.AddRule("ROOT www.shop.example.co.uk")
+	ruler.AddRule("ROOT[depth=1] example.org")
+	ruler.AddRule("ROOT example.net")
+	ruler.RemoveRule("ROOT a.example.net")
+
+	tests := []struct {
+		subject  string
+		expected bool
+	}{
+		{"example.co.uk", true},
+		{"www.example.co.uk", true},
+		{"shop.example.co.uk", true},
+		{"a.b.shop.example.co.uk", true},
+		{"example2.co.uk", false},
+		{"co.uk", false},
+		{"example.org", true},
+		{"a.example.org", true},
+		{"www.a.example.org", true},
+		{"a.b.example.org", false},
+		{"example.net", false},
+		{"a.example.net", false},
+	}
+
+	for _, test := range tests {
+		result := ruler.IsWhitelisted(test.subject)
+		if result != test.expected {
+			t.Errorf("IsWhitelisted(%q) = %v; want %v", test.subject, result, test.expected)
+		}
+	}
+
+	if ruler.AddRule("ROOT co.uk") {
+		t.Errorf("AddRule(%q) = true; want false", "ROOT co.uk")
+	}
+
+	if ruler.IsWhitelisted("co.uk") {
+		t.Errorf("IsWhitelisted(%q) = true; want false", "co.uk")
+	}
+
+	expected := []Rule{
+		{Kind: RuleKindRoot, Value: "example.co.uk"},
+		{Kind: RuleKindRoot, Value: "example.org", Modifiers: Modifiers{Depth: 1}},
+	}
+
+	result := ruler.Rules()
+
+	if len(result) != len(expected) {
+		t.Fatalf("Rules() = %v; want %v", result, expected)
+	}
+
+	for index := range expected {
+		if result[index] != expected[index] {
+			t.Errorf("Rules()[%d] = %v; want %v", index, result[index], expected[index])
+		}
+	}
+}
+
+func TestWhitelistingRule(t *testing.T) {
+	ruler := testGetNewRulerWithComplementsHandling()
+
+	ruler.AddRule("example.com")
+	ruler.AddRule("ALL example.org")
+	ruler.AddRule("ALL[depth=1] example.net")
+	ruler.AddRule("REG ^ads\\.")
+	ruler.AddRule("REG[icase] ^TRACK\\.")
+	ruler.AddRule("URL https://docs.example.info/shared")
+	ruler.AddRule("ALL foo.example.org")
+	ruler.AddRule("REG ^gone\\.")
+	ruler.RemoveRule("REG ^gone\\.")
+
+	tests := []struct {
+		subject     string
+		expected    Rule
+		whitelisted bool
+	}{
+		{"www.example.com", Rule{Kind: RuleKindPlain, Value: "example.com"}, true},
+		{"a.foo.example.org", Rule{Kind: RuleKindAll, Value: "example.org"}, true},
+		{"a.example.net", Rule{Kind: RuleKindAll, Value: "example.net", Modifiers: Modifiers{Depth: 1}}, true},
+		{"ads.example.biz", Rule{Kind: RuleKindReg, Value: "^ads\\."}, true},
+		{"www.track.example.biz", Rule{Kind: RuleKindReg, Value: "^TRACK\\.", Modifiers: Modifiers{CaseInsensitive: true}}, true},
+		{"https://docs.example.info/shared/a.pdf", Rule{Kind: RuleKindURL, Value: "https://docs.example.info/shared"}, true},
+		{"gone.example.biz", Rule{}, false},
+		{"example.biz", Rule{}, false},
+	}
+
+	for _, test := range tests {
+		result, whitelisted := ruler.WhitelistingRule(test.subject)
+
+		if whitelisted != test.whitelisted || result != test.expected {
+			t.Errorf("WhitelistingRule(%q) = %v, %v; want %v, %v", test.subject, result, whitelisted, test.expected, test.whitelisted)
+		}
+	}
+
+	ruler.RemoveRule("example.com")
+
+	if result, whitelisted := ruler.WhitelistingRule("example.com"); whitelisted {
+		t.Errorf("WhitelistingRule(%q) = %v, true; want false after removal", "example.com", result)
+	}
+}
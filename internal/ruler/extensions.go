@@ -0,0 +1,83 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/funilrys/givilsta/internal/data"
+)
+
+// sharedExtensions holds the known extensions, fetched once and shared by all
+// the rulers of the process.
+var sharedExtensions struct {
+	sync.Mutex
+	extensions []string
+}
+
+// loadSharedExtensions returns the known extensions (IANA and PSL), fetching
+// them until a fetch succeeds.
+//
+// Returns:
+//
+//	[]string: The known extensions. The list is shared: it must not be modified.
+//	error: An error if the extensions cannot be fetched.
+func loadSharedExtensions() ([]string, error) {
+	sharedExtensions.Lock()
+	defer sharedExtensions.Unlock()
+
+	if sharedExtensions.extensions != nil {
+		return sharedExtensions.extensions, nil
+	}
+
+	iana, err := data.NewIANAExtensions()
+	if err != nil {
+		return nil, err
+	}
+
+	psl, err := data.NewPSLExtensions()
+	if err != nil {
+		return nil, err
+	}
+
+	sharedExtensions.extensions = append(append([]string{}, iana.Extensions...), psl.Suffixes...)
+
+	return sharedExtensions.extensions, nil
+}
+
+// SetKnownExtensions sets the extensions the RZDB rules are expanded with,
+// instead of fetching them from the IANA and the PSL.
+//
+// Args:
+//
+//	extensions: The known extensions (e.g. "com", "co.uk").
+func (fun *InternalRuler) SetKnownExtensions(extensions []string) {
+	fun.extensions = append([]string{}, extensions...)
+}
+
+func (fun *InternalRuler) getKnownExtensions() ([]string, error) {
+	if len(fun.extensions) == 0 {
+		extensions, err := loadSharedExtensions()
+		if err != nil {
+			return nil, fmt.Errorf("loading the known extensions: %w", err)
+		}
+
+		fun.extensions = extensions
+	}
+
+	return fun.extensions, nil
+}
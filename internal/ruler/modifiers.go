@@ -0,0 +1,209 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// modifierPrefixRegex matches a flag followed by a modifier block and a separator (e.g. "ALL[depth=1] ").
+var modifierPrefixRegex = regexp.MustCompile(`(?i)^(ALL|REG|RZDB|RZD|URL|ROOT)\[([^\]]*)\]([ \t:#,@])`)
+
+// modifierSuffixRegex matches a modifier block at the end of a rule (e.g. "example.com[complement=off]").
+var modifierSuffixRegex = regexp.MustCompile(`^(.*[^\s\[])\[([^\[\]]*)\]$`)
+
+// modifierKeys are the keys accepted in a modifier block, with the kinds of rules they apply to.
+var modifierKeys = map[string][]string{
+	"depth":      {RuleKindAll, RuleKindRoot},
+	"complement": {RuleKindPlain, RuleKindAll, RuleKindRzdb, RuleKindURL, RuleKindRoot},
+	"icase":      {RuleKindReg},
+	"force":      {RuleKindPlain, RuleKindAll, RuleKindReg, RuleKindRzdb, RuleKindURL, RuleKindRoot},
+}
+
+// ExtractModifiers extracts the modifier block of the given rule. The block can
+// follow the flag (e.g. "ALL[depth=1] example.com", "REG[icase] ^ads") or end
+// the rule (e.g. "example.com[complement=off]"), except for the REG rules whose
+// brackets belong to the regular expression.
+//
+// Args:
+//
+//	rule: The rule to extract the modifiers from.
+//
+// Returns:
+//
+//	string: The rule without its modifier block.
+//	Modifiers: The modifiers of the rule.
+//	error: An error if the modifier block is invalid.
+func ExtractModifiers(rule string) (string, Modifiers, error) {
+	rule = stripInlineComment(rule)
+
+	if match := modifierPrefixRegex.FindStringSubmatch(rule); match != nil {
+		kind := ruleKindOfFlag(match[1])
+		modifiers, err := parseModifiers(match[2], kind)
+
+		if err != nil {
+			return "", Modifiers{}, err
+		}
+
+		return match[1] + match[3] + rule[len(match[0]):], modifiers, nil
+	}
+
+	kind := RuleKindPlain
+
+	if match := ruleFlagRegex.FindStringSubmatch(rule); match != nil {
+		kind = ruleKindOfFlag(match[1])
+	}
+
+	if kind == RuleKindReg {
+		return rule, Modifiers{}, nil
+	}
+
+	match := modifierSuffixRegex.FindStringSubmatch(rule)
+
+	if match == nil || !isModifierBlock(match[2]) {
+		// Brackets which are not a modifier block belong to the entry (e.g. an URL).
+		return rule, Modifiers{}, nil
+	}
+
+	modifiers, err := parseModifiers(match[2], kind)
+
+	if err != nil {
+		return "", Modifiers{}, err
+	}
+
+	return match[1], modifiers, nil
+}
+
+// ruleKindOfFlag returns the kind of rule of the given flag (without separator).
+func ruleKindOfFlag(flag string) string {
+	switch strings.ToUpper(flag) {
+	case "ALL":
+		return RuleKindAll
+	case "REG":
+		return RuleKindReg
+	case "RZD", "RZDB":
+		return RuleKindRzdb
+	case "URL":
+		return RuleKindURL
+	case "ROOT":
+		return RuleKindRoot
+	}
+
+	return RuleKindPlain
+}
+
+// isModifierBlock checks if all the items of the given block have a known key.
+func isModifierBlock(block string) bool {
+	for _, item := range strings.Split(block, ",") {
+		key, _, _ := strings.Cut(item, "=")
+
+		if _, ok := modifierKeys[strings.ToLower(strings.TrimSpace(key))]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseModifiers parses the given modifier block of a rule of the given kind.
+func parseModifiers(block string, kind string) (Modifiers, error) {
+	var modifiers Modifiers
+
+	if strings.TrimSpace(block) == "" {
+		return modifiers, nil
+	}
+
+	for _, item := range strings.Split(block, ",") {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(item), "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.ToLower(strings.TrimSpace(value))
+
+		kinds, ok := modifierKeys[key]
+
+		if !ok {
+			return Modifiers{}, fmt.Errorf("unknown modifier %q", key)
+		}
+
+		if !slices.Contains(kinds, kind) {
+			return Modifiers{}, fmt.Errorf("modifier %q can't be used on %s rules", key, kind)
+		}
+
+		switch key {
+		case "depth":
+			depth, err := strconv.Atoi(value)
+
+			if err != nil || depth < 1 {
+				return Modifiers{}, fmt.Errorf("invalid depth %q, expected a positive integer", value)
+			}
+
+			modifiers.Depth = depth
+		case "complement":
+			switch value {
+			case "on", "true", "yes":
+				modifiers.Complement = ComplementOn
+			case "off", "false", "no":
+				modifiers.Complement = ComplementOff
+			default:
+				return Modifiers{}, fmt.Errorf("invalid complement %q, expected on or off", value)
+			}
+		case "icase":
+			if hasValue {
+				return Modifiers{}, fmt.Errorf("modifier %q does not take a value", key)
+			}
+
+			modifiers.CaseInsensitive = true
+		case "force":
+			if hasValue {
+				return Modifiers{}, fmt.Errorf("modifier %q does not take a value", key)
+			}
+
+			modifiers.Force = true
+		}
+	}
+
+	return modifiers, nil
+}
+
+// IsZero checks if no modifier is set.
+func (m Modifiers) IsZero() bool {
+	return m == Modifiers{}
+}
+
+// String returns the modifiers as they would be written in a modifier block, without brackets.
+func (m Modifiers) String() string {
+	var items []string
+
+	if m.Depth > 0 {
+		items = append(items, fmt.Sprintf("depth=%d", m.Depth))
+	}
+
+	if m.Complement != "" {
+		items = append(items, fmt.Sprintf("complement=%s", m.Complement))
+	}
+
+	if m.CaseInsensitive {
+		items = append(items, "icase")
+	}
+
+	if m.Force {
+		items = append(items, "force")
+	}
+
+	return strings.Join(items, ",")
+}
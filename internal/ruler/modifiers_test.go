@@ -0,0 +1,89 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import "testing"
+
+func TestExtractModifiers(t *testing.T) {
+	tests := []struct {
+		input     string
+		rule      string
+		modifiers Modifiers
+		valid     bool
+	}{
+		{"example.com", "example.com", Modifiers{}, true},
+		{"ALL example.com", "ALL example.com", Modifiers{}, true},
+		{"ALL[depth=1] example.com", "ALL example.com", Modifiers{Depth: 1}, true},
+		{"ROOT[depth=2,complement=off] www.example.co.uk", "ROOT www.example.co.uk", Modifiers{Depth: 2, Complement: ComplementOff}, true},
+		{"ROOT[icase] example.co.uk", "", Modifiers{}, false},
+		{"all[DEPTH=2]:.example.com", "all:.example.com", Modifiers{Depth: 2}, true},
+		{"ALL[depth=1]#example.com # comment", "ALL#example.com", Modifiers{Depth: 1}, true},
+		{"ALL example.com[depth=3,complement=off]", "ALL example.com", Modifiers{Depth: 3, Complement: ComplementOff}, true},
+		{"example.com[complement=off]", "example.com", Modifiers{Complement: ComplementOff}, true},
+		{"example.com[complement=on] # comment", "example.com", Modifiers{Complement: ComplementOn}, true},
+		{"RZDB[complement=off] example", "RZDB example", Modifiers{Complement: ComplementOff}, true},
+		{"URL[complement=on] https://example.com/a", "URL https://example.com/a", Modifiers{Complement: ComplementOn}, true},
+		{"REG[icase] ^Ads[0-9]\\.", "REG ^Ads[0-9]\\.", Modifiers{CaseInsensitive: true}, true},
+		{"REG ^ads[0-9]", "REG ^ads[0-9]", Modifiers{}, true},
+		{"REG ^ads[icase]", "REG ^ads[icase]", Modifiers{}, true},
+		{"https://example.com/a[1]", "https://example.com/a[1]", Modifiers{}, true},
+		{"ALL[] example.com", "ALL example.com", Modifiers{}, true},
+		{"ALL[depth=0] example.com", "", Modifiers{}, false},
+		{"ALL[depth=a] example.com", "", Modifiers{}, false},
+		{"ALL[icase] example.com", "", Modifiers{}, false},
+		{"REG[depth=1] ^ads", "", Modifiers{}, false},
+		{"REG[complement=off] ^ads", "", Modifiers{}, false},
+		{"REG[icase=yes] ^ads", "", Modifiers{}, false},
+		{"REG[force] .*", "REG .*", Modifiers{Force: true}, true},
+		{"ALL .com[force=yes]", "", Modifiers{}, false},
+		{"ALL[foo] example.com", "", Modifiers{}, false},
+		{"example.com[depth=1]", "", Modifiers{}, false},
+		{"example.com[complement=maybe]", "", Modifiers{}, false},
+	}
+
+	for _, test := range tests {
+		rule, modifiers, err := ExtractModifiers(test.input)
+
+		if (err == nil) != test.valid {
+			t.Errorf("ExtractModifiers(%q) error = %v; want valid: %v", test.input, err, test.valid)
+			continue
+		}
+
+		if rule != test.rule || modifiers != test.modifiers {
+			t.Errorf("ExtractModifiers(%q) = %q, %+v; want %q, %+v", test.input, rule, modifiers, test.rule, test.modifiers)
+		}
+	}
+}
+
+func TestModifiersString(t *testing.T) {
+	tests := []struct {
+		rule     Rule
+		expected string
+	}{
+		{Rule{Kind: RuleKindPlain, Value: "example.com"}, "example.com"},
+		{Rule{Kind: RuleKindPlain, Value: "example.com", Modifiers: Modifiers{Complement: ComplementOff}}, "example.com[complement=off]"},
+		{Rule{Kind: RuleKindAll, Value: "example.com", Modifiers: Modifiers{Depth: 1, Complement: ComplementOn}}, "ALL[depth=1,complement=on] example.com"},
+		{Rule{Kind: RuleKindReg, Value: "^ads", Modifiers: Modifiers{CaseInsensitive: true}}, "REG[icase] ^ads"},
+		{Rule{Kind: RuleKindAll, Value: ".com", Modifiers: Modifiers{Depth: 1, Force: true}}, "ALL[depth=1,force] .com"},
+	}
+
+	for _, test := range tests {
+		result := test.rule.String()
+		if result != test.expected {
+			t.Errorf("String() = %q; want %q", result, test.expected)
+		}
+	}
+}
@@ -17,11 +17,14 @@ package ruler
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
 )
 
 // idnazeString converts a subject to its IDNA ASCII representation.
@@ -111,8 +114,154 @@ func idnaze(s string) (string, error) {
 	return idnazeString(s), nil
 }
 
-// NormalizeURL normalizes a URL by converting its network location to IDNA ASCII representation.
-// It handles both "http://" and "https://" prefixes, and returns the original URL if it cannot be normalized.
+// domainProfile is the UTS-46 profile used to canonicalize the domains.
+// Unlike idna.Lookup, it tolerates the underscores and the hyphens at any
+// position as they are common in blocklists (e.g. "r1---sn-abc.googlevideo.com").
+var domainProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.CheckHyphens(false),
+	idna.VerifyDNSLength(true),
+	idna.BidiRule(),
+)
+
+// labelRegex matches the characters allowed in an ASCII label.
+var labelRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// ruleFlagRegex matches the flag (and its separator) at the beginning of a rule.
+var ruleFlagRegex = regexp.MustCompile(`(?i)^(ALL|REG|RZDB|RZD|URL|ROOT)[ \t:#,@]\s*`)
+
+// maxLabelLength is the maximum length of a DNS label.
+const maxLabelLength = 63
+
+// CanonicalizeDomain converts a domain into its canonical form: lowercased,
+// UTS-46 mapped and converted to its IDNA ASCII (A-label) representation,
+// without trailing dot nor port. IP addresses are returned lowercased.
+//
+// Args:
+//
+//	domain: The domain to canonicalize.
+//
+// Returns:
+//
+//	string: The canonical domain.
+//	error: An error if the domain is not a syntactically valid name.
+func CanonicalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSpace(domain)
+
+	if domain == "" {
+		return "", fmt.Errorf("domain cannot be empty")
+	}
+
+	if net.ParseIP(domain) != nil {
+		return strings.ToLower(domain), nil
+	}
+
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+
+		if net.ParseIP(domain) != nil {
+			return strings.ToLower(domain), nil
+		}
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+
+	result, err := domainProfile.ToASCII(domain)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %q: %w", domain, err)
+	}
+
+	for _, label := range strings.Split(result, ".") {
+		if len(label) > maxLabelLength || !labelRegex.MatchString(label) {
+			return "", fmt.Errorf("invalid domain %q: invalid label %q", domain, label)
+		}
+	}
+
+	return result, nil
+}
+
+// ToUnicodeDomain converts a domain into its canonical Unicode (U-label) representation.
+//
+// Args:
+//
+//	domain: The domain to convert.
+//
+// Returns:
+//
+//	string: The domain in its U-label form.
+//	error: An error if the domain is not a syntactically valid name.
+func ToUnicodeDomain(domain string) (string, error) {
+	canonical, err := CanonicalizeDomain(domain)
+
+	if err != nil {
+		return "", err
+	}
+
+	return domainProfile.ToUnicode(canonical)
+}
+
+// RegistrableDomain returns the registrable domain (eTLD+1) of the given
+// domain, according to the Public Suffix List.
+//
+// Args:
+//
+//	domain: The domain to get the registrable domain of.
+//
+// Returns:
+//
+//	string: The registrable domain (e.g. "example.co.uk" for "www.shop.example.co.uk").
+//	error: An error if the domain is invalid, an IP address or a public suffix.
+func RegistrableDomain(domain string) (string, error) {
+	canonical, err := CanonicalizeDomain(domain)
+
+	if err != nil {
+		return "", err
+	}
+
+	if net.ParseIP(canonical) != nil {
+		return "", fmt.Errorf("an IP address has no registrable domain: %s", canonical)
+	}
+
+	return publicsuffix.EffectiveTLDPlusOne(canonical)
+}
+
+// isURL checks if the given subject or rule is an URL.
+func isURL(subject string) bool {
+	return strings.Contains(subject, "://") || strings.HasPrefix(subject, "//")
+}
+
+// stripInlineComment removes the comment of the given subject or rule. A "#" only
+// starts a comment at the beginning or after a whitespace, as it is also one of
+// the flag separators (e.g. "ALL#example.com").
+func stripInlineComment(subject string) string {
+	subject = strings.TrimSpace(subject)
+
+	if strings.HasPrefix(subject, "#") {
+		return ""
+	}
+
+	for index := strings.Index(subject, "#"); index > 0; {
+		if subject[index-1] == ' ' || subject[index-1] == '\t' {
+			return strings.TrimSpace(subject[:index])
+		}
+
+		next := strings.Index(subject[index+1:], "#")
+
+		if next < 0 {
+			break
+		}
+
+		index += next + 1
+	}
+
+	return subject
+}
+
+// NormalizeURL normalizes a URL by canonicalizing its network location.
+// It returns the original URL if it cannot be normalized.
 //
 // Args:
 //
@@ -124,17 +273,17 @@ func idnaze(s string) (string, error) {
 func NormalizeURL(urlStr string) string {
 	netloc, err := ExtractNetLocationFromURL(urlStr)
 
-	if err != nil {
+	if err != nil || netloc == "" {
 		return urlStr
 	}
 
-	idnazedNetloc, err := idnaze(netloc)
+	canonicalNetloc, err := CanonicalizeDomain(netloc)
 
 	if err != nil {
 		return urlStr
 	}
 
-	return strings.Replace(urlStr, netloc, idnazedNetloc, 1)
+	return strings.Replace(urlStr, netloc, canonicalNetloc, 1)
 }
 
 // NormalizeSubject normalizes a subject for further processing.
@@ -148,35 +297,37 @@ func NormalizeURL(urlStr string) string {
 //
 //	A normalized subject string, or an empty string if the subject is invalid.
 func NormalizeSubject(subject string, complementHandling bool) string {
-	subject = strings.TrimSpace(subject)
+	if complementHandling {
+		return normalizeSubject(subject, DefaultComplementPrefixes)
+	}
+
+	return normalizeSubject(subject, nil)
+}
 
-	if subject == "" || strings.HasPrefix(subject, "#") {
+// normalizeSubject normalizes a subject and removes its complement prefix.
+func normalizeSubject(subject string, complementPrefixes []string) string {
+	subject = stripInlineComment(subject)
+
+	if subject == "" {
 		return ""
 	}
 
-	if strings.HasPrefix(subject, "http://") || strings.HasPrefix(subject, "https://") {
+	if isURL(subject) {
 		return NormalizeURL(subject)
 	}
 
-	if strings.Contains(subject, "#") {
-		subject = strings.TrimSpace(subject[:strings.Index(subject, "#")-1])
-	}
-
-	idnazedSubject, err := idnaze(subject)
+	canonicalSubject, err := CanonicalizeDomain(subject)
 
 	if err != nil {
-		idnazedSubject = subject
-	}
-
-	if complementHandling {
-		idnazedSubject = strings.TrimPrefix(idnazedSubject, "www.")
+		return ""
 	}
 
-	return idnazedSubject
+	return StripComplementPrefix(canonicalSubject, complementPrefixes)
 }
 
 // NormalizeRule normalizes a rule for further processing.
-// It handles both URLs and plain text rules, converting them to IDNA ASCII representation.
+// The domain of the rule is canonicalized the same way as the subjects, the
+// regular expressions are only converted to their IDNA ASCII representation.
 //
 // Args:
 //
@@ -185,27 +336,61 @@ func NormalizeSubject(subject string, complementHandling bool) string {
 // Returns:
 // A normalized rule string, or an empty string if the rule is invalid.
 func NormalizeRule(rule string) string {
-	rule = strings.TrimSpace(rule)
+	rule = stripInlineComment(rule)
 
-	if rule == "" || strings.HasPrefix(rule, "#") {
+	if rule == "" {
 		return ""
 	}
 
-	if strings.HasPrefix(rule, "http://") || strings.HasPrefix(rule, "https://") {
+	if isURL(rule) {
 		return NormalizeURL(rule)
 	}
 
-	if strings.Contains(rule, "#") {
-		rule = strings.TrimSpace(rule[:strings.Index(rule, "#")-1])
+	var flag, kind string
+
+	if match := ruleFlagRegex.FindStringSubmatch(rule); match != nil {
+		flag, kind = match[0], strings.ToUpper(match[1])
 	}
 
-	idnazedRule, err := idnaze(rule)
+	value := strings.TrimPrefix(rule, flag)
 
-	if err != nil {
+	switch kind {
+	case "REG":
+		idnazedValue, err := idnaze(value)
+
+		if err != nil {
+			return rule
+		}
+
+		return flag + idnazedValue
+	case "URL":
 		return rule
+	case "ROOT":
+		// The registrable domain is computed later, the leading dot is meaningless.
+		value = strings.TrimPrefix(value, ".")
+	case "ALL":
+		dot := ""
+
+		if strings.HasPrefix(value, ".") {
+			dot, value = ".", value[1:]
+		}
+
+		canonicalValue, err := CanonicalizeDomain(value)
+
+		if err != nil {
+			return ""
+		}
+
+		return flag + dot + canonicalValue
+	}
+
+	canonicalValue, err := CanonicalizeDomain(value)
+
+	if err != nil {
+		return ""
 	}
 
-	return idnazedRule
+	return flag + canonicalValue
 }
 
 // ExtractNetLocationFromURL extracts the network location (host) from a given URL.
@@ -257,3 +442,258 @@ func ExtractNetLocationFromURL(rawURL string) (string, error) {
 
 	return result, nil
 }
+
+// defaultPorts maps the URL schemes to their default port.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// canonicalURL parses and canonicalizes the given URL. The scheme and host are
+// lowercased, the host is converted to its IDNA ASCII representation, the
+// default port, the trailing slashes and the fragment are dropped, the
+// percent-encoding of the path is normalized and the query parameters are sorted.
+func canonicalURL(rawURL string) (*url.URL, error) {
+	urlObj, err := url.Parse(strings.TrimSpace(rawURL))
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+
+	scheme := strings.ToLower(urlObj.Scheme)
+
+	if _, ok := defaultPorts[scheme]; !ok {
+		return nil, fmt.Errorf("unsupported URL scheme: %q", urlObj.Scheme)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(urlObj.Hostname()), ".")
+
+	if host == "" {
+		return nil, fmt.Errorf("URL has no host: %s", rawURL)
+	}
+
+	host, err = CanonicalizeDomain(host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Contains(host, ":") {
+		// IPv6 addresses have to be enclosed in brackets.
+		host = fmt.Sprintf("[%s]", host)
+	}
+
+	if port := urlObj.Port(); port != "" && port != defaultPorts[scheme] {
+		host = fmt.Sprintf("%s:%s", host, port)
+	}
+
+	query := ""
+
+	if urlObj.RawQuery != "" {
+		values, err := url.ParseQuery(urlObj.RawQuery)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse URL query: %w", err)
+		}
+
+		query = values.Encode()
+	}
+
+	return &url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     strings.TrimRight(urlObj.Path, "/"),
+		RawQuery: query,
+	}, nil
+}
+
+// CanonicalizeURL canonicalizes the given URL so that equivalent URLs can be
+// compared as strings.
+//
+// Args:
+//
+//	rawURL: The URL to canonicalize.
+//
+// Returns:
+//
+//	string: The canonical URL.
+//	error: An error if the URL is not a valid HTTP(S) URL.
+func CanonicalizeURL(rawURL string) (string, error) {
+	canonical, err := canonicalURL(rawURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	return canonical.String(), nil
+}
+
+// MatchURLPrefix checks if the given URL is under the given URL prefix.
+// The scheme and host have to be equal and the path of the URL has to be the
+// path of the prefix or one of its sub-paths. When the prefix has a query,
+// the URL must have the same path and carry all the parameters of the prefix.
+//
+// Args:
+//
+//	subject: The canonical URL to check.
+//	prefix: The canonical URL prefix.
+//
+// Returns:
+//
+//	bool: true if the URL is under the prefix, false otherwise.
+func MatchURLPrefix(subject string, prefix string) bool {
+	subjectBase, subjectQuery, _ := strings.Cut(subject, "?")
+	prefixBase, prefixQuery, _ := strings.Cut(prefix, "?")
+
+	if prefixQuery == "" {
+		return subjectBase == prefixBase || strings.HasPrefix(subjectBase, prefixBase+"/")
+	}
+
+	if subjectBase != prefixBase {
+		return false
+	}
+
+	subjectValues, _ := url.ParseQuery(subjectQuery)
+	prefixValues, _ := url.ParseQuery(prefixQuery)
+
+	for key, values := range prefixValues {
+		for _, value := range values {
+			if !slices.Contains(subjectValues[key], value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ComplementURLs returns the complements of the given canonical URL, that is
+// the URL with the complements of its host.
+//
+// Args:
+//
+//	canonical: The canonical URL to complement.
+//	prefixes: The complement prefixes.
+//
+// Returns:
+//
+//	[]string: The complement URLs.
+func ComplementURLs(canonical string, prefixes []string) []string {
+	urlObj, err := url.Parse(canonical)
+
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+
+	for _, host := range Complements(urlObj.Host, prefixes) {
+		complement := *urlObj
+		complement.Host = host
+
+		result = append(result, complement.String())
+	}
+
+	return result
+}
+
+// DefaultComplementPrefixes are the complement prefixes handled when the
+// complements are handled without giving any prefix.
+var DefaultComplementPrefixes = []string{"www."}
+
+// NormalizeComplementPrefixes lowercases the given complement prefixes and makes
+// sure they end with a dot. The result is sorted from the longest to the
+// shortest prefix so that "www2." is tried before "ww.".
+//
+// Args:
+//
+//	prefixes: The complement prefixes to normalize.
+//
+// Returns:
+//
+//	[]string: The normalized complement prefixes.
+func NormalizeComplementPrefixes(prefixes []string) []string {
+	var result []string
+
+	for _, prefix := range prefixes {
+		prefix = strings.Trim(strings.ToLower(strings.TrimSpace(prefix)), ".")
+
+		if prefix == "" {
+			continue
+		}
+
+		prefix += "."
+
+		if !slices.Contains(result, prefix) {
+			result = append(result, prefix)
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	return result
+}
+
+// StripComplementPrefix removes the first complement prefix the given subject
+// starts with. The subject is returned unchanged when it has no complement prefix.
+//
+// Args:
+//
+//	subject: The subject to strip.
+//	prefixes: The normalized complement prefixes.
+//
+// Returns:
+//
+//	string: The subject without its complement prefix.
+func StripComplementPrefix(subject string, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if base, found := strings.CutPrefix(subject, prefix); found && base != "" {
+			return base
+		}
+	}
+
+	return subject
+}
+
+// Complements returns the complements of the given subject: the subject without
+// its complement prefix and the subject with each of the complement prefixes.
+// The subject itself is not part of the result.
+//
+// Args:
+//
+//	subject: The subject to complement.
+//	prefixes: The normalized complement prefixes.
+//
+// Returns:
+//
+//	[]string: The complements of the subject.
+func Complements(subject string, prefixes []string) []string {
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	base := StripComplementPrefix(subject, prefixes)
+
+	var result []string
+
+	for _, complement := range append([]string{base}, prefixesOf(base, prefixes)...) {
+		if complement != subject && !slices.Contains(result, complement) {
+			result = append(result, complement)
+		}
+	}
+
+	return result
+}
+
+// prefixesOf returns the given subject prefixed with each of the complement prefixes.
+func prefixesOf(subject string, prefixes []string) []string {
+	result := make([]string, 0, len(prefixes))
+
+	for _, prefix := range prefixes {
+		result = append(result, prefix+subject)
+	}
+
+	return result
+}
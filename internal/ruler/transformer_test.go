@@ -15,7 +15,11 @@ limitations under the License.
 */
 package ruler
 
-import "testing"
+import (
+	"slices"
+	"strings"
+	"testing"
+)
 
 func TestIdnazeString(t *testing.T) {
 	tests := []struct {
@@ -116,6 +120,11 @@ func TestNormalizeSubjects(t *testing.T) {
 		{"localhost", "localhost"},
 		{"# comment", ""},
 		{"saarbrücken.saarland # comment", "xn--saarbrcken-feb.saarland"},
+		{"Example.COM.", "example.com"},
+		{"example.com:443", "example.com"},
+		{"example.com #comment", "example.com"},
+		{"example.com/path", ""},
+		{"https://Example.COM/Path", "https://example.com/Path"},
 	}
 
 	for _, test := range tests {
@@ -140,6 +149,7 @@ func TestNormalizeSubjectsWithComplementHandling(t *testing.T) {
 		{"localhost", "localhost"},
 		{"# comment", ""},
 		{"saarbrücken.saarland # comment", "xn--saarbrcken-feb.saarland"},
+		{"WWW.Example.COM.", "example.com"},
 	}
 
 	for _, test := range tests {
@@ -175,3 +185,365 @@ func TestExctractNetLocationFromURL(t *testing.T) {
 	}
 
 }
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"https://docs.example.com/shared/", "https://docs.example.com/shared", true},
+		{"HTTPS://Docs.Example.COM:443/shared//", "https://docs.example.com/shared", true},
+		{"http://docs.example.com:80/", "http://docs.example.com", true},
+		{"http://docs.example.com:8080/a", "http://docs.example.com:8080/a", true},
+		{"https://docs.example.com./a", "https://docs.example.com/a", true},
+		{"https://docs.example.com/%7euser/%c3%a9", "https://docs.example.com/~user/%C3%A9", true},
+		{"https://docs.example.com/a?b=2&a=1#top", "https://docs.example.com/a?a=1&b=2", true},
+		{"https://saarbrücken.saarland/a", "https://xn--saarbrcken-feb.saarland/a", true},
+		{"https://[2001:db8::1]:443/a", "https://[2001:db8::1]/a", true},
+		{"ftp://example.com/a", "", false},
+		{"example.com/a", "", false},
+		{"https:///a", "", false},
+	}
+
+	for _, test := range tests {
+		result, err := CanonicalizeURL(test.input)
+
+		if (err == nil) != test.valid {
+			t.Errorf("CanonicalizeURL(%q) error = %v; want valid: %v", test.input, err, test.valid)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("CanonicalizeURL(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestMatchURLPrefix(t *testing.T) {
+	tests := []struct {
+		subject  string
+		prefix   string
+		expected bool
+	}{
+		{"https://docs.example.com/shared", "https://docs.example.com/shared", true},
+		{"https://docs.example.com/shared/a/b", "https://docs.example.com/shared", true},
+		{"https://docs.example.com/shared/a?x=1", "https://docs.example.com/shared", true},
+		{"https://docs.example.com/sharedx", "https://docs.example.com/shared", false},
+		{"http://docs.example.com/shared/a", "https://docs.example.com/shared", false},
+		{"https://example.com/shared/a", "https://docs.example.com/shared", false},
+		{"https://docs.example.com/a", "https://docs.example.com", true},
+		{"https://docs.example.com/a?id=1&x=2", "https://docs.example.com/a?id=1", true},
+		{"https://docs.example.com/a?id=2", "https://docs.example.com/a?id=1", false},
+		{"https://docs.example.com/a/b?id=1", "https://docs.example.com/a?id=1", false},
+	}
+
+	for _, test := range tests {
+		result := MatchURLPrefix(test.subject, test.prefix)
+		if result != test.expected {
+			t.Errorf("MatchURLPrefix(%q, %q) = %v; want %v", test.subject, test.prefix, result, test.expected)
+		}
+	}
+}
+
+func TestComplementURLs(t *testing.T) {
+	tests := []struct {
+		input    string
+		prefixes []string
+		expected []string
+	}{
+		{"https://example.com/a", DefaultComplementPrefixes, []string{"https://www.example.com/a"}},
+		{"https://www.example.com/a", DefaultComplementPrefixes, []string{"https://example.com/a"}},
+		{"http://example.com:8080", DefaultComplementPrefixes, []string{"http://www.example.com:8080"}},
+		{"https://m.example.com/a", []string{"www.", "m."}, []string{"https://example.com/a", "https://www.example.com/a"}},
+		{"https://example.com/a", nil, nil},
+	}
+
+	for _, test := range tests {
+		result := ComplementURLs(test.input, test.prefixes)
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("ComplementURLs(%q, %q) = %q; want %q", test.input, test.prefixes, result, test.expected)
+		}
+	}
+}
+
+func TestNormalizeComplementPrefixes(t *testing.T) {
+	tests := []struct {
+		input    []string
+		expected []string
+	}{
+		{nil, nil},
+		{[]string{"www."}, []string{"www."}},
+		{[]string{"WWW", " m. ", ".amp."}, []string{"www.", "amp.", "m."}},
+		{[]string{"ww.", "www2.", "m.", "mobile.", "www."}, []string{"mobile.", "www2.", "www.", "ww.", "m."}},
+		{[]string{"m.", "m", ""}, []string{"m."}},
+	}
+
+	for _, test := range tests {
+		result := NormalizeComplementPrefixes(test.input)
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("NormalizeComplementPrefixes(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestComplements(t *testing.T) {
+	prefixes := NormalizeComplementPrefixes([]string{"www.", "m.", "mobile.", "amp.", "www2.", "ww."})
+
+	tests := []struct {
+		input    string
+		prefixes []string
+		expected []string
+	}{
+		{"example.com", nil, nil},
+		{"example.com", DefaultComplementPrefixes, []string{"www.example.com"}},
+		{"www.example.com", DefaultComplementPrefixes, []string{"example.com"}},
+		{"m.example.com", DefaultComplementPrefixes, []string{"www.m.example.com"}},
+		{"example.com", prefixes, []string{"mobile.example.com", "www2.example.com", "www.example.com", "amp.example.com", "ww.example.com", "m.example.com"}},
+		{"www2.example.com", prefixes, []string{"example.com", "mobile.example.com", "www.example.com", "amp.example.com", "ww.example.com", "m.example.com"}},
+		{"ww.example.com", prefixes, []string{"example.com", "mobile.example.com", "www2.example.com", "www.example.com", "amp.example.com", "m.example.com"}},
+	}
+
+	for _, test := range tests {
+		result := Complements(test.input, test.prefixes)
+		if !slices.Equal(result, test.expected) {
+			t.Errorf("Complements(%q, %q) = %q; want %q", test.input, test.prefixes, result, test.expected)
+		}
+	}
+}
+
+func TestStripComplementPrefix(t *testing.T) {
+	prefixes := NormalizeComplementPrefixes([]string{"www.", "m.", "mobile.", "www2.", "ww."})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"example.com", "example.com"},
+		{"www.example.com", "example.com"},
+		{"www2.example.com", "example.com"},
+		{"ww.example.com", "example.com"},
+		{"mobile.example.com", "example.com"},
+		{"m.example.com", "example.com"},
+		{"www.m.example.com", "m.example.com"},
+		{"wwww.example.com", "wwww.example.com"},
+		{"www.", "www."},
+	}
+
+	for _, test := range tests {
+		result := StripComplementPrefix(test.input, prefixes)
+		if result != test.expected {
+			t.Errorf("StripComplementPrefix(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestCanonicalizeDomain(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		// Case.
+		{"example.com", "example.com", true},
+		{"Example.COM", "example.com", true},
+		{"EXAMPLE.COM", "example.com", true},
+		{"  example.com\t", "example.com", true},
+		// Trailing dot.
+		{"example.com.", "example.com", true},
+		{"Example.Com.", "example.com", true},
+		// Ports.
+		{"example.com:443", "example.com", true},
+		{"Example.com.:8080", "example.com", true},
+		{"[2001:DB8::1]:53", "2001:db8::1", true},
+		{"192.0.2.1:80", "192.0.2.1", true},
+		// IP addresses.
+		{"192.0.2.1", "192.0.2.1", true},
+		{"2001:DB8::1", "2001:db8::1", true},
+		{"::1", "::1", true},
+		// Unicode.
+		{"saarbrücken.saarland", "xn--saarbrcken-feb.saarland", true},
+		{"SAARBRÜCKEN.saarland", "xn--saarbrcken-feb.saarland", true},
+		{"xn--saarbrcken-feb.saarland", "xn--saarbrcken-feb.saarland", true},
+		{"XN--SAARBRCKEN-FEB.saarland", "xn--saarbrcken-feb.saarland", true},
+		{"faß.de", "xn--fa-hia.de", true},
+		{"ＥＸＡＭＰＬＥ．com", "example.com", true},
+		{"bücher。de", "xn--bcher-kva.de", true},
+		{"😀.example", "xn--e28h.example", true},
+		{"пример.рф", "xn--e1afmkfd.xn--p1ai", true},
+		{"例子.测试", "xn--fsqu00a.xn--0zwm56d", true},
+		// Names found in the wild.
+		{"ad_server.example.com", "ad_server.example.com", true},
+		{"_dmarc.example.com", "_dmarc.example.com", true},
+		{"-ads.example.com", "-ads.example.com", true},
+		{"r1---sn-abc.googlevideo.com", "r1---sn-abc.googlevideo.com", true},
+		{"localhost", "localhost", true},
+		{"123.example", "123.example", true},
+		{"a.b.c.d.e.f.example.com", "a.b.c.d.e.f.example.com", true},
+		// Invalid names.
+		{"", "", false},
+		{"   ", "", false},
+		{"a..b", "", false},
+		{".example.com", "", false},
+		{"example..com", "", false},
+		{"ex ample.com", "", false},
+		{"example.com/path", "", false},
+		{"user@example.com", "", false},
+		{"*.example.com", "", false},
+		{"example!.com", "", false},
+		{"xn--zz.com", "", false},
+		{strings.Repeat("a", 64) + ".com", "", false},
+		{strings.Repeat("a.", 127) + "com", "", false},
+	}
+
+	for _, test := range tests {
+		result, err := CanonicalizeDomain(test.input)
+
+		if (err == nil) != test.valid {
+			t.Errorf("CanonicalizeDomain(%q) error = %v; want valid: %v", test.input, err, test.valid)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("CanonicalizeDomain(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestCanonicalizeDomainIdempotent(t *testing.T) {
+	inputs := []string{"Example.COM.", "saarbrücken.saarland", "faß.de", "ad_server.example.com:443", "2001:DB8::1"}
+
+	for _, input := range inputs {
+		first, err := CanonicalizeDomain(input)
+
+		if err != nil {
+			t.Fatalf("CanonicalizeDomain(%q) returned error: %v", input, err)
+		}
+
+		second, err := CanonicalizeDomain(first)
+
+		if err != nil || second != first {
+			t.Errorf("CanonicalizeDomain(%q) = %q, %v; want %q", first, second, err, first)
+		}
+	}
+}
+
+func TestToUnicodeDomain(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"example.com", "example.com", true},
+		{"xn--saarbrcken-feb.saarland", "saarbrücken.saarland", true},
+		{"XN--SAARBRCKEN-FEB.saarland.", "saarbrücken.saarland", true},
+		{"SAARBRÜCKEN.saarland", "saarbrücken.saarland", true},
+		{"xn--e1afmkfd.xn--p1ai", "пример.рф", true},
+		{"xn--fa-hia.de", "faß.de", true},
+		{"a..b", "", false},
+	}
+
+	for _, test := range tests {
+		result, err := ToUnicodeDomain(test.input)
+
+		if (err == nil) != test.valid {
+			t.Errorf("ToUnicodeDomain(%q) error = %v; want valid: %v", test.input, err, test.valid)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("ToUnicodeDomain(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"example.com", "example.com", true},
+		{"www.shop.example.co.uk", "example.co.uk", true},
+		{"WWW.Example.CO.UK.", "example.co.uk", true},
+		{"a.b.foo.blogspot.com", "foo.blogspot.com", true},
+		{"www.bücher.de", "xn--bcher-kva.de", true},
+		{"co.uk", "", false},
+		{"com", "", false},
+		{"192.0.2.1", "", false},
+		{"a..b", "", false},
+	}
+
+	for _, test := range tests {
+		result, err := RegistrableDomain(test.input)
+
+		if (err == nil) != test.valid {
+			t.Errorf("RegistrableDomain(%q) error = %v; want valid: %v", test.input, err, test.valid)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("RegistrableDomain(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestStripInlineComment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"# comment", ""},
+		{"example.com", "example.com"},
+		{"example.com # comment", "example.com"},
+		{"example.com #comment", "example.com"},
+		{"example.com\t# comment", "example.com"},
+		{"example.com    # comment # other", "example.com"},
+		{"ALL#example.com", "ALL#example.com"},
+		{"ALL#example.com # comment", "ALL#example.com"},
+		{"RZDB#example #comment#", "RZDB#example"},
+	}
+
+	for _, test := range tests {
+		result := stripInlineComment(test.input)
+		if result != test.expected {
+			t.Errorf("stripInlineComment(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestNormalizeRule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"# comment", ""},
+		{"Example.COM", "example.com"},
+		{"example.com.", "example.com"},
+		{"example.com:443", "example.com"},
+		{"example.com # the last character must survive", "example.com"},
+		{"saarbrücken.saarland", "xn--saarbrcken-feb.saarland"},
+		{"ALL Example.COM.", "ALL example.com"},
+		{"all,.Example.org", "all,.example.org"},
+		{"ALL#.org", "ALL#.org"},
+		{"ROOT .WWW.Example.CO.UK", "ROOT www.example.co.uk"},
+		{"ALL@saarbrücken.saarland # comment", "ALL@xn--saarbrcken-feb.saarland"},
+		{"RZDB Güter", "RZDB xn--gter-0ra"},
+		{"RZD:example", "RZD:example"},
+		{"REG ^Ads?\\.example$", "REG ^Ads?\\.example$"},
+		{"REG vöklingen.*", "REG xn--vklingen-n4a.*"},
+		{"URL https://Docs.example.com/Shared/", "URL https://Docs.example.com/Shared/"},
+		{"https://Example.COM/Path", "https://example.com/Path"},
+		{"example.com/path", ""},
+		{"ALL exa mple.com", ""},
+	}
+
+	for _, test := range tests {
+		result := NormalizeRule(test.input)
+		if result != test.expected {
+			t.Errorf("NormalizeRule(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
@@ -20,23 +20,101 @@ import (
 	"regexp"
 )
 
+// Kinds of rules.
+const (
+	RuleKindPlain = "PLAIN"
+	RuleKindAll   = "ALL"
+	RuleKindReg   = "REG"
+	RuleKindRzdb  = "RZDB"
+	RuleKindURL   = "URL"
+	RuleKindRoot  = "ROOT"
+)
+
+// Complement overrides of the modifiers.
+const (
+	ComplementOn  = "on"
+	ComplementOff = "off"
+)
+
+// Modifiers are the match modifiers of a single rule (e.g. "ALL[depth=1] example.com").
+type Modifiers struct {
+	// Depth is the maximum number of labels an ALL rule matches in front of its domain, 0 when unlimited.
+	Depth int
+	// Complement overrides the complement handling of the ruler (ComplementOn or ComplementOff), empty otherwise.
+	Complement string
+	// CaseInsensitive makes a REG rule case-insensitive.
+	CaseInsensitive bool
+	// Force loads the rule even when it is over-broad.
+	Force bool
+}
+
+// Rule describes a rule loaded into the ruler.
+type Rule struct {
+	// Kind is the kind of the rule (PLAIN, ALL, REG, RZDB, URL or ROOT).
+	Kind string
+	// Value is the entry of the rule, without its flag.
+	Value string
+	// Modifiers are the match modifiers of the rule.
+	Modifiers Modifiers
+}
+
+// depthRule is an ALL rule whose match is limited in depth.
+type depthRule struct {
+	// suffix is the suffix the subjects have to end with (e.g. ".example.com").
+	suffix string
+	// depth is the maximum number of labels in front of the suffix.
+	depth int
+	// prefixes are the complement prefixes handled by the rule.
+	prefixes []string
+	// owner is the rule the suffix comes from.
+	owner Rule
+}
+
+// regexRule is a single regular expression of the REG rules.
+type regexRule struct {
+	// pattern is the regular expression, as pushed into the combined one.
+	pattern string
+	// compiled is the compiled regular expression.
+	compiled *regexp.Regexp
+	// owner is the rule the regular expression comes from.
+	owner Rule
+}
+
+// Indexes the rules are pushed into, used to find the rule behind a match.
+const (
+	indexStrict   = "strict:"
+	indexEnds     = "ends:"
+	indexPrefixes = "prefixes:"
+)
+
 type InternalRuler struct {
-	strict            map[string][]string
-	ends              map[string][]string
-	present           map[string][]string
-	regex             string
-	compiled_regexp   *regexp.Regexp
-	handle_complement bool
-	extensions        []string
-	logger            *slog.Logger
+	rules               []Rule
+	origins             map[Rule]string
+	strict              map[string][]string
+	ends                map[string][]string
+	prefixes            map[string][]string
+	owners              map[string][]Rule
+	depthEnds           map[string][]depthRule
+	regex               string
+	compiled_regexp     *regexp.Regexp
+	regexRules          []regexRule
+	complement_prefixes []string
+	allow_broad_rules   bool
+	frozen              bool
+	extensions          []string
+	logger              *slog.Logger
 
 	// Flags for different rule types
 	FlagsAll     []string
 	FlagsReg     []string
 	FlagsRzdb    []string
+	FlagsURL     []string
+	FlagsRoot    []string
 	AllowedFlags []string
 	// Default flag for each rule type
 	FlagAll  string
 	FlagReg  string
 	FlagRzdb string
+	FlagURL  string
+	FlagRoot string
 }
@@ -0,0 +1,76 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ValidateRule checks that the given rule is valid: its modifiers, and its
+// entry according to its flag (a domain, a regular expression, a URL or a
+// domain with a registrable domain). Unlike AddRule, which skips the invalid
+// entries, the reason why the rule is invalid is reported.
+//
+// Args:
+//
+//	rule: The rule to validate.
+//
+// Returns:
+//
+//	error: An *InvalidRuleError if the rule is invalid, nil otherwise.
+func ValidateRule(rule string) error {
+	invalid := func(reason string) error {
+		return &InvalidRuleError{Rule: strings.TrimSpace(rule), Reason: reason}
+	}
+
+	withoutModifiers, modifiers, err := ExtractModifiers(rule)
+
+	if err != nil {
+		return invalid(err.Error())
+	}
+
+	entry := stripInlineComment(withoutModifiers)
+
+	if entry == "" {
+		return invalid("the rule is empty or a comment")
+	}
+
+	kind := RuleKindPlain
+
+	if match := ruleFlagRegex.FindStringSubmatch(entry); match != nil {
+		entry, kind = strings.TrimPrefix(entry, match[0]), ruleKindOfFlag(match[1])
+	}
+
+	switch {
+	case kind == RuleKindReg:
+		_, err = regexp.Compile(regexOf(entry, modifiers))
+	case kind == RuleKindURL || (kind == RuleKindPlain && isURL(entry)):
+		_, err = CanonicalizeURL(entry)
+	case kind == RuleKindRoot:
+		_, err = RegistrableDomain(strings.TrimPrefix(entry, "."))
+	case kind == RuleKindAll:
+		_, err = CanonicalizeDomain(strings.TrimPrefix(entry, "."))
+	default:
+		_, err = CanonicalizeDomain(entry)
+	}
+
+	if err != nil {
+		return invalid(err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruler
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		rule  string
+		valid bool
+	}{
+		{"example.com", true},
+		{"ALL .example.com", true},
+		{"ALL[depth=2] example.com", true},
+		{"REG ^ads[0-9]+\\.", true},
+		{"RZDB example", true},
+		{"URL https://example.com/ads/", true},
+		{"https://example.com/ads/", true},
+		{"ROOT www.example.co.uk", true},
+		{"", false},
+		{"# comment", false},
+		{"exa mple.com", false},
+		{"ALL .exa_mple..com", false},
+		{"REG ([a-z", false},
+		{"ROOT co.uk", false},
+		{"URL https://exa mple.com/", false},
+		{"ALL[depth=0] example.com", false},
+	}
+
+	for _, test := range tests {
+		err := ValidateRule(test.rule)
+
+		if (err == nil) != test.valid {
+			t.Errorf("ValidateRule(%q) = %v; want valid: %v", test.rule, err, test.valid)
+		}
+
+		var invalid *InvalidRuleError
+
+		if err != nil && !errors.As(err, &invalid) {
+			t.Errorf("ValidateRule(%q) = %v; want an *InvalidRuleError", test.rule, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruleset
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// Loader loads the rules into a new ruler.
+type Loader func() (givilsta.GivilstaRuler, error)
+
+// Ruleset is a loaded ruler. It is never modified once loaded: a reload swaps
+// it for a new one, so that the checks in flight keep the one they started with.
+type Ruleset struct {
+	// Ruler is the frozen ruler.
+	Ruler givilsta.GivilstaRuler
+	// LoadedAt is when the rules were loaded.
+	LoadedAt time.Time
+	// Rules is the number of loaded rules.
+	Rules int
+}
+
+// Holder holds the current ruleset, swapped atomically on reload.
+type Holder struct {
+	load    Loader
+	logger  *slog.Logger
+	current atomic.Pointer[Ruleset]
+
+	// reloadLock serializes the reloads.
+	reloadLock sync.Mutex
+	// loadError is the error of the last load, nil if it succeeded.
+	loadError atomic.Pointer[error]
+}
+
+// NewHolder creates a holder loading its rules with the given loader. The rules
+// are not loaded yet: Current returns nil until the first successful Reload.
+//
+// Args:
+//
+//	load: The loader of the rules.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	*Holder: The holder.
+func NewHolder(load Loader, logger *slog.Logger) *Holder {
+	return &Holder{load: load, logger: logger}
+}
+
+// Reload loads the rules into a new ruler and swaps it for the current one.
+// On error, the current ruler is kept.
+//
+// Returns:
+//
+//	error: An error if the rules cannot be loaded.
+func (h *Holder) Reload() error {
+	h.reloadLock.Lock()
+	defer h.reloadLock.Unlock()
+
+	start := time.Now()
+	ruler, err := h.load()
+
+	if err != nil {
+		h.logger.Error("Error loading the rules, keeping the current ones.", slog.String("error", err.Error()))
+		h.loadError.Store(&err)
+
+		return err
+	}
+
+	ruler.Freeze()
+
+	set := &Ruleset{Ruler: ruler, LoadedAt: time.Now(), Rules: len(ruler.Rules())}
+	h.current.Store(set)
+	h.loadError.Store(nil)
+
+	h.logger.Info("Loaded the rules.", slog.Int("rules", set.Rules), slog.Duration("duration", time.Since(start)))
+
+	return nil
+}
+
+// Current returns the current ruleset.
+//
+// Returns:
+//
+//	*Ruleset: The current ruleset, nil if the rules were never loaded.
+func (h *Holder) Current() *Ruleset {
+	return h.current.Load()
+}
+
+// LoadError returns the error of the last load.
+//
+// Returns:
+//
+//	error: The error of the last load, nil if it succeeded or no load happened yet.
+func (h *Holder) LoadError() error {
+	if err := h.loadError.Load(); err != nil {
+		return *err
+	}
+
+	return nil
+}
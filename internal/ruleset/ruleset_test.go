@@ -0,0 +1,127 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package ruleset_test
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/internal/ruleset/rulesettest"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+func TestReload(t *testing.T) {
+	loads := 0
+	loaders := []ruleset.Loader{rulesettest.Loader("example.org"), rulesettest.Loader("example.com", "example.net")}
+	failure := errors.New("syntax error")
+
+	holder := ruleset.NewHolder(func() (givilsta.GivilstaRuler, error) {
+		loads++
+
+		if loads > len(loaders) {
+			return nil, failure
+		}
+
+		return loaders[loads-1]()
+	}, slog.New(slog.DiscardHandler))
+
+	if set := holder.Current(); set != nil {
+		t.Errorf("Current() before loading = %+v; want nil", set)
+	}
+
+	if err := holder.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	first := holder.Current()
+
+	if err := holder.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	second := holder.Current()
+
+	if !first.Ruler.IsSubjectWhitelisted("example.org") || first.Rules != 1 {
+		t.Errorf("first ruleset = %+v; want example.org whitelisted by its single rule", first)
+	}
+
+	if second.Ruler.IsSubjectWhitelisted("example.org") || second.Rules != 2 || !second.Ruler.IsFrozen() {
+		t.Errorf("second ruleset = %+v; want the 2 frozen rules of the reload", second)
+	}
+
+	// A failed reload keeps the current rules.
+	if err := holder.Reload(); !errors.Is(err, failure) {
+		t.Errorf("Reload() = %v; want %v", err, failure)
+	}
+
+	if holder.Current() != second || !errors.Is(holder.LoadError(), failure) {
+		t.Errorf("after a failed reload: Current() = %+v, LoadError() = %v; want the second ruleset and the error", holder.Current(), holder.LoadError())
+	}
+}
+
+func TestReloadInvalidRule(t *testing.T) {
+	loaded := []string{"example.org"}
+
+	holder := ruleset.NewHolder(func() (givilsta.GivilstaRuler, error) { return rulesettest.Loader(loaded...)() }, slog.New(slog.DiscardHandler))
+
+	if err := holder.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	loaded = []string{"example.com", "REG ([a-z"}
+
+	var invalid *givilsta.InvalidRuleError
+
+	if err := holder.Reload(); !errors.As(err, &invalid) {
+		t.Fatalf("Reload() with an invalid REG rule = %v; want an *InvalidRuleError", err)
+	}
+
+	if set := holder.Current(); !set.Ruler.IsSubjectWhitelisted("example.org") || set.Ruler.IsSubjectWhitelisted("example.com") {
+		t.Errorf("Current() after a failed reload = %+v; want the previous rules", set)
+	}
+}
+
+func TestReloadWhileReading(t *testing.T) {
+	holder := rulesettest.NewHolder(t, "example.org")
+
+	var wait sync.WaitGroup
+
+	for range 4 {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			for range 200 {
+				if !holder.Current().Ruler.IsSubjectWhitelisted("example.org") {
+					t.Errorf("Current() during a reload does not whitelist example.org")
+					return
+				}
+			}
+		}()
+	}
+
+	for range 20 {
+		if err := holder.Reload(); err != nil {
+			t.Errorf("Reload() returned error: %v", err)
+		}
+	}
+
+	wait.Wait()
+}
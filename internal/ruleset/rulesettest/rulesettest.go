@@ -0,0 +1,55 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package rulesettest
+
+import (
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// Loader returns a loader loading the given rules, with their position as
+// origin (e.g. "test.list:1"). Like the command line, the loader fails on the
+// first refused rule.
+func Loader(rules ...string) ruleset.Loader {
+	return func() (givilsta.GivilstaRuler, error) {
+		ruler := givilsta.NewGivilstaRuler(false, slog.New(slog.DiscardHandler))
+
+		for index, rule := range rules {
+			if _, err := ruler.AddRuleWithOrigin(rule, givilsta.NoFlag, fmt.Sprintf("test.list:%d", index+1)); err != nil {
+				return nil, err
+			}
+		}
+
+		return ruler, nil
+	}
+}
+
+// NewHolder creates a holder with the given rules already loaded.
+func NewHolder(t testing.TB, rules ...string) *ruleset.Holder {
+	t.Helper()
+
+	holder := ruleset.NewHolder(Loader(rules...), slog.New(slog.DiscardHandler))
+
+	if err := holder.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	return holder
+}
@@ -0,0 +1,256 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// Server answers the whitelist checks over HTTP. The rules are held by the
+// holder and can be reloaded at any time without disturbing the requests in flight.
+type Server struct {
+	rules  *ruleset.Holder
+	logger *slog.Logger
+	mux    *http.ServeMux
+}
+
+// New creates a server answering with the rules of the given holder. The
+// server is not ready until the rules are loaded.
+//
+// Args:
+//
+//	rules: The holder of the rules.
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	*Server: The server.
+func New(rules *ruleset.Holder, logger *slog.Logger) *Server {
+	s := &Server{rules: rules, logger: logger, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /v1/check", s.handleCheck)
+	s.mux.HandleFunc("POST /v1/check", s.handleBatchCheck)
+	s.mux.HandleFunc("GET /v1/explain", s.handleExplain)
+	s.mux.HandleFunc("POST /v1/reload", s.handleReload)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+
+	return s
+}
+
+// ServeHTTP dispatches the request to the endpoint handling it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.logger.Debug("Handling request.", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+	s.mux.ServeHTTP(w, r)
+}
+
+// check checks the given subject against the given ruleset.
+//
+// Args:
+//
+//	set: The ruleset to check the subject against.
+//	subject: The subject to check.
+//
+// Returns:
+//
+//	CheckResult: The result of the check.
+//	givilsta.Rule: The rule which whitelists the subject, the zero rule when blocked.
+func check(set *ruleset.Ruleset, subject string) (CheckResult, givilsta.Rule) {
+	result := CheckResult{Subject: subject, Status: StatusBlocked}
+	rule, whitelisted := set.Ruler.WhitelistingRule(subject)
+
+	if whitelisted {
+		result.Status = StatusWhitelisted
+		result.Rule = rule.String()
+		result.Kind = rule.Kind
+		result.Origin = set.Ruler.RuleOrigin(rule)
+	}
+
+	return result, rule
+}
+
+// ruleset returns the current ruleset, or writes an error when the rules are
+// not loaded yet.
+func (s *Server) ruleset(w http.ResponseWriter) *ruleset.Ruleset {
+	set := s.rules.Current()
+
+	if set == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("the rules are not loaded yet"))
+	}
+
+	return set
+}
+
+// handleCheck checks the subject given by the "subject" query parameter.
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	subject := r.URL.Query().Get("subject")
+
+	if subject == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing subject parameter"))
+		return
+	}
+
+	if set := s.ruleset(w); set != nil {
+		result, _ := check(set, subject)
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// handleBatchCheck checks the subjects of the JSON body.
+func (s *Server) handleBatchCheck(w http.ResponseWriter, r *http.Request) {
+	var request BatchRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+
+	if err := decoder.Decode(&request); err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		if errors.As(err, &maxBytesError) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body larger than %d bytes", maxBytesError.Limit))
+			return
+		}
+
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+
+		return
+	}
+
+	if len(request.Subjects) > MaxBatchSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("too many subjects: %d (maximum %d)", len(request.Subjects), MaxBatchSize))
+		return
+	}
+
+	// The whole batch is checked against the same ruleset, even if a reload happens meanwhile.
+	set := s.ruleset(w)
+	if set == nil {
+		return
+	}
+
+	response := BatchResponse{Results: make([]CheckResult, 0, len(request.Subjects))}
+
+	for _, subject := range request.Subjects {
+		result, _ := check(set, subject)
+		response.Results = append(response.Results, result)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleExplain explains why the subject given by the "subject" query
+// parameter is whitelisted or blocked.
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	subject := r.URL.Query().Get("subject")
+
+	if subject == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing subject parameter"))
+		return
+	}
+
+	set := s.ruleset(w)
+	if set == nil {
+		return
+	}
+
+	result, rule := check(set, subject)
+	explanation := Explanation{CheckResult: result, LoadedAt: set.LoadedAt}
+
+	if explanation.Status == StatusBlocked {
+		explanation.Reason = "no loaded rule matches the subject"
+		writeJSON(w, http.StatusOK, explanation)
+
+		return
+	}
+
+	explanation.Value = rule.Value
+	explanation.Reason = fmt.Sprintf("the subject matches the %s rule %q", rule.Kind, rule.String())
+
+	if explanation.Origin != "" {
+		explanation.Reason += fmt.Sprintf(" from %s", explanation.Origin)
+	}
+
+	if !rule.Modifiers.IsZero() {
+		explanation.Modifiers = &ModifiersExplanation{
+			Depth:           rule.Modifiers.Depth,
+			Complement:      rule.Modifiers.Complement,
+			CaseInsensitive: rule.Modifiers.CaseInsensitive,
+			Force:           rule.Modifiers.Force,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, explanation)
+}
+
+// handleReload reloads the rules.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.rules.Reload(); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("reloading the rules: %w", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.status("reloaded"))
+}
+
+// handleHealth tells that the server is alive.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Status{Status: "ok"})
+}
+
+// handleReady tells whether the server can answer the checks.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.rules.Current() == nil {
+		writeJSON(w, http.StatusServiceUnavailable, s.status("loading"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.status("ready"))
+}
+
+// status describes the current ruleset with the given status.
+func (s *Server) status(status string) Status {
+	result := Status{Status: status}
+
+	if set := s.rules.Current(); set != nil {
+		result.Rules = set.Rules
+		result.LoadedAt = &set.LoadedAt
+	}
+
+	if err := s.rules.LoadError(); err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// writeJSON writes the given value as the JSON response.
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	// The client may be gone, there is nobody to report the error to.
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes the given error as the JSON response.
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,330 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/internal/ruleset/rulesettest"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// newTestServer creates a server with the given rules already loaded.
+func newTestServer(t *testing.T, rules ...string) *Server {
+	t.Helper()
+
+	return New(rulesettest.NewHolder(t, rules...), slog.New(slog.DiscardHandler))
+}
+
+// serve sends the given request to the handler and decodes the JSON response into result.
+func serve(t *testing.T, handler http.Handler, method string, target string, body string, result any) int {
+	t.Helper()
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("%s %s: Content-Type = %q; want application/json", method, target, contentType)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s %s: invalid JSON response %q: %v", method, target, recorder.Body.String(), err)
+		}
+	}
+
+	return recorder.Code
+}
+
+func TestCheck(t *testing.T) {
+	s := newTestServer(t, "example.org", "ALL .example.net")
+
+	tests := []struct {
+		target string
+		code   int
+		want   CheckResult
+	}{
+		{"/v1/check?subject=example.org", http.StatusOK, CheckResult{Subject: "example.org", Status: StatusWhitelisted, Rule: "example.org", Kind: "PLAIN", Origin: "test.list:1"}},
+		{"/v1/check?subject=api.example.net", http.StatusOK, CheckResult{Subject: "api.example.net", Status: StatusWhitelisted, Rule: "ALL .example.net", Kind: "ALL", Origin: "test.list:2"}},
+		{"/v1/check?subject=example.com", http.StatusOK, CheckResult{Subject: "example.com", Status: StatusBlocked}},
+	}
+
+	for _, test := range tests {
+		var result CheckResult
+
+		if code := serve(t, s, http.MethodGet, test.target, "", &result); code != test.code {
+			t.Errorf("GET %s: status = %d; want %d", test.target, code, test.code)
+		}
+
+		if result != test.want {
+			t.Errorf("GET %s = %+v; want %+v", test.target, result, test.want)
+		}
+	}
+
+	var response errorResponse
+
+	if code := serve(t, s, http.MethodGet, "/v1/check", "", &response); code != http.StatusBadRequest || response.Error == "" {
+		t.Errorf("GET /v1/check without subject: status = %d, error = %q; want %d with an error", code, response.Error, http.StatusBadRequest)
+	}
+}
+
+func TestBatchCheck(t *testing.T) {
+	s := newTestServer(t, "example.org")
+
+	var response BatchResponse
+
+	code := serve(t, s, http.MethodPost, "/v1/check", `{"subjects": ["example.org", "example.com"]}`, &response)
+
+	if code != http.StatusOK {
+		t.Fatalf("POST /v1/check: status = %d; want %d", code, http.StatusOK)
+	}
+
+	want := []CheckResult{
+		{Subject: "example.org", Status: StatusWhitelisted, Rule: "example.org", Kind: "PLAIN", Origin: "test.list:1"},
+		{Subject: "example.com", Status: StatusBlocked},
+	}
+
+	if len(response.Results) != len(want) {
+		t.Fatalf("POST /v1/check returned %d results; want %d", len(response.Results), len(want))
+	}
+
+	for index, result := range response.Results {
+		if result != want[index] {
+			t.Errorf("POST /v1/check results[%d] = %+v; want %+v", index, result, want[index])
+		}
+	}
+
+	tooMany, _ := json.Marshal(BatchRequest{Subjects: make([]string, MaxBatchSize+1)})
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"invalid JSON", `{"subjects": [`, http.StatusBadRequest},
+		{"too many subjects", string(tooMany), http.StatusRequestEntityTooLarge},
+		{"body too large", `{"subjects": ["` + strings.Repeat("a", maxBodySize) + `"]}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		var response errorResponse
+
+		if code := serve(t, s, http.MethodPost, "/v1/check", test.body, &response); code != test.code || response.Error == "" {
+			t.Errorf("POST /v1/check with %s: status = %d, error = %q; want %d with an error", test.name, code, response.Error, test.code)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	s := newTestServer(t, "ALL[depth=1] .example.net")
+
+	var explanation Explanation
+
+	if code := serve(t, s, http.MethodGet, "/v1/explain?subject=api.example.net", "", &explanation); code != http.StatusOK {
+		t.Fatalf("GET /v1/explain: status = %d; want %d", code, http.StatusOK)
+	}
+
+	if explanation.Status != StatusWhitelisted || explanation.Kind != "ALL" || explanation.Origin != "test.list:1" {
+		t.Errorf("GET /v1/explain = %+v; want whitelisted by the ALL rule of test.list:1", explanation)
+	}
+
+	if explanation.Modifiers == nil || explanation.Modifiers.Depth != 1 {
+		t.Errorf("GET /v1/explain modifiers = %+v; want depth 1", explanation.Modifiers)
+	}
+
+	if !strings.Contains(explanation.Reason, "test.list:1") || explanation.LoadedAt.IsZero() {
+		t.Errorf("GET /v1/explain = %+v; want a reason with the origin and the load time", explanation)
+	}
+
+	explanation = Explanation{}
+	serve(t, s, http.MethodGet, "/v1/explain?subject=deep.api.example.net", "", &explanation)
+
+	if explanation.Status != StatusBlocked || explanation.Rule != "" || explanation.Reason == "" {
+		t.Errorf("GET /v1/explain = %+v; want blocked with a reason", explanation)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	failure := errors.New("whitelist file 'missing.list' does not exist")
+	rules := ruleset.NewHolder(func() (givilsta.GivilstaRuler, error) { return nil, failure }, slog.New(slog.DiscardHandler))
+	s := New(rules, slog.New(slog.DiscardHandler))
+
+	var status Status
+
+	if code := serve(t, s, http.MethodGet, "/healthz", "", &status); code != http.StatusOK || status.Status != "ok" {
+		t.Errorf("GET /healthz: status = %d, %+v; want %d", code, status, http.StatusOK)
+	}
+
+	if code := serve(t, s, http.MethodGet, "/readyz", "", &status); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz before loading: status = %d; want %d", code, http.StatusServiceUnavailable)
+	}
+
+	if code := serve(t, s, http.MethodGet, "/v1/check?subject=example.org", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /v1/check before loading: status = %d; want %d", code, http.StatusServiceUnavailable)
+	}
+
+	if err := rules.Reload(); !errors.Is(err, failure) {
+		t.Errorf("Reload() = %v; want %v", err, failure)
+	}
+
+	status = Status{}
+
+	if code := serve(t, s, http.MethodGet, "/readyz", "", &status); code != http.StatusServiceUnavailable || status.Error != failure.Error() {
+		t.Errorf("GET /readyz after a failed load: status = %d, %+v; want %d with the error", code, status, http.StatusServiceUnavailable)
+	}
+}
+
+func TestReload(t *testing.T) {
+	loads := 0
+	loaders := []ruleset.Loader{rulesettest.Loader("example.org"), rulesettest.Loader("example.com")}
+
+	rules := ruleset.NewHolder(func() (givilsta.GivilstaRuler, error) {
+		loads++
+
+		if loads > len(loaders) {
+			return nil, errors.New("syntax error")
+		}
+
+		return loaders[loads-1]()
+	}, slog.New(slog.DiscardHandler))
+	s := New(rules, slog.New(slog.DiscardHandler))
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	get := func(target string) string {
+		response, err := http.Get(server.URL + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		body, _ := io.ReadAll(response.Body)
+
+		return response.Status + " " + string(body)
+	}
+
+	post := func(target string) int {
+		response, err := http.Post(server.URL+target, "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+
+		return response.StatusCode
+	}
+
+	if code := post("/v1/reload"); code != http.StatusOK {
+		t.Fatalf("POST /v1/reload: status = %d; want %d", code, http.StatusOK)
+	}
+
+	if body := get("/v1/check?subject=example.org"); !strings.Contains(body, `"whitelisted"`) {
+		t.Errorf("GET /v1/check after the first load = %s; want example.org whitelisted", body)
+	}
+
+	if code := post("/v1/reload"); code != http.StatusOK {
+		t.Fatalf("POST /v1/reload: status = %d; want %d", code, http.StatusOK)
+	}
+
+	if body := get("/v1/check?subject=example.org"); !strings.Contains(body, `"blocked"`) {
+		t.Errorf("GET /v1/check after the reload = %s; want example.org blocked", body)
+	}
+
+	// A failed reload keeps the current rules.
+	if code := post("/v1/reload"); code != http.StatusInternalServerError {
+		t.Errorf("POST /v1/reload with invalid rules: status = %d; want %d", code, http.StatusInternalServerError)
+	}
+
+	if body := get("/v1/check?subject=example.com"); !strings.Contains(body, `"whitelisted"`) {
+		t.Errorf("GET /v1/check after a failed reload = %s; want example.com still whitelisted", body)
+	}
+
+	if body := get("/readyz"); !strings.HasPrefix(body, "200") || !strings.Contains(body, "syntax error") {
+		t.Errorf("GET /readyz after a failed reload = %s; want ready with the error", body)
+	}
+}
+
+func TestReloadInvalidRule(t *testing.T) {
+	loaded := []string{"example.org"}
+
+	rules := ruleset.NewHolder(func() (givilsta.GivilstaRuler, error) { return rulesettest.Loader(loaded...)() }, slog.New(slog.DiscardHandler))
+	s := New(rules, slog.New(slog.DiscardHandler))
+
+	if err := rules.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	loaded = []string{"example.com", "REG ([a-z"}
+
+	var invalid *givilsta.InvalidRuleError
+
+	if err := rules.Reload(); !errors.As(err, &invalid) {
+		t.Fatalf("Reload() with an invalid REG rule = %v; want an *InvalidRuleError", err)
+	}
+
+	for subject, want := range map[string]string{"example.org": StatusWhitelisted, "example.com": StatusBlocked} {
+		var result CheckResult
+
+		serve(t, s, http.MethodGet, "/v1/check?subject="+subject, "", &result)
+
+		if result.Status != want {
+			t.Errorf("GET /v1/check?subject=%s after a failed reload = %+v; want %s", subject, result, want)
+		}
+	}
+}
+
+func TestReloadWhileChecking(t *testing.T) {
+	rules := rulesettest.NewHolder(t, "example.org")
+	s := New(rules, slog.New(slog.DiscardHandler))
+
+	var wait sync.WaitGroup
+
+	for range 4 {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			for range 200 {
+				var result CheckResult
+
+				serve(t, s, http.MethodGet, "/v1/check?subject=example.org", "", &result)
+
+				if result.Status != StatusWhitelisted {
+					t.Errorf("GET /v1/check during a reload = %+v; want whitelisted", result)
+					return
+				}
+			}
+		}()
+	}
+
+	for range 20 {
+		if err := rules.Reload(); err != nil {
+			t.Errorf("Reload() returned error: %v", err)
+		}
+	}
+
+	wait.Wait()
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package server
+
+import (
+	"time"
+)
+
+// Statuses reported by the check and explain endpoints.
+const (
+	StatusWhitelisted = "whitelisted"
+	StatusBlocked     = "blocked"
+)
+
+const (
+	// MaxBatchSize is the maximum number of subjects of a batch check.
+	MaxBatchSize = 10000
+	// maxBodySize is the maximum size of a request body, in bytes.
+	maxBodySize = 1 << 20
+)
+
+// CheckResult is the result of the check of a single subject.
+type CheckResult struct {
+	// Subject is the subject, as given by the client.
+	Subject string `json:"subject"`
+	// Status is either "whitelisted" or "blocked".
+	Status string `json:"status"`
+	// Rule is the rule which whitelists the subject, empty when blocked.
+	Rule string `json:"rule,omitempty"`
+	// Kind is the kind of the rule which whitelists the subject, empty when blocked.
+	Kind string `json:"kind,omitempty"`
+	// Origin is where the rule comes from (e.g. "whitelist.list:3"), empty when unknown.
+	Origin string `json:"origin,omitempty"`
+}
+
+// BatchRequest is the body of a batch check.
+type BatchRequest struct {
+	// Subjects are the subjects to check.
+	Subjects []string `json:"subjects"`
+}
+
+// BatchResponse is the response of a batch check.
+type BatchResponse struct {
+	// Results are the results, in the order of the subjects.
+	Results []CheckResult `json:"results"`
+}
+
+// Explanation details why a subject is whitelisted or blocked.
+type Explanation struct {
+	CheckResult
+	// Value is the entry of the rule, without its flag and modifiers.
+	Value string `json:"value,omitempty"`
+	// Modifiers are the match modifiers of the rule, nil when it has none.
+	Modifiers *ModifiersExplanation `json:"modifiers,omitempty"`
+	// Reason describes the decision in plain words.
+	Reason string `json:"reason"`
+	// LoadedAt is when the rules the subject was checked against were loaded.
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// ModifiersExplanation are the match modifiers of the rule of an explanation.
+type ModifiersExplanation struct {
+	// Depth is the maximum number of labels an ALL rule matches in front of its domain, 0 when unlimited.
+	Depth int `json:"depth,omitempty"`
+	// Complement overrides the complement handling of the ruler ("on" or "off").
+	Complement string `json:"complement,omitempty"`
+	// CaseInsensitive tells whether a REG rule is case-insensitive.
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+	// Force tells whether the rule was loaded despite being over-broad.
+	Force bool `json:"force,omitempty"`
+}
+
+// Status is the response of the readiness and reload endpoints.
+type Status struct {
+	// Status is "ready", "reloaded" or "loading".
+	Status string `json:"status"`
+	// Rules is the number of loaded rules.
+	Rules int `json:"rules"`
+	// LoadedAt is when the rules were loaded, omitted when not loaded yet.
+	LoadedAt *time.Time `json:"loaded_at,omitempty"`
+	// Error is the error of the last load, if it failed.
+	Error string `json:"error,omitempty"`
+}
+
+// errorResponse is the response of a failed request.
+type errorResponse struct {
+	// Error describes what failed.
+	Error string `json:"error"`
+}
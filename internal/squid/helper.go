@@ -0,0 +1,129 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package squid
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+)
+
+// New creates a helper answering with the rules of the given holder. The rules
+// have to be loaded before serving.
+//
+// Args:
+//
+//	rules: The holder of the rules.
+//	concurrent: Whether the requests start with a channel ID (Squid's concurrency option above 0).
+//	logger: The logger to use.
+//
+// Returns:
+//
+//	*Helper: The helper.
+func New(rules *ruleset.Holder, concurrent bool, logger *slog.Logger) *Helper {
+	return &Helper{rules: rules, concurrent: concurrent, logger: logger}
+}
+
+// Serve answers the requests read from the reader until its end.
+//
+// Args:
+//
+//	reader: The reader to read the requests from, Squid's stdin.
+//	writer: The writer to write the replies to, Squid's stdout.
+//
+// Returns:
+//
+//	error: An error if the requests cannot be read or the replies written.
+func (h *Helper) Serve(reader io.Reader, writer io.Writer) error {
+	if h.rules.Current() == nil {
+		return errors.New("the rules are not loaded")
+	}
+
+	input := bufio.NewReader(reader)
+	output := bufio.NewWriter(writer)
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+
+	for scanner.Scan() {
+		if _, err := output.WriteString(h.Reply(scanner.Text()) + "\n"); err != nil {
+			return err
+		}
+
+		// The replies are sent at once when Squid has no other request pending.
+		if input.Buffered() == 0 {
+			if err := output.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return output.Flush()
+}
+
+// Reply answers a single request line.
+//
+// Args:
+//
+//	line: The request, "[channel-ID] subject [...]". The subject is the URL or
+//	host, %-encoded by Squid. The other fields are ignored.
+//
+// Returns:
+//
+//	string: The reply, without line terminator.
+func (h *Helper) Reply(line string) string {
+	fields := strings.Fields(line)
+	prefix := ""
+
+	if h.concurrent && len(fields) != 0 {
+		prefix = fields[0] + " "
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 {
+		h.logger.Debug("Request without subject.", slog.String("line", line))
+		return prefix + ReplyBH + ` message="missing subject"`
+	}
+
+	subject := fields[0]
+
+	if decoded, err := url.PathUnescape(subject); err == nil {
+		subject = decoded
+	}
+
+	set := h.rules.Current()
+
+	if set == nil {
+		return prefix + ReplyBH + ` message="rules not loaded"`
+	}
+
+	if set.Ruler.IsSubjectWhitelisted(subject) {
+		h.logger.Debug("Subject whitelisted.", slog.String("subject", subject))
+		return prefix + ReplyOK
+	}
+
+	h.logger.Debug("Subject not whitelisted.", slog.String("subject", subject))
+
+	return prefix + ReplyERR
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package squid
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+	"github.com/funilrys/givilsta/internal/ruleset/rulesettest"
+	"github.com/funilrys/givilsta/pkg/givilsta"
+)
+
+// newTestHelper creates a helper with the given rules already loaded.
+func newTestHelper(t *testing.T, concurrent bool, rules ...string) *Helper {
+	t.Helper()
+
+	return New(rulesettest.NewHolder(t, rules...), concurrent, slog.New(slog.DiscardHandler))
+}
+
+func TestReply(t *testing.T) {
+	tests := []struct {
+		concurrent bool
+		line       string
+		want       string
+	}{
+		{false, "example.org", "OK"},
+		{false, "api.example.net", "OK"},
+		{false, "example.com", "ERR"},
+		{false, "https://example.org/some/path?query=1", "OK"},
+		{false, "https://example.com/", "ERR"},
+		{false, "https%3A%2F%2Fapi.example.net%2Fpath", "OK"},
+		{false, "example.org 443 CONNECT", "OK"},
+		{false, "example.org:443", "OK"},
+		{false, "", `BH message="missing subject"`},
+		{true, "0 example.org", "0 OK"},
+		{true, "12 example.com", "12 ERR"},
+		{true, "7", `7 BH message="missing subject"`},
+	}
+
+	for _, test := range tests {
+		helper := newTestHelper(t, test.concurrent, "example.org", "ALL .example.net")
+
+		if reply := helper.Reply(test.line); reply != test.want {
+			t.Errorf("Reply(%q) with concurrent=%t = %q; want %q", test.line, test.concurrent, reply, test.want)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	helper := newTestHelper(t, true, "example.org")
+
+	var output strings.Builder
+
+	if err := helper.Serve(strings.NewReader("0 example.org\n1 example.com\n2 http://example.org/\n"), &output); err != nil {
+		t.Fatalf("Serve() returned error: %v", err)
+	}
+
+	if want := "0 OK\n1 ERR\n2 OK\n"; output.String() != want {
+		t.Errorf("Serve() wrote %q; want %q", output.String(), want)
+	}
+
+	if err := New(ruleset.NewHolder(rulesettest.Loader(), slog.New(slog.DiscardHandler)), false, slog.New(slog.DiscardHandler)).Serve(strings.NewReader(""), io.Discard); err == nil {
+		t.Errorf("Serve() without loaded rules returned no error")
+	}
+}
+
+func TestServeFlushesEachReply(t *testing.T) {
+	helper := newTestHelper(t, false, "example.org")
+
+	requests, requestWriter := io.Pipe()
+	replyReader, replies := io.Pipe()
+
+	go func() {
+		_ = helper.Serve(requests, replies)
+		replies.Close()
+	}()
+
+	buffer := make([]byte, 16)
+
+	// Squid waits for the reply before sending the next request.
+	for _, exchange := range [][2]string{{"example.org\n", "OK\n"}, {"example.com\n", "ERR\n"}} {
+		if _, err := requestWriter.Write([]byte(exchange[0])); err != nil {
+			t.Fatal(err)
+		}
+
+		count, err := replyReader.Read(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buffer[:count]) != exchange[1] {
+			t.Errorf("reply to %q = %q; want %q", exchange[0], buffer[:count], exchange[1])
+		}
+	}
+
+	requestWriter.Close()
+}
+
+func TestReload(t *testing.T) {
+	loaded := []string{"example.org"}
+
+	rules := ruleset.NewHolder(func() (givilsta.GivilstaRuler, error) { return rulesettest.Loader(loaded...)() }, slog.New(slog.DiscardHandler))
+	helper := New(rules, false, slog.New(slog.DiscardHandler))
+
+	if reply := helper.Reply("example.org"); !strings.HasPrefix(reply, ReplyBH) {
+		t.Errorf("Reply() before loading = %q; want %s", reply, ReplyBH)
+	}
+
+	if err := rules.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	if reply := helper.Reply("example.org"); reply != ReplyOK {
+		t.Errorf("Reply() after loading = %q; want %s", reply, ReplyOK)
+	}
+
+	loaded = []string{"example.com"}
+
+	if err := rules.Reload(); err != nil {
+		t.Fatalf("Reload() returned error: %v", err)
+	}
+
+	if reply := helper.Reply("example.com"); reply != ReplyOK {
+		t.Errorf("Reply() after the reload = %q; want %s", reply, ReplyOK)
+	}
+}
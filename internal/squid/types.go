@@ -0,0 +1,44 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package squid
+
+import (
+	"log/slog"
+
+	"github.com/funilrys/givilsta/internal/ruleset"
+)
+
+// Replies of the helper.
+const (
+	// ReplyOK tells Squid that the subject is whitelisted.
+	ReplyOK = "OK"
+	// ReplyERR tells Squid that the subject is not whitelisted.
+	ReplyERR = "ERR"
+	// ReplyBH tells Squid that the request could not be answered.
+	ReplyBH = "BH"
+)
+
+// maxLineSize is the maximum size of a request line, in bytes.
+const maxLineSize = 1 << 20
+
+// Helper answers the requests of Squid's external_acl_type helper protocol:
+// one request per line, "[channel-ID] subject [...]", answered with
+// "[channel-ID] OK" when the subject is whitelisted and "[channel-ID] ERR" otherwise.
+type Helper struct {
+	rules      *ruleset.Holder
+	concurrent bool
+	logger     *slog.Logger
+}
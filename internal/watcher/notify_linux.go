@@ -0,0 +1,110 @@
+//go:build linux
+
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// notifyMask are the inotify events waking the watcher up. The directories of
+// the files are watched, as editors often replace a file instead of writing it.
+const notifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM
+
+// notifier wakes the watcher up on the inotify events of the watched directories.
+type notifier struct {
+	fd int
+	// file reads the events of fd through the runtime poller, so that closing
+	// it wakes the reading goroutine up.
+	file    *os.File
+	done    chan struct{}
+	wake    chan<- struct{}
+	lock    sync.Mutex
+	watched map[string]bool
+}
+
+func newNotifier(wake chan<- struct{}) (*notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &notifier{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		done:    make(chan struct{}),
+		wake:    wake,
+		watched: make(map[string]bool),
+	}
+
+	go n.read()
+
+	return n, nil
+}
+
+// watch adds the directories of the given files to the watched ones.
+func (n *notifier) watch(paths []string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+
+		if n.watched[dir] {
+			continue
+		}
+
+		// A missing directory is left to the polling.
+		if _, err := syscall.InotifyAddWatch(n.fd, dir, notifyMask); err == nil {
+			n.watched[dir] = true
+		}
+	}
+}
+
+// read forwards the events as wake-ups, until the notifier is closed.
+func (n *notifier) read() {
+	defer close(n.done)
+
+	buffer := make([]byte, 4096)
+
+	for {
+		count, err := n.file.Read(buffer)
+
+		if err != nil || count <= 0 {
+			return
+		}
+
+		select {
+		case n.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// close stops the notifier and waits for the reading goroutine to return.
+func (n *notifier) close() error {
+	err := n.file.Close()
+
+	<-n.done
+
+	return err
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEnableNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whitelist.list")
+
+	watcher := New(time.Hour)
+	defer watcher.Close()
+
+	watcher.Watch([]string{path})
+
+	if err := watcher.EnableNotify(); err != nil {
+		t.Skipf("inotify not available: %v", err)
+	}
+
+	done := make(chan bool, 1)
+
+	go func() {
+		done <- watcher.Wait(make(chan struct{}))
+	}()
+
+	if err := os.WriteFile(path, []byte("example.org\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Wait() not woken up by the notification")
+	}
+
+	if changed := watcher.Changes(); len(changed) != 1 || changed[0] != path {
+		t.Errorf("Changes() = %v; want [%s]", changed, path)
+	}
+}
+
+func TestNotifierClose(t *testing.T) {
+	notifier, err := newNotifier(make(chan struct{}, 1))
+	if err != nil {
+		t.Skipf("inotify not available: %v", err)
+	}
+
+	notifier.watch([]string{filepath.Join(t.TempDir(), "whitelist.list")})
+
+	closed := make(chan error, 1)
+
+	go func() {
+		closed <- notifier.close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("close() returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("close() did not wake the reading goroutine up")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watcher
+
+import "errors"
+
+// notifier is not available on this platform, the watcher only polls.
+type notifier struct{}
+
+func newNotifier(wake chan<- struct{}) (*notifier, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (n *notifier) watch(paths []string) {}
+
+func (n *notifier) close() error {
+	return nil
+}
@@ -0,0 +1,177 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package watcher
+
+import (
+	"os"
+	"slices"
+	"time"
+)
+
+// fileState is the state of a watched file, compared between two polls.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// Watcher detects the changes of a set of files by polling their state. When
+// notifications are enabled (see EnableNotify), the file system wakes it up as
+// soon as something changes, the polling remaining the safety net.
+type Watcher struct {
+	interval time.Duration
+	states   map[string]fileState
+	wake     chan struct{}
+	notifier *notifier
+}
+
+// New creates a watcher polling the watched files at the given interval.
+//
+// Args:
+//
+//	interval: The interval between two polls.
+//
+// Returns:
+//
+//	*Watcher: The watcher, watching nothing yet.
+func New(interval time.Duration) *Watcher {
+	return &Watcher{
+		interval: interval,
+		states:   make(map[string]fileState),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Watch sets the files to watch. The files which were not watched yet are
+// recorded as they are now, their current state is not reported as a change.
+//
+// Args:
+//
+//	paths: The files to watch.
+func (w *Watcher) Watch(paths []string) {
+	states := make(map[string]fileState, len(paths))
+
+	for _, path := range paths {
+		if state, ok := w.states[path]; ok {
+			states[path] = state
+			continue
+		}
+
+		states[path] = stat(path)
+	}
+
+	w.states = states
+
+	if w.notifier != nil {
+		w.notifier.watch(paths)
+	}
+}
+
+// Changes returns the watched files which changed since the previous call.
+//
+// Returns:
+//
+//	[]string: The changed files, sorted.
+func (w *Watcher) Changes() []string {
+	var changed []string
+
+	for path, previous := range w.states {
+		current := stat(path)
+
+		if current != previous {
+			w.states[path] = current
+			changed = append(changed, path)
+		}
+	}
+
+	slices.Sort(changed)
+
+	return changed
+}
+
+// Wait waits for the next poll: the end of the interval, or a notification.
+//
+// Args:
+//
+//	stop: The channel closed to stop waiting.
+//
+// Returns:
+//
+//	bool: false if the stop channel was closed, true otherwise.
+func (w *Watcher) Wait(stop <-chan struct{}) bool {
+	timer := time.NewTimer(w.interval)
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+	case <-w.wake:
+	}
+
+	return true
+}
+
+// EnableNotify asks the file system to notify the changes of the watched files.
+//
+// Returns:
+//
+//	error: An error if the notifications are not supported, the watcher keeps polling.
+func (w *Watcher) EnableNotify() error {
+	if w.notifier != nil {
+		return nil
+	}
+
+	notifier, err := newNotifier(w.wake)
+	if err != nil {
+		return err
+	}
+
+	w.notifier = notifier
+
+	paths := make([]string, 0, len(w.states))
+
+	for path := range w.states {
+		paths = append(paths, path)
+	}
+
+	w.notifier.watch(paths)
+
+	return nil
+}
+
+// Close releases the notifications, if enabled.
+func (w *Watcher) Close() error {
+	if w.notifier == nil {
+		return nil
+	}
+
+	err := w.notifier.close()
+	w.notifier = nil
+
+	return err
+}
+
+// stat returns the current state of the given file.
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "kept.list")
+	edited := filepath.Join(dir, "edited.list")
+	created := filepath.Join(dir, "created.list")
+
+	for _, path := range []string{kept, edited} {
+		if err := os.WriteFile(path, []byte("example.org\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watcher := New(time.Hour)
+	watcher.Watch([]string{kept, edited, created})
+
+	if changed := watcher.Changes(); len(changed) != 0 {
+		t.Fatalf("Changes() = %v; want no change before any edit", changed)
+	}
+
+	if err := os.WriteFile(edited, []byte("example.org\nexample.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(created, []byte("example.net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := watcher.Changes(); !slices.Equal(changed, []string{created, edited}) {
+		t.Errorf("Changes() = %v; want %v", changed, []string{created, edited})
+	}
+
+	if changed := watcher.Changes(); len(changed) != 0 {
+		t.Errorf("Changes() = %v; want the changes to be reported once", changed)
+	}
+
+	if err := os.Remove(kept); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := watcher.Changes(); !slices.Equal(changed, []string{kept}) {
+		t.Errorf("Changes() = %v; want %v", changed, []string{kept})
+	}
+}
+
+func TestWatchKeepsStates(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.list")
+	second := filepath.Join(dir, "second.list")
+
+	watcher := New(time.Hour)
+	watcher.Watch([]string{first})
+
+	if err := os.WriteFile(first, []byte("example.org\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Watching a new file must not forget the pending change of the other one.
+	watcher.Watch([]string{first, second})
+
+	if changed := watcher.Changes(); !slices.Equal(changed, []string{first}) {
+		t.Errorf("Changes() = %v; want %v", changed, []string{first})
+	}
+
+	watcher.Watch([]string{second})
+
+	if err := os.WriteFile(first, []byte("example.com\nexample.net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed := watcher.Changes(); len(changed) != 0 {
+		t.Errorf("Changes() = %v; want unwatched files to be ignored", changed)
+	}
+}
+
+func TestWait(t *testing.T) {
+	watcher := New(10 * time.Millisecond)
+
+	if !watcher.Wait(make(chan struct{})) {
+		t.Errorf("Wait() = false; want true at the end of the interval")
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+
+	watcher = New(time.Hour)
+
+	if watcher.Wait(stop) {
+		t.Errorf("Wait() = true; want false once stopped")
+	}
+}
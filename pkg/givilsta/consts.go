@@ -15,6 +15,8 @@ limitations under the License.
 */
 package givilsta
 
+import "github.com/funilrys/givilsta/internal/ruler"
+
 type Flags string
 
 const (
@@ -24,7 +26,25 @@ const (
 	FlagReg = "REG@"
 	// RZDB: the RZDB rule.
 	FlagRzdb = "RZDB@"
+	// URL: the URL-prefix rule.
+	FlagURL = "URL@"
+	// ROOT: the registrable domain rule.
+	FlagRoot = "ROOT@"
 
 	// NoFlag: the classic rule, no flag is applied.
 	NoFlag = ""
 )
+
+// DefaultComplementPrefixes are the complement prefixes handled by NewGivilstaRuler
+// when the complements are handled.
+var DefaultComplementPrefixes = ruler.DefaultComplementPrefixes
+
+// LabelForm is the form the internationalized domains are written in.
+type LabelForm string
+
+const (
+	// LabelFormALabel: the IDNA ASCII form (e.g. "xn--bcher-kva.de").
+	LabelFormALabel LabelForm = "a-label"
+	// LabelFormULabel: the Unicode form (e.g. "bücher.de").
+	LabelFormULabel LabelForm = "u-label"
+)
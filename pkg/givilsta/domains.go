@@ -0,0 +1,67 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package givilsta
+
+import (
+	"github.com/funilrys/givilsta/internal/ruler"
+)
+
+// CanonicalizeDomain converts a domain into the canonical form the rules and
+// subjects are compared in: lowercased, UTS-46 mapped, in its A-label form,
+// without trailing dot nor port.
+//
+// Args:
+//
+//	domain: The domain to canonicalize.
+//
+// Returns:
+//
+//	string: The canonical domain.
+//	error: An error if the domain is not a syntactically valid name.
+func CanonicalizeDomain(domain string) (string, error) {
+	return ruler.CanonicalizeDomain(domain)
+}
+
+// FormatDomain writes a domain in the given label form. The domain is returned
+// unchanged when it is not a syntactically valid name.
+//
+// Args:
+//
+//	domain: The domain to format.
+//	form: The label form to write the domain in.
+//
+// Returns:
+//
+//	string: The formatted domain.
+func FormatDomain(domain string, form LabelForm) string {
+	var result string
+	var err error
+
+	switch form {
+	case LabelFormALabel:
+		result, err = ruler.CanonicalizeDomain(domain)
+	case LabelFormULabel:
+		result, err = ruler.ToUnicodeDomain(domain)
+	default:
+		return domain
+	}
+
+	if err != nil {
+		return domain
+	}
+
+	return result
+}
@@ -31,6 +31,17 @@ func NewGivilstaRuler(handle_complement bool, logger *slog.Logger) GivilstaRuler
 	return &givilstaRuler{intRuler: intRuler, logger: logger}
 }
 
+// NewGivilstaRulerWithComplementPrefixes creates a new instance of our GivilstaRuler
+// which handles the complements of the given prefixes (e.g. "www.", "m.", "amp.").
+// A subject and the subject prefixed with any of the prefixes are then
+// considered as the same subject. The complements are not handled when no
+// prefix is given.
+func NewGivilstaRulerWithComplementPrefixes(complement_prefixes []string, logger *slog.Logger) GivilstaRuler {
+	intRuler := ruler.NewInternalRulerWithComplementPrefixes(complement_prefixes, logger)
+
+	return &givilstaRuler{intRuler: intRuler, logger: logger}
+}
+
 // NewGivilstaRulerWithLogger creates a new instance of our GivilstaRuler with a logger.
 func (g *givilstaRuler) Logger() *slog.Logger {
 	return g.logger
@@ -61,6 +72,35 @@ func (g *givilstaRuler) AddRuleWithFlag(rule string, flag Flags) bool {
 	return g.intRuler.AddRule(fmt.Sprintf("%s%s", flag, rule))
 }
 
+// AddRuleWithOrigin indexes a rule to the GivilstaRuler and records where it comes from.
+// Args:
+//
+//	rule: The rule to add.
+//	flag: The flag to use for the rule, NoFlag for none.
+//	origin: The origin of the rule (e.g. "whitelist.list:3").
+//
+// Returns:
+//
+//	bool: true if the rule was added successfully, false otherwise.
+//	error: An *InvalidRuleError if the rule cannot be loaded, a *BroadRuleError if the
+//	rule is over-broad, ErrFrozenRuler if the ruler is frozen, an error if the known
+//	extensions cannot be fetched, nil otherwise.
+func (g *givilstaRuler) AddRuleWithOrigin(rule string, flag Flags, origin string) (bool, error) {
+	return g.intRuler.AddRuleWithOrigin(fmt.Sprintf("%s%s", flag, rule), origin)
+}
+
+// RuleOrigin returns where a loaded rule comes from.
+// Args:
+//
+//	rule: The loaded rule, as returned by Rules or WhitelistingRule.
+//
+// Returns:
+//
+//	string: The origin of the rule, empty when unknown.
+func (g *givilstaRuler) RuleOrigin(rule Rule) string {
+	return g.intRuler.Origin(rule)
+}
+
 // RemoveRule removes a rule from the GivilstaRuler.
 // Args:
 //
@@ -69,7 +109,8 @@ func (g *givilstaRuler) AddRuleWithFlag(rule string, flag Flags) bool {
 // Returns:
 //
 //	bool: true if the rule was removed successfully, false otherwise.
-func (g *givilstaRuler) RemoveRule(rule string) bool {
+//	error: ErrFrozenRuler if the ruler is frozen, nil otherwise.
+func (g *givilstaRuler) RemoveRule(rule string) (bool, error) {
 	return g.intRuler.RemoveRule(rule)
 }
 
@@ -82,10 +123,60 @@ func (g *givilstaRuler) RemoveRule(rule string) bool {
 // Returns:
 //
 //	bool: true if the rule was removed successfully, false otherwise.
-func (g *givilstaRuler) RemoveRuleWithFlag(rule string, flag Flags) bool {
+//	error: ErrFrozenRuler if the ruler is frozen, nil otherwise.
+func (g *givilstaRuler) RemoveRuleWithFlag(rule string, flag Flags) (bool, error) {
 	return g.intRuler.RemoveRule(fmt.Sprintf("%s%s", flag, rule))
 }
 
+// SetAllowBroadRules allows (or refuses) the over-broad rules. The over-broad
+// rules (e.g. "ALL .com", "REG .*") are refused by default, unless they carry
+// the "force" modifier.
+// Args:
+//
+//	allow: Whether the over-broad rules are allowed or not.
+func (g *givilstaRuler) SetAllowBroadRules(allow bool) {
+	g.intRuler.SetAllowBroadRules(allow)
+}
+
+// Freeze makes the GivilstaRuler immutable, so that it can be shared by
+// concurrent goroutines. The rules can no longer be added or removed (ErrFrozenRuler).
+func (g *givilstaRuler) Freeze() {
+	g.intRuler.Freeze()
+}
+
+// IsFrozen checks if the GivilstaRuler is frozen.
+// Returns:
+//
+//	bool: true if the ruler is frozen, false otherwise.
+func (g *givilstaRuler) IsFrozen() bool {
+	return g.intRuler.IsFrozen()
+}
+
+// CheckRule checks if a rule would be refused because it cannot be loaded or is over-broad.
+// Args:
+//
+//	rule: The rule to check.
+//
+// Returns:
+//
+//	error: An *InvalidRuleError or a *BroadRuleError if the rule would be refused, nil otherwise.
+func (g *givilstaRuler) CheckRule(rule string) error {
+	return g.intRuler.CheckRule(rule)
+}
+
+// CheckRuleWithFlag checks if a rule would be refused because it cannot be loaded or is over-broad, with a specific flag.
+// Args:
+//
+//	rule: The rule to check.
+//	flag: The flag to use for the rule.
+//
+// Returns:
+//
+//	error: An *InvalidRuleError or a *BroadRuleError if the rule would be refused, nil otherwise.
+func (g *givilstaRuler) CheckRuleWithFlag(rule string, flag Flags) error {
+	return g.intRuler.CheckRule(fmt.Sprintf("%s%s", flag, rule))
+}
+
 // IsSubjectWhitelisted checks if a subject is whitelisted.
 // Args:
 //
@@ -110,6 +201,32 @@ func (g *givilstaRuler) IsSubjectBlacklisted(subject string) bool {
 	return !g.IsSubjectWhitelisted(subject)
 }
 
+// IsSubjectWildcardWhitelisted checks if a subject and all its subdomains are whitelisted.
+// This is how wildcard subjects (e.g. "*.example.com") should be checked.
+// Args:
+//
+//	subject: The subject to check.
+//
+// Returns:
+//
+//	bool: true if the subject and all its subdomains are whitelisted, false otherwise.
+func (g *givilstaRuler) IsSubjectWildcardWhitelisted(subject string) bool {
+	return g.intRuler.IsWildcardWhitelisted(subject)
+}
+
+// WhitelistingRule returns the rule which whitelists a subject.
+// Args:
+//
+//	subject: The subject to check.
+//
+// Returns:
+//
+//	Rule: The rule which whitelists the subject, the first loaded one when several rules match.
+//	bool: true if the subject is whitelisted, false otherwise.
+func (g *givilstaRuler) WhitelistingRule(subject string) (Rule, bool) {
+	return g.intRuler.WhitelistingRule(subject)
+}
+
 // Same as IsSubjectWhitelisted, but assume that the given line come straight from
 // one of the supported format: hosts file or plain text (maybe others in the future).
 //
@@ -185,3 +302,32 @@ func (g *givilstaRuler) GetBlacklistedFromLine(line string) []string {
 
 	return result
 }
+
+// Rules returns the rules currently loaded into the GivilstaRuler, in the order they
+// were added. Bypassed rules are not part of the result.
+//
+// Returns:
+//
+//	[]Rule: The loaded rules.
+func (g *givilstaRuler) Rules() []Rule {
+	return g.intRuler.Rules()
+}
+
+// SetKnownExtensions sets the extensions the RZDB rules are expanded with, instead of
+// fetching them from the network.
+// Args:
+//
+//	extensions: The known extensions (e.g. "com", "co.uk").
+func (g *givilstaRuler) SetKnownExtensions(extensions []string) {
+	g.intRuler.SetKnownExtensions(extensions)
+}
+
+// KnownExtensions returns the extensions the RZDB rules are expanded with.
+// Please note that the extensions are fetched from the network on the first call.
+//
+// Returns:
+//
+//	[]string: The known extensions.
+func (g *givilstaRuler) KnownExtensions() []string {
+	return g.intRuler.KnownExtensions()
+}
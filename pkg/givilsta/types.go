@@ -21,16 +21,40 @@ import (
 	"github.com/funilrys/givilsta/internal/ruler"
 )
 
+// Rule describes a rule loaded into a GivilstaRuler.
+type Rule = ruler.Rule
+
+// BroadRuleError describes a rule which was refused because it whitelists too much.
+type BroadRuleError = ruler.BroadRuleError
+
+// InvalidRuleError describes a rule which was refused because it cannot be loaded.
+type InvalidRuleError = ruler.InvalidRuleError
+
+// ErrFrozenRuler is returned when a rule is added to or removed from a frozen GivilstaRuler.
+var ErrFrozenRuler = ruler.ErrFrozenRuler
+
 type GivilstaRuler interface {
 	Logger() *slog.Logger
 	AddRule(rule string) bool
 	AddRuleWithFlag(rule string, flag Flags) bool
-	RemoveRule(rule string) bool
-	RemoveRuleWithFlag(rule string, flag Flags) bool
+	AddRuleWithOrigin(rule string, flag Flags, origin string) (bool, error)
+	RuleOrigin(rule Rule) string
+	RemoveRule(rule string) (bool, error)
+	RemoveRuleWithFlag(rule string, flag Flags) (bool, error)
+	SetAllowBroadRules(allow bool)
+	Freeze()
+	IsFrozen() bool
+	CheckRule(rule string) error
+	CheckRuleWithFlag(rule string, flag Flags) error
 	IsSubjectWhitelisted(subject string) bool
 	IsSubjectBlacklisted(subject string) bool
+	IsSubjectWildcardWhitelisted(subject string) bool
+	WhitelistingRule(subject string) (Rule, bool)
 	GetWhitelistedFromLine(line string) []string
 	GetBlacklistedFromLine(line string) []string
+	Rules() []Rule
+	SetKnownExtensions(extensions []string)
+	KnownExtensions() []string
 }
 
 type givilstaRuler struct {
@@ -0,0 +1,38 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package givilsta
+
+import (
+	"fmt"
+
+	"github.com/funilrys/givilsta/internal/ruler"
+)
+
+// ValidateRule checks that a rule is valid, with a specific flag. Unlike the
+// GivilstaRuler, which skips the invalid entries (e.g. invalid domains), the
+// reason why the rule is invalid is reported.
+//
+// Args:
+//
+//	rule: The rule to validate.
+//	flag: The flag to use for the rule, NoFlag for none.
+//
+// Returns:
+//
+//	error: An *InvalidRuleError if the rule is invalid, nil otherwise.
+func ValidateRule(rule string, flag Flags) error {
+	return ruler.ValidateRule(fmt.Sprintf("%s%s", flag, rule))
+}
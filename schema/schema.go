@@ -0,0 +1,26 @@
+/*
+Copyright © 2025 Nissar Chababy
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package schema
+
+import _ "embed"
+
+// WhitelistURL is the published location (the "$id") of the structured rule file schema.
+const WhitelistURL = "https://raw.githubusercontent.com/funilrys/givilsta/master/schema/whitelist.schema.json"
+
+// Whitelist is the JSON schema of the structured rule files.
+//
+//go:embed whitelist.schema.json
+var Whitelist []byte